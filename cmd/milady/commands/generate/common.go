@@ -19,6 +19,7 @@ import (
 
 	"github.com/huandu/xstrings"
 
+	"github.com/moweilong/milady/pkg/goast"
 	"github.com/moweilong/milady/pkg/gobash"
 	"github.com/moweilong/milady/pkg/gofile"
 	"github.com/moweilong/milady/pkg/replacer"
@@ -209,6 +210,45 @@ func convertServerName(serverName string) string {
 	return strings.ReplaceAll(serverName, "-", "_")
 }
 
+// defaultAPIVersion version segment of the generated api package
+const defaultAPIVersion = "v1"
+
+var apiVersionRegexp = regexp.MustCompile(`^v[1-9][0-9]*$`)
+
+// checkAPIVersion check the version segment of the api package, e.g. v1, v2
+func checkAPIVersion(version string) error {
+	if !apiVersionRegexp.MatchString(version) {
+		return fmt.Errorf(`invalid api version "%s", it must be like "v1", "v2"`, version)
+	}
+	return nil
+}
+
+// apiVersionFields replace the version segment of the output directory, proto package and go_package,
+// the fields must be placed before the fields replacing "serverNameExample"
+func apiVersionFields(serverName string, version string) []replacer.Field {
+	return []replacer.Field{
+		// replace go_package and its package name, it must be placed before the directory name
+		{
+			Old: "api/serverNameExample/v1;v1",
+			New: fmt.Sprintf("api/%s/%s;%s", serverName, version, version),
+		},
+		// replace directory name
+		{
+			Old: strings.Join([]string{"api", "serverNameExample", "v1"}, gofile.GetPathDelimiter()),
+			New: strings.Join([]string{"api", serverName, version}, gofile.GetPathDelimiter()),
+		},
+		{
+			Old: "api/serverNameExample/v1",
+			New: fmt.Sprintf("api/%s/%s", serverName, version),
+		},
+		// Note: protobuf package no "-" signs allowed
+		{
+			Old: "api.serverNameExample.v1",
+			New: fmt.Sprintf("api.%s.%s", serverName, version),
+		},
+	}
+}
+
 func convertProjectAndServerName(projectName, serverName string) (pn string, sn string, err error) {
 	if strings.HasSuffix(serverName, "-test") {
 		err = fmt.Errorf(`the server name (%s) suffix "-test" is not supported for code generation, please delete suffix "-test" or change it to another name. `, serverName)
@@ -896,6 +936,16 @@ func getLocalGoVersion() string {
 	return versionList[0].goVersion
 }
 
+// checkCodeTypes type-checks the generated go package in outPath/subDir with go/types, it is best-effort,
+// external imports are stubbed, so only the errors which can be detected without dependencies are reported.
+func checkCodeTypes(outPath string, subDir string) error {
+	dir := filepath.Join(outPath, subDir)
+	if err := goast.CheckTypesInDir(dir); err != nil {
+		return fmt.Errorf("type check generated code in %s failed:\n%v", cutPath(dir), err)
+	}
+	return nil
+}
+
 func dbDriverErr(driver string) error {
 	return errors.New("unsupported db driver: " + driver)
 }
@@ -20,6 +20,8 @@ func ModelCommand(parentName string) *cobra.Command {
 		outPath string
 		// dbTables database table names, multiple table names are separated by commas.
 		dbTables string
+		// isTypeCheck type-check the generated code with go/types, internal use only.
+		isTypeCheck bool
 
 		// sqlArgs sql2code arguments. default package is "model", JSONTag and GormType are enabled.
 		sqlArgs = sql2code.Args{
@@ -70,6 +72,12 @@ func ModelCommand(parentName string) *cobra.Command {
 				}
 			}
 
+			if isTypeCheck {
+				if err := checkCodeTypes(outPath, "internal/model"); err != nil {
+					return err
+				}
+			}
+
 			fmt.Printf(`
 using help:
   move the folder "internal" to your project code folder.
@@ -88,6 +96,8 @@ using help:
 	cmd.Flags().BoolVarP(&sqlArgs.IsEmbed, "embed", "e", false, "whether to embed gorm.model struct, invalid for mongodb")
 	cmd.Flags().IntVarP(&sqlArgs.JSONNamedType, "json-name-type", "j", 0, "json tags name type, 0:snake case, 1:camel case")
 	cmd.Flags().StringVarP(&outPath, "out", "o", "", "output directory, default is ./model_<time>")
+	cmd.Flags().BoolVar(&isTypeCheck, "type-check", false, "type-check the generated code with go/types")
+	_ = cmd.Flags().MarkHidden("type-check")
 
 	return cmd
 }
@@ -8,7 +8,6 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
-	"github.com/moweilong/milady/pkg/gofile"
 	"github.com/moweilong/milady/pkg/replacer"
 	"github.com/moweilong/milady/pkg/sql2code"
 	"github.com/moweilong/milady/pkg/sql2code/parser"
@@ -21,6 +20,7 @@ func ProtobufCommand() *cobra.Command {
 		serverName string // server name
 		outPath    string // output directory
 		dbTables   string // table names
+		apiVersion string // version segment of the api package
 
 		sqlArgs = sql2code.Args{
 			JSONTag: true,
@@ -43,6 +43,9 @@ func ProtobufCommand() *cobra.Command {
   # Generate protobuf code that include router path and swagger info.
   sponge micro protobuf --module-name=yourModuleName --server-name=yourServerName --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test --db-table=user --web-type=true
 
+  # Generate protobuf code into the api package of version v2, e.g. api/yourServerName/v2.
+  sponge micro protobuf --module-name=yourModuleName --server-name=yourServerName --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test --db-table=user --api-version=v2
+
   # Generate protobuf code and specify the server directory, Note: code generation will be canceled when the latest generated file already exists.
   sponge micro protobuf --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test --db-table=user --out=./yourServerDir`),
 		SilenceErrors: true,
@@ -61,6 +64,9 @@ func ProtobufCommand() *cobra.Command {
 			}
 
 			serverName = convertServerName(serverName)
+			if err := checkAPIVersion(apiVersion); err != nil {
+				return err
+			}
 			if sqlArgs.DBDriver == DBDriverMongodb {
 				sqlArgs.IsEmbed = false
 			}
@@ -80,6 +86,7 @@ func ProtobufCommand() *cobra.Command {
 				g := &protobufGenerator{
 					moduleName: moduleName,
 					serverName: serverName,
+					apiVersion: apiVersion,
 					codes:      codes,
 					outPath:    outPath,
 				}
@@ -112,6 +119,7 @@ using help:
 	cmd.Flags().IntVarP(&sqlArgs.JSONNamedType, "json-name-type", "j", 1, "json tags name type, 0:snake case, 1:camel case")
 	cmd.Flags().BoolVarP(&sqlArgs.IsWebProto, "web-type", "w", false, "if true, the proto file include router path and swagger info")
 	cmd.Flags().BoolVarP(&sqlArgs.IsExtendedAPI, "extended-api", "a", false, "whether to generate extended crud api, additional includes: DeleteByIDs, GetByCondition, ListByIDs, ListByLatestID")
+	cmd.Flags().StringVarP(&apiVersion, "api-version", "", defaultAPIVersion, "version segment of the api package, the proto package, go_package and output directory are api/<server-name>/<api-version>, e.g. v1, v2")
 	cmd.Flags().StringVarP(&outPath, "out", "o", "", "output directory, default is ./protobuf_<time>, "+flagTip("module-name", "server-name"))
 
 	return cmd
@@ -120,6 +128,7 @@ using help:
 type protobufGenerator struct {
 	moduleName string
 	serverName string
+	apiVersion string
 	codes      map[string]string
 	outPath    string
 }
@@ -134,6 +143,9 @@ func (g *protobufGenerator) generateCode() (string, error) {
 	if g.serverName == "" {
 		g.serverName = g.moduleName
 	}
+	if g.apiVersion == "" {
+		g.apiVersion = defaultAPIVersion
+	}
 
 	// specify the subdirectory and files
 	subDirs := []string{}
@@ -163,20 +175,9 @@ func (g *protobufGenerator) addFields(r replacer.Replacer) []replacer.Field {
 			Old: "github.com/go-dev-frame/sponge",
 			New: g.moduleName,
 		},
-		// replace directory name
-		{
-			Old: strings.Join([]string{"api", "serverNameExample", "v1"}, gofile.GetPathDelimiter()),
-			New: strings.Join([]string{"api", g.serverName, "v1"}, gofile.GetPathDelimiter()),
-		},
-		{
-			Old: "api/serverNameExample/v1",
-			New: fmt.Sprintf("api/%s/v1", g.serverName),
-		},
-		// Note: protobuf package no "-" signs allowed
-		{
-			Old: "api.serverNameExample.v1",
-			New: fmt.Sprintf("api.%s.v1", g.serverName),
-		},
+	}...)
+	fields = append(fields, apiVersionFields(g.serverName, g.apiVersion)...)
+	fields = append(fields, []replacer.Field{
 		{
 			Old: "serverNameExample",
 			New: g.serverName,
@@ -0,0 +1,65 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moweilong/milady/pkg/replacer"
+	"github.com/moweilong/milady/pkg/sql2code/parser"
+)
+
+func TestProtobufGenerator_APIVersion(t *testing.T) {
+	tplDir := t.TempDir()
+	protoDir := filepath.Join(tplDir, "api", "serverNameExample", "v1")
+	require.NoError(t, os.MkdirAll(protoDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(protoDir, "userExample.proto"), []byte(protoFileMark+"\n"), 0o644))
+
+	r, err := replacer.New(tplDir)
+	require.NoError(t, err)
+	old, ok := Replacers[TplNameMilady]
+	Replacers[TplNameMilady] = r
+	defer func() {
+		if ok {
+			Replacers[TplNameMilady] = old
+		} else {
+			delete(Replacers, TplNameMilady)
+		}
+	}()
+
+	g := &protobufGenerator{
+		moduleName: "user",
+		serverName: "user",
+		apiVersion: "v2",
+		codes: map[string]string{
+			parser.CodeTypeProto: `syntax = "proto3";
+
+package api.serverNameExample.v1;
+
+option go_package = "github.com/moweilong/milady/api/serverNameExample/v1;v1";
+`,
+			parser.TableName: "order",
+		},
+		outPath: t.TempDir(),
+	}
+	outPath, err := g.generateCode()
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(outPath, "api", "user", "v2", "order.proto"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "package api.user.v2;")
+	assert.Contains(t, string(data), `option go_package = "github.com/moweilong/milady/api/user/v2;v2";`)
+	assert.NotContains(t, string(data), "v1")
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	for _, version := range []string{"v1", "v2", "v10"} {
+		assert.NoError(t, checkAPIVersion(version))
+	}
+	for _, version := range []string{"", "1", "v0", "V2", "v2beta", "v/2"} {
+		assert.Error(t, checkAPIVersion(version), version)
+	}
+}
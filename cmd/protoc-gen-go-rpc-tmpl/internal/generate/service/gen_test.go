@@ -0,0 +1,31 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/moweilong/milady/cmd/protoc-gen-go-rpc-tmpl/internal/parse"
+)
+
+func TestGenServiceTmplFile(t *testing.T) {
+	pss := []*parse.PbService{
+		{
+			Name:         "Greeter",
+			LowerName:    "greeter",
+			ProtoName:    "greeter.proto",
+			ImportPkgMap: map[string]string{"greeterV1": `greeterV1 "example/api/greeter/v1"`},
+			ProtoFileDir: "api/greeter/v1",
+			ProtoPkgName: "greeterV1",
+			ModuleName:   "example",
+		},
+	}
+
+	content := string(genServiceTmplFile(pss))
+	embed := "type greeter struct {\n\tgreeterV1.UnimplementedGreeterServer\n"
+	if !strings.Contains(content, embed) {
+		t.Errorf("service struct does not embed the unimplemented server, got:\n%s", content)
+	}
+	if !strings.Contains(content, `greeterV1 "example/api/greeter/v1"`) {
+		t.Errorf("missing import of api service package, got:\n%s", content)
+	}
+}
@@ -0,0 +1,144 @@
+package goast
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CheckTypesInDir type-checks the go files (excluding test files) of the package in dir,
+// see CheckTypes for details.
+func CheckTypesInDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string][]byte)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		files[name] = data
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no go files in %s", dir)
+	}
+
+	return CheckTypes(files)
+}
+
+// CheckTypes type-checks the go files of one package with go/types, the key of files is file name.
+// It is best-effort, the standard library is imported from source, the external imports are stubbed
+// as empty packages, and the errors caused by referencing stubbed packages are ignored, so only
+// the errors which can be detected without external dependencies are reported.
+func CheckTypes(files map[string][]byte) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fset := token.NewFileSet()
+	astFiles := make([]*ast.File, 0, len(files))
+	stubNames := make(map[string]struct{})
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, files[name], parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		for _, spec := range f.Imports {
+			path := strings.Trim(spec.Path.Value, `"`)
+			if isStdImport(path) {
+				continue
+			}
+			localName := guessPackageName(path)
+			if spec.Name != nil {
+				localName = spec.Name.Name
+			}
+			stubNames[localName] = struct{}{}
+		}
+		astFiles = append(astFiles, f)
+	}
+
+	var errs []error
+	conf := types.Config{
+		Importer: &stubImporter{std: importer.ForCompiler(fset, "source", nil), packages: map[string]*types.Package{}},
+		Error: func(err error) {
+			if isStubError(err, stubNames) {
+				return
+			}
+			errs = append(errs, err)
+		},
+	}
+	_, _ = conf.Check(astFiles[0].Name.Name, fset, astFiles, nil)
+
+	return errors.Join(errs...)
+}
+
+// stubImporter import the standard library packages, other packages are stubbed as empty packages
+type stubImporter struct {
+	std      types.Importer
+	packages map[string]*types.Package
+}
+
+// Import implements types.Importer
+func (i *stubImporter) Import(path string) (*types.Package, error) {
+	if isStdImport(path) {
+		return i.std.Import(path)
+	}
+	if pkg, ok := i.packages[path]; ok {
+		return pkg, nil
+	}
+	pkg := types.NewPackage(path, guessPackageName(path))
+	pkg.MarkComplete()
+	i.packages[path] = pkg
+	return pkg, nil
+}
+
+// isStdImport return true if the import path is a standard library package, e.g. fmt, net/http
+func isStdImport(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// guessPackageName guess the package name from import path, e.g. gorm.io/gorm -> gorm,
+// github.com/golang-jwt/jwt/v5 -> jwt, gopkg.in/yaml.v3 -> yaml
+func guessPackageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	if before, _, ok := strings.Cut(name, ".v"); ok {
+		name = before
+	}
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// isStubError return true if the error is caused by referencing a stubbed package
+func isStubError(err error, stubNames map[string]struct{}) bool {
+	var typeErr types.Error
+	if !errors.As(err, &typeErr) {
+		return false
+	}
+	for name := range stubNames {
+		if strings.Contains(typeErr.Msg, name+".") {
+			return true
+		}
+	}
+	return false
+}
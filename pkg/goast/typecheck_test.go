@@ -0,0 +1,84 @@
+package goast
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var typeCheckModelCode = `package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserOrder struct {
+	ID        uint64     ` + "`gorm:\"column:id;primary_key\" json:\"id\"`" + `
+	OrderNo   string     ` + "`gorm:\"column:order_no\" json:\"orderNo\"`" + `
+	CreatedAt *time.Time ` + "`gorm:\"column:created_at\" json:\"createdAt\"`" + `
+}
+
+func (m *UserOrder) BeforeCreate(tx *gorm.DB) error {
+	if m.OrderNo == "" {
+		m.OrderNo = time.Now().Format("20060102150405")
+	}
+	return nil
+}
+`
+
+func TestCheckTypes(t *testing.T) {
+	// valid code, the references of external package are ignored
+	err := CheckTypes(map[string][]byte{"userOrder.go": []byte(typeCheckModelCode)})
+	assert.NoError(t, err)
+
+	// intentionally broken code
+	brokenCode := typeCheckModelCode + `
+func (m *UserOrder) TableName() string {
+	return m.OrderNumber
+}
+
+func (m *UserOrder) Age() int {
+	return m.CreatedAt
+}
+`
+	err = CheckTypes(map[string][]byte{"userOrder.go": []byte(brokenCode)})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "OrderNumber")
+	assert.Contains(t, err.Error(), "m.CreatedAt")
+
+	// syntax error
+	err = CheckTypes(map[string][]byte{"userOrder.go": []byte("package model\nfunc {")})
+	assert.Error(t, err)
+}
+
+func TestCheckTypesInDir(t *testing.T) {
+	dir := t.TempDir()
+	err := CheckTypesInDir(dir)
+	assert.Error(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "userOrder.go"), []byte(typeCheckModelCode), 0o666)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "userOrder_test.go"), []byte("package model_test\nfunc {"), 0o666)
+	assert.NoError(t, err)
+	err = CheckTypesInDir(dir)
+	assert.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "broken.go"), []byte("package model\n\nvar count int = \"1\"\n"), 0o666)
+	assert.NoError(t, err)
+	err = CheckTypesInDir(dir)
+	assert.Error(t, err)
+
+	err = CheckTypesInDir(filepath.Join(dir, "not_exist"))
+	assert.Error(t, err)
+}
+
+func Test_guessPackageName(t *testing.T) {
+	assert.Equal(t, "gorm", guessPackageName("gorm.io/gorm"))
+	assert.Equal(t, "jwt", guessPackageName("github.com/golang-jwt/jwt/v5"))
+	assert.Equal(t, "yaml", guessPackageName("gopkg.in/yaml.v3"))
+	assert.Equal(t, "go_redis", guessPackageName("github.com/foo/go-redis"))
+}
@@ -37,11 +37,62 @@ type TokenStore interface {
 	Count(ctx context.Context) (int, error)
 }
 
+// PrefixDeleter is an optional interface of TokenStore, it removes all tokens whose key has the prefix,
+// used to revoke the refresh tokens of a tenant
+type PrefixDeleter interface {
+	// DeleteByPrefix removes the tokens whose key starts with prefix
+	// Returns the number of tokens removed and any error encountered
+	DeleteByPrefix(ctx context.Context, prefix string) (int, error)
+}
+
+// ExpiryGetter is an optional interface of TokenStore, it returns the expiry of a stored token,
+// used to rotate the refresh token only when it is near expiry
+type ExpiryGetter interface {
+	// GetExpiry retrieves the expiration time of a refresh token
+	// Returns ErrRefreshTokenNotFound if token does't exist or is expired
+	GetExpiry(ctx context.Context, token string) (time.Time, error)
+}
+
+// SessionTracker is an optional interface of TokenStore, it records when each token was last used
+// and lists the stored tokens, used by session management
+type SessionTracker interface {
+	// Touch records the time when the refresh token was last used
+	// Returns ErrRefreshTokenNotFound if token does't exist or is expired
+	Touch(ctx context.Context, token string, lastUsed time.Time) error
+
+	// ListSessions returns the data of the active tokens whose key starts with prefix,
+	// the key of the map is the token key in store
+	ListSessions(ctx context.Context, prefix string) (map[string]*RefreshTokenData, error)
+}
+
+// UserTokenIndexer is an optional interface of TokenStore, it indexes the tokens by user,
+// used to revoke all the sessions of a user and to limit the sessions per user
+type UserTokenIndexer interface {
+	// AddUserToken adds the token to the index of the user, the entry is kept until the expiry of the token
+	AddUserToken(ctx context.Context, user string, token string, expiry time.Time) error
+
+	// ListUserTokens returns the data of the active tokens of the user,
+	// the key of the map is the token key in store
+	ListUserTokens(ctx context.Context, user string) (map[string]*RefreshTokenData, error)
+
+	// DeleteByUser removes all tokens of the user and the index of the user
+	// Returns the number of tokens removed and any error encountered
+	DeleteByUser(ctx context.Context, user string) (int, error)
+}
+
+// Unwrapper is implemented by the stores wrapping another TokenStore, e.g. a cache in front of it,
+// the wrapper supports an optional interface only if the wrapped store supports it too
+type Unwrapper interface {
+	// Unwrap returns the wrapped store
+	Unwrap() TokenStore
+}
+
 // RefreshTokenData holds the data stored with each refresh token
 type RefreshTokenData struct {
 	UserData any       `json:"user_data"`
 	Expiry   time.Time `json:"expiry"`
 	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used,omitzero"` // zero if the token is not used since created
 }
 
 // IsExpired checks if the token data has expired
@@ -0,0 +1,145 @@
+// Package grpc provides the grpc server interceptors which authenticate the requests with the config of
+// GinJWTMiddleware, the gin and grpc services share the same signing keys, revocation and authorization.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/moweilong/milady/pkg/jwt"
+)
+
+// the metadata key of the token, the value is "<TokenHeadName> <token>"
+const headerAuthorization = "authorization"
+
+type claimsContextKey struct{}
+
+// ClaimsFromContext returns the jwt claims injected by the interceptors
+func ClaimsFromContext(ctx context.Context) (gojwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(gojwt.MapClaims)
+	return claims, ok
+}
+
+// UnaryServerInterceptor returns the unary interceptor mirroring MiddlewareFunc, the token is read from
+// the authorization metadata, the claims are available by ClaimsFromContext in the handler
+func UnaryServerInterceptor(mw *jwt.GinJWTMiddleware) grpc.UnaryServerInterceptor {
+	engine := gin.New()
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		newCtx, err := authenticate(ctx, mw, engine, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+// StreamServerInterceptor returns the stream interceptor mirroring MiddlewareFunc
+func StreamServerInterceptor(mw *jwt.GinJWTMiddleware) grpc.StreamServerInterceptor {
+	engine := gin.New()
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, err := authenticate(stream.Context(), mw, engine, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: newCtx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate validates the token of the request and runs the checks of jwt.AuthorizeClaims, return the context
+// with claims. ClaimsValidator, IdentityHandler and Authorizer take the gin context, it is adapted from the grpc
+// request, the request path is the full method and the headers are the metadata.
+func authenticate(ctx context.Context, mw *jwt.GinJWTMiddleware, engine *gin.Engine, fullMethod string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokenString, err := tokenFromMD(mw, md)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	token, err := mw.ParseTokenString(tokenString)
+	if err != nil {
+		if errors.Is(err, gojwt.ErrTokenExpired) {
+			return nil, status.Error(codes.Unauthenticated, jwt.ErrExpiredToken.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	claims, ok := token.Claims.(gojwt.MapClaims)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid token claims type")
+	}
+
+	c := newGinContext(ctx, engine, fullMethod, md)
+	if _, err = mw.AuthorizeClaims(c, claims); err != nil {
+		if errors.Is(err, jwt.ErrForbidden) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return context.WithValue(ctx, claimsContextKey{}, claims), nil
+}
+
+// tokenFromMD get the token from the authorization metadata, the scheme is TokenHeadName
+func tokenFromMD(mw *jwt.GinJWTMiddleware, md metadata.MD) (string, error) {
+	values := md.Get(headerAuthorization)
+	if len(values) == 0 || values[0] == "" {
+		return "", jwt.ErrEmptyAuthHeader
+	}
+
+	parts := strings.SplitN(values[0], " ", 2)
+	if len(parts) != 2 {
+		return "", jwt.ErrInvalidAuthHeader
+	}
+	if mw.TokenHeadNameCaseInsensitive {
+		if !strings.EqualFold(parts[0], mw.TokenHeadName) {
+			return "", jwt.ErrInvalidAuthHeader
+		}
+		parts[1] = strings.TrimLeft(parts[1], " ")
+	} else if parts[0] != mw.TokenHeadName {
+		return "", jwt.ErrInvalidAuthHeader
+	}
+	if parts[1] == "" {
+		return "", jwt.ErrInvalidAuthHeader
+	}
+
+	return parts[1], nil
+}
+
+// newGinContext adapt the grpc request to gin context of engine, the callbacks of GinJWTMiddleware read it,
+// the response written by the callbacks is discarded
+func newGinContext(ctx context.Context, engine *gin.Engine, fullMethod string, md metadata.MD) *gin.Context {
+	header := make(http.Header, len(md))
+	for k, values := range md {
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Path: fullMethod},
+		Header: header,
+		Body:   http.NoBody,
+	}
+	c := gin.CreateTestContextOnly(httptest.NewRecorder(), engine)
+	c.Request = req.WithContext(ctx)
+	return c
+}
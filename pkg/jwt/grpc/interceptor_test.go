@@ -0,0 +1,180 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/moweilong/milady/pkg/jwt"
+)
+
+func newTestMiddleware(t *testing.T) *jwt.GinJWTMiddleware {
+	mw, err := jwt.New(&jwt.GinJWTMiddleware{
+		Realm:   "test zone",
+		Key:     []byte("secret key"),
+		Timeout: time.Hour,
+		PayloadFunc: func(data any) gojwt.MapClaims {
+			return gojwt.MapClaims{jwt.IdentityKey: data}
+		},
+		Authorizer: func(c *gin.Context, data any) bool {
+			// the request path is the full method of grpc
+			return data == "admin" || c.Request.URL.Path == healthpb.Health_Watch_FullMethodName
+		},
+	})
+	require.NoError(t, err)
+	return mw
+}
+
+// runServer runs the health server with the interceptors over bufconn, the claims of the requests are recorded
+func runServer(t *testing.T, mw *jwt.GinJWTMiddleware) (healthpb.HealthClient, <-chan gojwt.MapClaims) {
+	claimsCh := make(chan gojwt.MapClaims, 10)
+	recordUnary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		claims, _ := ClaimsFromContext(ctx)
+		claimsCh <- claims
+		return handler(ctx, req)
+	}
+	recordStream := func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		claims, _ := ClaimsFromContext(stream.Context())
+		claimsCh <- claims
+		return handler(srv, stream)
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(mw), recordUnary),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(mw), recordStream),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return healthpb.NewHealthClient(conn), claimsCh
+}
+
+func withToken(authorization string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", authorization)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	mw := newTestMiddleware(t)
+	client, claimsCh := runServer(t, mw)
+
+	token, err := mw.TokenGenerator(context.Background(), "admin")
+	require.NoError(t, err)
+	_, err = client.Check(withToken("Bearer "+token.AccessToken), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	claims := <-claimsCh
+	assert.Equal(t, "admin", claims[jwt.IdentityKey])
+
+	// the Authorizer rejects the user
+	token, err = mw.TokenGenerator(context.Background(), "guest")
+	require.NoError(t, err)
+	_, err = client.Check(withToken("Bearer "+token.AccessToken), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	for _, authorization := range []string{"", "Bearer", "Test " + token.AccessToken, "Bearer invalid"} {
+		_, err = client.Check(withToken(authorization), &healthpb.HealthCheckRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), authorization)
+	}
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Len(t, claimsCh, 0)
+}
+
+func TestUnaryServerInterceptor_Revoked(t *testing.T) {
+	mw := newTestMiddleware(t)
+	mw.AccessTokenBlacklist = true
+	client, _ := runServer(t, mw)
+
+	token, err := mw.TokenGenerator(context.Background(), "admin")
+	require.NoError(t, err)
+	ctx := withToken("Bearer " + token.AccessToken)
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+
+	parsed, err := mw.ParseTokenString(token.AccessToken)
+	require.NoError(t, err)
+	jti, _ := parsed.Claims.(gojwt.MapClaims)["jti"].(string)
+	require.NotEmpty(t, jti)
+	require.NoError(t, mw.RevokeAccessToken(context.Background(), jti, time.Now().Add(time.Hour)))
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestUnaryServerInterceptor_GinContext(t *testing.T) {
+	mw := newTestMiddleware(t)
+	// the callbacks can use the gin context like in the middleware
+	mw.ClaimsValidator = func(c *gin.Context, claims gojwt.MapClaims) error {
+		c.Header("X-User", "admin")
+		c.Set("validated", true)
+		c.Next()
+		return nil
+	}
+	mw.Authorizer = func(c *gin.Context, data any) bool {
+		if !c.GetBool("validated") {
+			return false
+		}
+		if data != "admin" {
+			c.AbortWithStatus(http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+	client, _ := runServer(t, mw)
+
+	token, err := mw.TokenGenerator(context.Background(), "admin")
+	require.NoError(t, err)
+	_, err = client.Check(withToken("Bearer "+token.AccessToken), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+
+	token, err = mw.TokenGenerator(context.Background(), "guest")
+	require.NoError(t, err)
+	_, err = client.Check(withToken("Bearer "+token.AccessToken), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	mw := newTestMiddleware(t)
+	client, claimsCh := runServer(t, mw)
+
+	token, err := mw.TokenGenerator(context.Background(), "guest")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(withToken("Bearer " + token.AccessToken))
+	defer cancel()
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	resp, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	claims := <-claimsCh
+	assert.Equal(t, "guest", claims[jwt.IdentityKey])
+
+	stream, err = client.Watch(withToken("Bearer invalid"), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -2,16 +2,33 @@ package jwt
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"math/big"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/youmark/pkcs8"
@@ -29,10 +46,23 @@ type GinJWTMiddleware struct {
 	// Realm name to display to the user. Required.
 	Realm string
 
-	// signing algorithm - possible values are HS256, HS384, HS512, RS256, RS384 or RS512
+	// RealmFunc returns the realm of the current request, e.g. derived from the tenant host.
+	// Optional, Realm is used when it is nil or returns an empty string.
+	RealmFunc func(c *gin.Context) string
+
+	// signing algorithm - possible values are HS256, HS384, HS512, RS256, RS384, RS512,
+	// ES256, ES384, ES512 or EdDSA (Ed25519)
 	// Optional, default is HS256.
 	SigningAlgorithm string
 
+	// TokenType is the typ header of the generated access tokens.
+	// Optional, default is JWT.
+	TokenType string
+
+	// RequireTyp rejects the tokens whose typ header is missing or not TokenType (case-insensitive),
+	// so the tokens of the same key but another purpose are not accepted as access tokens.
+	RequireTyp bool
+
 	// Secret key used for signing. Required.
 	Key []byte
 
@@ -40,6 +70,23 @@ type GinJWTMiddleware struct {
 	// all other key settings
 	KeyFunc func(token *jwt.Token) (any, error)
 
+	// StrictKeyConfig makes MiddlewareInit return ErrConflictingKeyConfig when KeyFunc is set together with
+	// Key or the key files/bytes, otherwise only a warning is logged. Optional, default is false.
+	StrictKeyConfig bool
+
+	// TrustedUnsigned decodes the tokens without verifying the signature when the request comes from a trusted
+	// gateway which already validated them, the exp and other time based claims are still enforced.
+	// It only takes effect for the requests whose TrustedGatewayHeader equals TrustedGatewayValue,
+	// MiddlewareInit returns ErrMissingTrustedGateway if either of them is empty. Optional, default is false.
+	TrustedUnsigned bool
+
+	// TrustedGatewayHeader the header name set by the trusted gateway, required when TrustedUnsigned is true.
+	// The gateway must strip this header from the client requests.
+	TrustedGatewayHeader string
+
+	// TrustedGatewayValue the shared secret value of TrustedGatewayHeader, required when TrustedUnsigned is true
+	TrustedGatewayValue string
+
 	// Duration that a jwt token is valid. Optional, defaults to one hour.
 	Timeout time.Duration
 	// Callback function that will override the default timeout duration.
@@ -51,19 +98,39 @@ type GinJWTMiddleware struct {
 	// Optional, defaults to 0 meaning not refreshable.
 	MaxRefresh time.Duration
 
+	// Tokens whose orig_iat is earlier than NotBeforeIssuedAt are rejected, it can be used to
+	// invalidate all tokens issued before a revocation cutoff without a per-token denylist.
+	// Optional, defaults to zero time meaning no cutoff.
+	NotBeforeIssuedAt time.Time
+
 	// Callback function that should perform the authentication of the user based on login info.
 	// Must return user data as user identifier, it will be stored in Claim Array. Required.
 	// Check error (e) to determine the appropriate error message.
 	Authenticator func(c *gin.Context) (any, error)
 
+	// Authenticators are tried in order before Authenticator, the first one which returns no error wins,
+	// so the same login endpoint supports multiple methods, such as password and api key.
+	// Authenticators reading the request body should use c.ShouldBindBodyWith so the body can be read again.
+	// Optional, Authenticator is required when it is empty.
+	Authenticators []func(c *gin.Context) (any, error)
+
 	// Callback function that should perform the authorization of the authenticated user. Called
 	// only after an authentication success. Must return true on success, false on failure.
 	// Optional, default to success.
 	Authorizer func(c *gin.Context, data any) bool
 
+	// ClaimsValidator enforces custom rules on the claims of the access token, e.g. the tenant_id claim
+	// must match the request host. Called before Authorizer, a non-nil error responds 401 with the message
+	// of HTTPStatusMessageFunc. Optional.
+	ClaimsValidator func(c *gin.Context, claims jwt.MapClaims) error
+
+	// HTTP status code returned when Authorizer fails, e.g. 404 to avoid leaking
+	// the existence of resources. Optional, defaults to http.StatusForbidden.
+	ForbiddenStatusCode int
+
 	// Callback function that will be called during login.
 	// Using this function it is possible to add additional payload data to the webtoken.
-	// The data is then made available during requests via c.Get("JWT_PAYLOAD").
+	// The data is then made available during requests via c.Get(PayloadContextKey).
 	// Note that the payload is not encrypted.
 	// The attributes mentioned on jwt.io can't be used as keys for the map.
 	// Optional, by default no additional data will be set.
@@ -81,12 +148,36 @@ type GinJWTMiddleware struct {
 	// User can define own RefreshResponse func.
 	RefreshResponse func(c *gin.Context, token *core.Token)
 
+	// MetricsCollector collects the counts of issued, refreshed and rejected tokens and the latency of the
+	// refresh token store, NewPrometheusMetricsCollector provides a prometheus adapter.
+	// Optional, no metrics are collected when it is nil.
+	MetricsCollector MetricsCollector
+
+	// OnLogin is called after a successful login with the identity of the user data, e.g. for audit logging.
+	// The identity is the IdentityKey claim of PayloadFunc if present, otherwise the data of Authenticator.
+	// The hooks are called after the response is written, so they can not change the response. Optional.
+	OnLogin func(c *gin.Context, identity any)
+
+	// OnLoginFailed is called after a failed login with the error of the authenticator or token creation. Optional.
+	OnLoginFailed func(c *gin.Context, err error)
+
+	// OnRefresh is called after the token is refreshed with the identity of the refresh token data. Optional.
+	OnRefresh func(c *gin.Context, identity any)
+
+	// OnLogout is called after logout with the identity of the access token,
+	// the identity is nil if the request has no valid access token. Optional.
+	OnLogout func(c *gin.Context, identity any)
+
 	// Set the identity handler function
 	IdentityHandler func(*gin.Context) any
 
 	// Set the identity key
 	IdentityKey string
 
+	// FallbackIdentityKeys the claim keys tried in order by the default IdentityHandler
+	// when the token has no IdentityKey claim, e.g. "sub" for tokens of another issuer
+	FallbackIdentityKeys []string
+
 	// TokenLookup is a string in the form of "<source>:<name>" that is used
 	// to extract token from the request.
 	// Optional. Default value "header:Authorization".
@@ -99,6 +190,11 @@ type GinJWTMiddleware struct {
 	// TokenHeadName is a string in the header. Default value is "Bearer"
 	TokenHeadName string
 
+	// TokenHeadNameCaseInsensitive compares the scheme of the header with TokenHeadName case-insensitively,
+	// e.g. "bearer" and "BEARER" are accepted, and tolerates repeated spaces between the scheme and the token.
+	// Default is false, the scheme must equal TokenHeadName exactly.
+	TokenHeadNameCaseInsensitive bool
+
 	// TimeFunc provides the current time. You can override it to use another time value. This is useful for testing or if your server uses a different time zone than your tokens.
 	TimeFunc func() time.Time
 
@@ -125,11 +221,32 @@ type GinJWTMiddleware struct {
 	// Note: PubKeyFile takes precedence over PubKeyBytes if both are set
 	PubKeyBytes []byte
 
-	// Private key
-	privKey *rsa.PrivateKey
+	// Directory of public key PEM files for asymmetric algorithms, all keys in the directory
+	// are loaded and exposed in JWKS, a token signed by any of them is accepted.
+	//
+	// Note: the key of PubKeyFile or PubKeyBytes is still used if set
+	PubKeyDir string
+
+	// WatchKeyFiles reloads the keys when PrivKeyFile or PubKeyFile changes, so the mounted key files
+	// can be rotated without restarting, the current keys are kept if the changed files are invalid.
+	// Call CloseKeyWatcher to stop watching.
+	WatchKeyFiles bool
+
+	// guards the keys reloaded by the key file watcher
+	keyMu      sync.RWMutex
+	keyWatcher *fsnotify.Watcher
+
+	// Private key, *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey
+	privKey crypto.PrivateKey
+
+	// Key id of the private key, set as the kid header of the generated tokens
+	privKeyID string
+
+	// Public key, *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+	pubKey crypto.PublicKey
 
-	// Public key
-	pubKey *rsa.PublicKey
+	// All public keys used for verification, including pubKey and the keys in PubKeyDir
+	pubKeys []publicKeyEntry
 
 	// Optionally return the token as a cookie
 	SendCookie bool
@@ -137,6 +254,11 @@ type GinJWTMiddleware struct {
 	// Duration that a cookie is valid. Optional, by default equals to Timeout value.
 	CookieMaxAge time.Duration
 
+	// CookieMaxAgeFunc returns the duration that the cookie of the current request is valid, such as a longer one
+	// for remember-me, the Authenticator can mark the request by c.Set and the func reads it.
+	// Optional, CookieMaxAge is used when it is nil or returns a non-positive duration.
+	CookieMaxAgeFunc func(c *gin.Context) time.Duration
+
 	// Allow insecure cookies for development over http
 	SecureCookie bool
 
@@ -158,22 +280,68 @@ type GinJWTMiddleware struct {
 	// CookieSameSite allow use http.SameSite cookie param
 	CookieSameSite http.SameSite
 
+	// RefreshCookieName is the name of the cookie holding the refresh token, RefreshHandler and
+	// LogoutHandler read the refresh token from it if the request has no refresh_token parameter.
+	// If SendCookie is true, the refresh token is also sent as an HttpOnly cookie on login and refresh.
+	// Optional, defaults to "" meaning the refresh token is not read from or set to cookie.
+	RefreshCookieName string
+
 	// ParseOptions allow to modify jwt's parser methods.
 	// WithTimeFunc is always added to ensure the TimeFunc is propagated to the validator
 	ParseOptions []jwt.ParserOption
 
+	// Audience is set as the aud claim of the access tokens, e.g. the services which the tokens are issued for.
+	// Optional, the aud claim is not set if empty.
+	Audience []string
+
+	// ExpectedAudience rejects the access tokens whose aud claim does not include any of the audiences,
+	// e.g. the name of this service. Optional, the aud claim is not validated if empty.
+	ExpectedAudience []string
+
+	// UseJSONNumber decodes numeric claims as json.Number instead of float64,
+	// use ClaimInt64 to read numeric claims in both modes
+	UseJSONNumber bool
+
 	// Default value is "exp"
 	// Deprecated
 	ExpField string
 
+	// MirrorExpField copies the expiry into an additional claim with this name for consumers which
+	// expect a nonstandard expiry claim, the standard exp claim is still set and validated.
+	// Optional, no mirror claim is added when it is empty.
+	MirrorExpField string
+
 	// RefreshTokenTimeout specifies how long refresh tokens are valid
 	// Defaults to 30 days if not set
 	RefreshTokenTimeout time.Duration
 
+	// RefreshRotateThreshold rotates the refresh token on refresh only when it expires within the threshold,
+	// otherwise the existing refresh token is returned with the new access token.
+	// Optional, the refresh token is rotated on every refresh when it is 0 or the store does not implement
+	// core.ExpiryGetter.
+	RefreshRotateThreshold time.Duration
+
+	// TrackLastUsed records the time when a refresh token is last validated, the sessions with the last used
+	// time are listed by ListSessions. The RefreshTokenStore must implement core.SessionTracker, both built-in
+	// stores implement it, otherwise the time is not recorded.
+	TrackLastUsed bool
+
+	// MaxSessionsPerUser limits the active refresh tokens of a user, the oldest tokens are revoked when a new
+	// token exceeds the limit. The RefreshTokenStore must implement core.UserTokenIndexer, both built-in stores
+	// implement it, otherwise the sessions are not limited.
+	// Optional, the sessions are not limited when it is 0.
+	MaxSessionsPerUser int
+
 	// RefreshTokenStore interface for storing and retrieving refresh tokens
 	// If nil, an in-memory store will be used
 	RefreshTokenStore core.TokenStore
 
+	// StoreTimeout limits each call of RefreshTokenStore, including the revoked access tokens, the refresh token
+	// families and the user index, so an outage of the store does not block the handlers, the call exceeding
+	// the timeout returns ErrStoreTimeout.
+	// Optional, the store calls are limited by the request context only when it is 0.
+	StoreTimeout time.Duration
+
 	// RefreshTokenLength specifies the byte length of refresh tokens (default: 32)
 	RefreshTokenLength int
 
@@ -185,10 +353,64 @@ type GinJWTMiddleware struct {
 	// If nil when UseRedisStore is true, will use default Redis configuration
 	RedisConfig *store.RedisConfig
 
+	// PostgresDB enables the Postgres store when set, the refresh tokens are saved in the table of PostgresOptions
+	// The store is preferred over Redis and falls back to in-memory store if the table cannot be created
+	PostgresDB *sql.DB
+
+	// PostgresOptions configuration for Postgres store when PostgresDB is set
+	PostgresOptions []store.PostgresOption
+
+	// RefreshDataCodec controls how user data is serialized into the refresh token store,
+	// so complex identity structs round-trip regardless of the store implementation.
+	// Optional, by default user data is passed to the store as is.
+	RefreshDataCodec *RefreshDataCodec
+
+	// TenantFunc returns the tenant of the current request, the refresh token store keys are prefixed with
+	// the tenant, so the tokens of tenants are isolated and can be revoked by RevokeAllForTenant.
+	// Optional, tokens are not namespaced when it is nil or returns an empty string.
+	TenantFunc func(c *gin.Context) string
+
+	// HashRefreshAtRest stores the sha256 of refresh tokens as the store keys, the clients hold the raw tokens,
+	// so a store compromise does not leak usable tokens. Changing it invalidates the issued refresh tokens.
+	HashRefreshAtRest bool
+
+	// AccessTokenBlacklist enables revoking access tokens before they expire, LogoutHandler revokes the jti of
+	// the access token and the middleware rejects the revoked tokens. The revoked jti are kept in
+	// RefreshTokenStore until the original exp of the tokens.
+	AccessTokenBlacklist bool
+
+	// RefreshTokenReuseDetection detects the replay of rotated refresh tokens, each refresh token belongs to the
+	// family of the login, when a rotated token is presented again, the token may be stolen, the whole family is
+	// revoked and ErrInvalidRefreshToken is returned. The families are kept in RefreshTokenStore.
+	RefreshTokenReuseDetection bool
+
+	// StatelessRefreshToken issues the refresh tokens as JWTs signed with RefreshSigningKey instead of keeping them
+	// in RefreshTokenStore, the user data is carried in the claims of the refresh JWT whose typ claim is "refresh".
+	// The refresh JWTs rotated on refresh or revoked on logout are recorded by jti in RefreshTokenStore until they
+	// expire, like the access tokens of AccessTokenBlacklist, so no store is needed for the active sessions.
+	// RevokeAllForUser and RefreshTokenReuseDetection do not apply to them.
+	StatelessRefreshToken bool
+
+	// RefreshSigningKey the HMAC key signing the refresh JWTs when StatelessRefreshToken is on, it should differ
+	// from Key, so the access tokens are never accepted as refresh tokens. Required by StatelessRefreshToken.
+	RefreshSigningKey []byte
+
+	// RefreshSigningAlgorithm the signing algorithm of the refresh JWTs, HS256, HS384 or HS512.
+	// Optional, default HS256.
+	RefreshSigningAlgorithm string
+
 	// inMemoryStore internal fallback refresh token store
 	inMemoryStore *store.InMemoryRefreshTokenStore
 }
 
+// RefreshDataCodec marshal and unmarshal the user data stored with refresh tokens
+type RefreshDataCodec struct {
+	// Marshal serializes user data before it is saved to the store
+	Marshal func(data any) ([]byte, error)
+	// Unmarshal reconstructs the typed user data read from the store
+	Unmarshal func(data []byte) (any, error)
+}
+
 var (
 	// ErrMissingSecretKey indicates Secret key is required
 	ErrMissingSecretKey = errors.New("secret key is required")
@@ -219,6 +441,9 @@ var (
 	// ErrMissingExpField missing exp field in token
 	ErrMissingExpField = errors.New("missing exp field")
 
+	// ErrTokenIssuedBeforeCutoff indicates the token was issued before NotBeforeIssuedAt
+	ErrTokenIssuedBeforeCutoff = errors.New("token issued before revocation cutoff")
+
 	// ErrWrongFormatOfExp field must be float64 format
 	ErrWrongFormatOfExp = errors.New("exp must be float64 format")
 
@@ -234,21 +459,68 @@ var (
 	// ErrEmptyParamToken can be thrown if authing with parameter in path, the parameter in path is empty
 	ErrEmptyParamToken = errors.New("parameter token is empty")
 
-	// ErrInvalidSigningAlgorithm indicates signing algorithm is invalid, needs to be HS256, HS384, HS512, RS256, RS384 or RS512
+	// ErrInvalidSigningAlgorithm indicates signing algorithm is invalid, needs to be HS256, HS384, HS512, RS256, RS384, RS512, ES256, ES384, ES512 or EdDSA
 	ErrInvalidSigningAlgorithm = errors.New("invalid signing algorithm")
 
+	// ErrInvalidTokenType indicates the typ header of the token is not TokenType when RequireTyp is set
+	ErrInvalidTokenType = errors.New("invalid token type")
+
+	// ErrUnsupportedCritHeader indicates the token lists critical header extensions which are not understood
+	ErrUnsupportedCritHeader = errors.New("unsupported critical header")
+
 	// ErrNoPrivKeyFile indicates that the given private key is unreadable
 	ErrNoPrivKeyFile = errors.New("private key file unreadable")
 
 	// ErrNoPubKeyFile indicates that the given public key is unreadable
 	ErrNoPubKeyFile = errors.New("public key file unreadable")
 
+	// ErrMissingTrustedGateway indicates TrustedUnsigned is enabled without TrustedGatewayHeader or TrustedGatewayValue
+	ErrMissingTrustedGateway = errors.New("TrustedUnsigned requires TrustedGatewayHeader and TrustedGatewayValue")
+
+	// ErrConflictingKeyConfig indicates KeyFunc is set together with other key settings under StrictKeyConfig
+	ErrConflictingKeyConfig = errors.New("KeyFunc conflicts with Key, PrivKeyFile, PrivKeyBytes, PubKeyFile, PubKeyBytes or PubKeyDir")
+
+	// ErrMissingTenant indicates the tenant is empty when revoking tokens of a tenant
+	ErrMissingTenant = errors.New("tenant is empty")
+
+	// ErrPrefixDeleteNotSupported indicates the refresh token store does not implement core.PrefixDeleter
+	ErrPrefixDeleteNotSupported = store.ErrPrefixDeleteNotSupported
+
+	// ErrSessionTrackingNotSupported indicates the refresh token store does not implement core.SessionTracker
+	ErrSessionTrackingNotSupported = store.ErrSessionTrackingNotSupported
+
+	// ErrUserIndexNotSupported indicates the refresh token store does not implement core.UserTokenIndexer
+	ErrUserIndexNotSupported = store.ErrUserIndexNotSupported
+
+	// ErrMissingIdentity indicates the access token has no identity when logging out all sessions of the user
+	ErrMissingIdentity = errors.New("missing identity")
+
+	// ErrRevokedToken indicates the access token has been revoked by RevokeAccessToken
+	ErrRevokedToken = errors.New("token has been revoked")
+
+	// ErrMissingJTI indicates the jti of the access token to revoke is empty
+	ErrMissingJTI = errors.New("missing jti")
+
+	// ErrStoreTimeout indicates the refresh token store call exceeds StoreTimeout
+	ErrStoreTimeout = errors.New("refresh token store timeout")
+
+	// ErrMissingRefreshSigningKey indicates StatelessRefreshToken is enabled without RefreshSigningKey
+	ErrMissingRefreshSigningKey = errors.New("StatelessRefreshToken requires RefreshSigningKey")
+
+	// ErrNoPubKeyDir indicates that the given public key directory is unreadable or has no key
+	ErrNoPubKeyDir = errors.New("public key directory unreadable or empty")
+
 	// ErrInvalidPrivKey indicates that the given private key is invalid
 	ErrInvalidPrivKey = errors.New("private key invalid")
 
 	// ErrInvalidPubKey indicates the the given public key is invalid
 	ErrInvalidPubKey = errors.New("public key invalid")
 
+	// ErrKeyAlgorithmMismatch indicates the type of the given key does not match the signing algorithm,
+	// RS256, RS384 and RS512 require RSA keys, ES256, ES384 and ES512 require ECDSA keys of the P-256, P-384
+	// and P-521 curves, EdDSA requires Ed25519 keys
+	ErrKeyAlgorithmMismatch = errors.New("key type does not match the signing algorithm")
+
 	// IdentityKey default identity key
 	IdentityKey = "identity"
 
@@ -278,6 +550,10 @@ func (mw *GinJWTMiddleware) MiddlewareInit() error {
 		mw.SigningAlgorithm = "HS256"
 	}
 
+	if mw.TokenType == "" {
+		mw.TokenType = "JWT"
+	}
+
 	if mw.Timeout == 0 {
 		mw.Timeout = time.Hour
 	}
@@ -341,7 +617,15 @@ func (mw *GinJWTMiddleware) MiddlewareInit() error {
 	if mw.IdentityHandler == nil {
 		mw.IdentityHandler = func(c *gin.Context) any {
 			claims := ExtractClaims(c)
-			return claims[mw.IdentityKey]
+			if identity, ok := claims[mw.IdentityKey]; ok {
+				return identity
+			}
+			for _, key := range mw.FallbackIdentityKeys {
+				if identity, ok := claims[key]; ok {
+					return identity
+				}
+			}
+			return nil
 		}
 	}
 
@@ -355,6 +639,10 @@ func (mw *GinJWTMiddleware) MiddlewareInit() error {
 		mw.Realm = "milady jwt"
 	}
 
+	if mw.ForbiddenStatusCode == 0 {
+		mw.ForbiddenStatusCode = http.StatusForbidden
+	}
+
 	if mw.CookieMaxAge == 0 {
 		mw.CookieMaxAge = mw.Timeout
 	}
@@ -376,12 +664,27 @@ func (mw *GinJWTMiddleware) MiddlewareInit() error {
 		mw.RefreshTokenLength = 32 // 256 bits default
 	}
 
+	if mw.StatelessRefreshToken {
+		if len(mw.RefreshSigningKey) == 0 {
+			return ErrMissingRefreshSigningKey
+		}
+		if mw.RefreshSigningAlgorithm == "" {
+			mw.RefreshSigningAlgorithm = "HS256"
+		}
+		if _, ok := jwt.GetSigningMethod(mw.RefreshSigningAlgorithm).(*jwt.SigningMethodHMAC); !ok {
+			return ErrInvalidSigningAlgorithm
+		}
+	}
+
 	if mw.RefreshTokenStore == nil {
 		// Initialize in-memory store first (will be used as fallback)
 		mw.inMemoryStore = store.NewInMemoryRefreshTokenStore()
 
-		// Try to initialize Redis store if enabled
-		mw.initializeRedisStore()
+		// Try to initialize Postgres or Redis store if enabled
+		mw.initializePostgresStore()
+		if mw.RefreshTokenStore == nil {
+			mw.initializeRedisStore()
+		}
 
 		// If Redis initialization didn't set a store, use in-memory
 		if mw.RefreshTokenStore == nil {
@@ -389,13 +692,33 @@ func (mw *GinJWTMiddleware) MiddlewareInit() error {
 		}
 	}
 
+	if mw.TrustedUnsigned {
+		if mw.TrustedGatewayHeader == "" || mw.TrustedGatewayValue == "" {
+			return ErrMissingTrustedGateway
+		}
+		log.Printf("Warning: TrustedUnsigned is enabled, token signatures are not verified for requests with header %s",
+			mw.TrustedGatewayHeader)
+	}
+
 	// bypass other key settings if KeyFunc is set
 	if mw.KeyFunc != nil {
+		if mw.hasKeyConfig() {
+			if mw.StrictKeyConfig {
+				return ErrConflictingKeyConfig
+			}
+			log.Printf("Warning: KeyFunc is set, Key and key files/bytes settings are ignored")
+		}
 		return nil
 	}
 
 	if mw.usingPublicKeyAlgo() {
-		return mw.readKeys()
+		if err := mw.readKeys(); err != nil {
+			return err
+		}
+		if mw.WatchKeyFiles {
+			return mw.watchKeyFiles()
+		}
+		return nil
 	}
 
 	if mw.Key == nil {
@@ -410,6 +733,56 @@ func (mw *GinJWTMiddleware) MiddlewareInit() error {
 	return nil
 }
 
+// Warmup eagerly connects the refresh token store and verifies the keys are loadable,
+// call it after New to fail fast at boot instead of on the first request.
+// Unlike MiddlewareInit, it returns an error rather than falling back to the in-memory store
+// when UseRedisStore is enabled and Redis is unreachable, or the table of PostgresDB cannot be created.
+func (mw *GinJWTMiddleware) Warmup(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if mw.PostgresDB != nil && (mw.RefreshTokenStore == nil || mw.RefreshTokenStore == core.TokenStore(mw.inMemoryStore)) {
+		postgresStore, err := store.NewPostgresRefreshTokenStore(mw.PostgresDB, mw.PostgresOptions...)
+		if err != nil {
+			return err
+		}
+		mw.RefreshTokenStore = postgresStore
+	}
+
+	if mw.UseRedisStore && (mw.RefreshTokenStore == nil || mw.RefreshTokenStore == core.TokenStore(mw.inMemoryStore)) {
+		redisStore, err := store.NewRedisRefreshTokenStore(mw.RedisConfig)
+		if err != nil {
+			return err
+		}
+		mw.RefreshTokenStore = redisStore
+	}
+
+	if p, ok := mw.RefreshTokenStore.(interface{ Ping() error }); ok {
+		errCh := make(chan error, 1)
+		go func() { errCh <- p.Ping() }()
+		select {
+		case err := <-errCh:
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if mw.KeyFunc != nil {
+		return nil
+	}
+	if mw.usingPublicKeyAlgo() {
+		return mw.readKeys()
+	}
+	if mw.Key == nil {
+		return ErrMissingSecretKey
+	}
+	return nil
+}
+
 // generateTokenResponse creates a RFC 6749 compliant token response with refresh token
 func (mw *GinJWTMiddleware) generateTokenResponse(_ *gin.Context, token *core.Token) gin.H {
 	response := gin.H{
@@ -426,9 +799,22 @@ func (mw *GinJWTMiddleware) generateTokenResponse(_ *gin.Context, token *core.To
 	return response
 }
 
+// the gin context keys set by the middleware, use them instead of the string literals
+const (
+	// PayloadContextKey the gin context key of the jwt claims, they are read by ExtractClaims
+	PayloadContextKey = "JWT_PAYLOAD"
+
+	// TokenContextKey the gin context key of the raw jwt token string, it is read by GetToken
+	TokenContextKey = "JWT_TOKEN"
+
+	// TokenSourceContextKey the gin context key of the TokenLookup source which the token is read from,
+	// it is read by GetTokenSource
+	TokenSourceContextKey = "JWT_TOKEN_SOURCE"
+)
+
 // ExtractClaims help to extract the JWT claims
 func ExtractClaims(c *gin.Context) jwt.MapClaims {
-	claims, exists := c.Get("JWT_PAYLOAD")
+	claims, exists := c.Get(PayloadContextKey)
 	if !exists {
 		return make(jwt.MapClaims)
 	}
@@ -441,19 +827,101 @@ func ExtractClaims(c *gin.Context) jwt.MapClaims {
 	return mapClaims
 }
 
+// GetTokenSource returns the TokenLookup source which the token of the request is read from,
+// e.g. "header" or "cookie", it is empty if no source has the token
+func GetTokenSource(c *gin.Context) string {
+	return c.GetString(TokenSourceContextKey)
+}
+
+// hasKeyConfig return true if any key setting other than KeyFunc is set
+func (mw *GinJWTMiddleware) hasKeyConfig() bool {
+	return len(mw.Key) > 0 || mw.PrivKeyFile != "" || len(mw.PrivKeyBytes) > 0 ||
+		mw.PubKeyFile != "" || len(mw.PubKeyBytes) > 0 || mw.PubKeyDir != ""
+}
+
 func (mw *GinJWTMiddleware) usingPublicKeyAlgo() bool {
 	switch mw.SigningAlgorithm {
-	case "RS256", "RS512", "RS384":
+	case "RS256", "RS512", "RS384", "ES256", "ES384", "ES512", "EdDSA":
 		return true
 	}
 	return false
 }
 
+// checkKeyType check the private or public key matches the signing algorithm
+func (mw *GinJWTMiddleware) checkKeyType(key any) error {
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		if strings.HasPrefix(mw.SigningAlgorithm, "RS") {
+			return nil
+		}
+	case *ecdsa.PrivateKey:
+		return mw.checkCurve(k.Curve.Params().Name)
+	case *ecdsa.PublicKey:
+		return mw.checkCurve(k.Curve.Params().Name)
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		if mw.SigningAlgorithm == "EdDSA" {
+			return nil
+		}
+	}
+	return ErrKeyAlgorithmMismatch
+}
+
+// checkCurve check the curve of ECDSA key matches the signing algorithm
+func (mw *GinJWTMiddleware) checkCurve(curve string) error {
+	if ecdsaCurves[mw.SigningAlgorithm] != curve {
+		return ErrKeyAlgorithmMismatch
+	}
+	return nil
+}
+
+// ecdsaCurves the curve required by the ECDSA signing algorithm
+var ecdsaCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+// parsePrivateKeyFromPEM parse a RSA, ECDSA or Ed25519 private key from PEM
+func parsePrivateKeyFromPEM(keyData []byte) (crypto.PrivateKey, error) {
+	rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyData)
+	if err == nil {
+		return rsaKey, nil
+	}
+	ecKey, err := jwt.ParseECPrivateKeyFromPEM(keyData)
+	if err == nil {
+		return ecKey, nil
+	}
+	return jwt.ParseEdPrivateKeyFromPEM(keyData)
+}
+
+// parsePublicKeyFromPEM parse a RSA, ECDSA or Ed25519 public key from PEM
+func parsePublicKeyFromPEM(keyData []byte) (crypto.PublicKey, error) {
+	rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(keyData)
+	if err == nil {
+		return rsaKey, nil
+	}
+	ecKey, err := jwt.ParseECPublicKeyFromPEM(keyData)
+	if err == nil {
+		return ecKey, nil
+	}
+	return jwt.ParseEdPublicKeyFromPEM(keyData)
+}
+
 func (mw *GinJWTMiddleware) readKeys() error {
+	mw.keyMu.Lock()
+	defer mw.keyMu.Unlock()
+	return mw.loadKeys()
+}
+
+func (mw *GinJWTMiddleware) loadKeys() error {
 	err := mw.privateKey()
 	if err != nil {
 		return err
 	}
+	mw.privKeyID = ""
+	if signer, ok := mw.privKey.(crypto.Signer); ok {
+		mw.privKeyID = publicKeyID(signer.Public())
+	}
 
 	err = mw.publicKey()
 	if err != nil {
@@ -491,24 +959,27 @@ func (mw *GinJWTMiddleware) privateKey() error {
 		if err != nil {
 			return ErrInvalidPrivKey
 		}
-		rsaKey, ok := key.(*rsa.PrivateKey)
-		if !ok {
-			return ErrInvalidPrivKey
+		if err = mw.checkKeyType(key); err != nil {
+			return err
 		}
-		mw.privKey = rsaKey
+		mw.privKey = key
 		return nil
 	}
 
-	var key *rsa.PrivateKey
-	key, err = jwt.ParseRSAPrivateKeyFromPEM(keyData)
+	key, err := parsePrivateKeyFromPEM(keyData)
 	if err != nil {
 		return ErrInvalidPrivKey
 	}
+	if err = mw.checkKeyType(key); err != nil {
+		return err
+	}
 	mw.privKey = key
 	return nil
 }
 
 func (mw *GinJWTMiddleware) publicKey() error {
+	mw.pubKeys = nil
+
 	var keyData []byte
 	if mw.PubKeyFile == "" {
 		keyData = mw.PubKeyBytes
@@ -522,106 +993,405 @@ func (mw *GinJWTMiddleware) publicKey() error {
 		keyData = filecontent
 	}
 
-	key, err := jwt.ParseRSAPublicKeyFromPEM(keyData)
-	if err != nil {
-		return ErrInvalidPubKey
+	if len(keyData) > 0 || mw.PubKeyDir == "" {
+		key, err := parsePublicKeyFromPEM(keyData)
+		if err != nil {
+			return ErrInvalidPubKey
+		}
+		if err = mw.checkKeyType(key); err != nil {
+			return err
+		}
+		mw.pubKey = key
+		mw.addPublicKey(key)
+	}
+
+	if mw.PubKeyDir != "" {
+		if err := mw.publicKeysFromDir(); err != nil {
+			return err
+		}
+		if mw.pubKey == nil {
+			mw.pubKey = mw.pubKeys[0].key
+		}
 	}
-	mw.pubKey = key
+
 	return nil
 }
 
-// MiddlewareFunc makes GinJWTMiddleware implement the Middleware interface.
-func (mw *GinJWTMiddleware) MiddlewareFunc() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		mw.middlewareImpl(c)
+// watchKeyFiles watch PrivKeyFile and PubKeyFile and reload the keys when they change, the directories
+// of the files are watched because the mounted secrets are rotated by replacing the symlinks
+func (mw *GinJWTMiddleware) watchKeyFiles() error {
+	if mw.keyWatcher != nil || (mw.PrivKeyFile == "" && mw.PubKeyFile == "") {
+		return nil
 	}
-}
 
-func (mw *GinJWTMiddleware) middlewareImpl(c *gin.Context) {
-	claims, err := mw.GetClaimsFromJWT(c)
+	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		mw.handleTokenError(c, err)
-		return
+		return err
+	}
+	for _, file := range []string{mw.PrivKeyFile, mw.PubKeyFile} {
+		if file == "" {
+			continue
+		}
+		if err = watcher.Add(filepath.Dir(file)); err != nil {
+			_ = watcher.Close()
+			return err
+		}
 	}
+	mw.keyWatcher = watcher
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				mw.reloadKeys()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Key file watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
 
-	// For backwards compatibility since technically exp is not required in the spec but has been in gin-jwt
-	if claims["exp"] == nil {
-		mw.unauthorized(c, http.StatusBadRequest, mw.HTTPStatusMessageFunc(c, ErrMissingExpField))
-		return
+// reloadKeys read the key files again, the current keys are kept if the files are invalid,
+// for example a file is being written
+func (mw *GinJWTMiddleware) reloadKeys() {
+	mw.keyMu.Lock()
+	defer mw.keyMu.Unlock()
+
+	privKey, privKeyID, pubKey, pubKeys := mw.privKey, mw.privKeyID, mw.pubKey, mw.pubKeys
+	if err := mw.loadKeys(); err != nil {
+		log.Printf("Failed to reload key files, the current keys are kept: %v", err)
+		mw.privKey, mw.privKeyID, mw.pubKey, mw.pubKeys = privKey, privKeyID, pubKey, pubKeys
 	}
+}
 
-	c.Set("JWT_PAYLOAD", claims)
-	identity := mw.IdentityHandler(c)
+// CloseKeyWatcher stop watching the key files, it is a no-op if WatchKeyFiles is not enabled
+func (mw *GinJWTMiddleware) CloseKeyWatcher() error {
+	mw.keyMu.Lock()
+	defer mw.keyMu.Unlock()
+	if mw.keyWatcher == nil {
+		return nil
+	}
+	err := mw.keyWatcher.Close()
+	mw.keyWatcher = nil
+	return err
+}
 
-	if identity != nil {
-		c.Set(mw.IdentityKey, identity)
+// publicKeysFromDir load all public key PEM files in PubKeyDir, hidden files and sub directories are skipped
+func (mw *GinJWTMiddleware) publicKeysFromDir() error {
+	entries, err := os.ReadDir(mw.PubKeyDir)
+	if err != nil {
+		log.Printf("Failed to read public key directory %s: %v", mw.PubKeyDir, err)
+		return ErrNoPubKeyDir
 	}
 
-	if !mw.Authorizer(c, identity) {
-		mw.unauthorized(c, http.StatusForbidden, mw.HTTPStatusMessageFunc(c, ErrForbidden))
-		return
+	count := 0
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		keyData, err := os.ReadFile(filepath.Join(mw.PubKeyDir, entry.Name()))
+		if err != nil {
+			log.Printf("Failed to read public key file %s: %v", entry.Name(), err)
+			return ErrNoPubKeyFile
+		}
+		key, err := parsePublicKeyFromPEM(keyData)
+		if err != nil {
+			log.Printf("Invalid public key file %s: %v", entry.Name(), err)
+			return ErrInvalidPubKey
+		}
+		if err = mw.checkKeyType(key); err != nil {
+			log.Printf("Invalid public key file %s: %v", entry.Name(), err)
+			return err
+		}
+		mw.addPublicKey(key)
+		count++
 	}
 
-	c.Next()
+	if count == 0 {
+		return ErrNoPubKeyDir
+	}
+	return nil
 }
 
-// handleTokenError handles different types of JWT token validation errors
-func (mw *GinJWTMiddleware) handleTokenError(c *gin.Context, err error) {
-	switch {
-	case errors.Is(err, jwt.ErrTokenExpired):
-		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(c, ErrExpiredToken))
-	case errors.Is(err, jwt.ErrInvalidType) && strings.Contains(err.Error(), "exp is invalid"):
-		mw.unauthorized(c, http.StatusBadRequest, mw.HTTPStatusMessageFunc(c, ErrWrongFormatOfExp))
-	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing) && strings.Contains(err.Error(), "exp claim is required"):
-		mw.unauthorized(c, http.StatusBadRequest, mw.HTTPStatusMessageFunc(c, ErrMissingExpField))
-	default:
-		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(c, err))
+// publicKeyEntry a verification key and its key id
+type publicKeyEntry struct {
+	kid string
+	key crypto.PublicKey
+}
+
+// addPublicKey add a verification key, the same key is only added once
+func (mw *GinJWTMiddleware) addPublicKey(key crypto.PublicKey) {
+	kid := publicKeyID(key)
+	for _, entry := range mw.pubKeys {
+		if entry.kid == kid {
+			return
+		}
 	}
+	mw.pubKeys = append(mw.pubKeys, publicKeyEntry{kid: kid, key: key})
 }
 
-func (mw *GinJWTMiddleware) unauthorized(c *gin.Context, code int, message string) {
-	c.Header("WWW-Authenticate", "JWT realm=\""+mw.Realm+"\"")
-	if !mw.DisabledAbort {
-		c.Abort()
+// publicKeyID return a stable key id, it is the base64url encoded SHA-256 of the public key
+func publicKeyID(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return ""
 	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
 
-	mw.Unauthorized(c, code, message)
+// KeyFingerprints return the base64url encoded SHA-256 fingerprints of the active keys, they can be logged
+// for audit without exposing the keys. For HMAC it is the fingerprint of the secret key, for RSA, ECDSA and EdDSA
+// they are the key ids of the signing key and the verification keys. Keys from KeyFunc are not included.
+func (mw *GinJWTMiddleware) KeyFingerprints() []string {
+	if !mw.usingPublicKeyAlgo() {
+		if len(mw.Key) == 0 {
+			return nil
+		}
+		sum := sha256.Sum256(mw.Key)
+		return []string{base64.RawURLEncoding.EncodeToString(sum[:])}
+	}
+
+	mw.keyMu.RLock()
+	defer mw.keyMu.RUnlock()
+
+	fingerprints := make([]string, 0, len(mw.pubKeys)+1)
+	if mw.privKeyID != "" {
+		fingerprints = append(fingerprints, mw.privKeyID)
+	}
+	for _, entry := range mw.pubKeys {
+		if entry.kid != "" && !slices.Contains(fingerprints, entry.kid) {
+			fingerprints = append(fingerprints, entry.kid)
+		}
+	}
+	return fingerprints
 }
 
-// GetClaimsFromJWT get claims from JWT token
-func (mw *GinJWTMiddleware) GetClaimsFromJWT(c *gin.Context) (jwt.MapClaims, error) {
-	token, err := mw.ParseToken(c)
-	if err != nil {
-		return nil, err
+// verificationKey return the key used to verify the token signature, if there are multiple
+// public keys, the key matching the kid header is selected, otherwise all keys are tried in order
+func (mw *GinJWTMiddleware) verificationKey(t *jwt.Token) any {
+	if !mw.usingPublicKeyAlgo() {
+		return mw.Key
 	}
 
-	if mw.SendAuthorization {
-		if v, ok := c.Get("JWT_TOKEN"); ok {
-			if tokenStr, ok := v.(string); ok {
-				c.Header("Authorization", mw.TokenHeadName+" "+tokenStr)
+	mw.keyMu.RLock()
+	defer mw.keyMu.RUnlock()
+	if len(mw.pubKeys) <= 1 {
+		return mw.pubKey
+	}
+
+	if kid, ok := t.Header["kid"].(string); ok {
+		for _, entry := range mw.pubKeys {
+			if entry.kid == kid {
+				return entry.key
 			}
 		}
 	}
+	keySet := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(mw.pubKeys))}
+	for _, entry := range mw.pubKeys {
+		keySet.Keys = append(keySet.Keys, entry.key)
+	}
+	return keySet
+}
 
-	mapClaims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("invalid token claims type")
+// JWKSHandler can be used by clients to get the public keys as a JWKS document,
+// so that other services can validate tokens without sharing the PEM files.
+// The kid of each key is stable, the generated tokens carry the kid of the signing key in the header.
+func (mw *GinJWTMiddleware) JWKSHandler(c *gin.Context) {
+	mw.keyMu.RLock()
+	defer mw.keyMu.RUnlock()
+
+	keys := make([]gin.H, 0, len(mw.pubKeys))
+	if mw.usingPublicKeyAlgo() {
+		for _, entry := range mw.pubKeys {
+			switch key := entry.key.(type) {
+			case *rsa.PublicKey:
+				keys = append(keys, gin.H{
+					"kty": "RSA",
+					"use": "sig",
+					"alg": mw.SigningAlgorithm,
+					"kid": entry.kid,
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				})
+			case *ecdsa.PublicKey:
+				size := (key.Curve.Params().BitSize + 7) / 8
+				keys = append(keys, gin.H{
+					"kty": "EC",
+					"crv": key.Curve.Params().Name,
+					"use": "sig",
+					"alg": mw.SigningAlgorithm,
+					"kid": entry.kid,
+					"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+					"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+				})
+			case ed25519.PublicKey:
+				keys = append(keys, gin.H{
+					"kty": "OKP",
+					"crv": "Ed25519",
+					"use": "sig",
+					"alg": mw.SigningAlgorithm,
+					"kid": entry.kid,
+					"x":   base64.RawURLEncoding.EncodeToString(key),
+				})
+			}
+		}
 	}
 
-	// Return the claims directly without unnecessary copying
-	return mapClaims, nil
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
 }
 
-// ParseToken parse jwt token from gin context
-func (mw *GinJWTMiddleware) ParseToken(c *gin.Context) (*jwt.Token, error) {
-	var token string
-	var err error
-
+// MiddlewareFunc makes GinJWTMiddleware implement the Middleware interface.
+func (mw *GinJWTMiddleware) MiddlewareFunc() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mw.middlewareImpl(c)
+	}
+}
+
+func (mw *GinJWTMiddleware) middlewareImpl(c *gin.Context) {
+	claims, err := mw.GetClaimsFromJWT(c)
+	if err != nil {
+		mw.handleTokenError(c, err)
+		return
+	}
+
+	if _, err = mw.AuthorizeClaims(c, claims); err != nil {
+		code := http.StatusUnauthorized
+		switch {
+		case errors.Is(err, ErrMissingExpField):
+			code = http.StatusBadRequest
+		case errors.Is(err, ErrForbidden):
+			code = mw.ForbiddenStatusCode
+		}
+		mw.unauthorized(c, code, mw.HTTPStatusMessageFunc(c, err))
+		return
+	}
+
+	c.Next()
+}
+
+// AuthorizeClaims runs the checks of the middleware on the claims of a parsed access token: the exp claim,
+// NotBeforeIssuedAt, AccessTokenBlacklist, ClaimsValidator, IdentityHandler and Authorizer. The claims and
+// identity are set in c, the identity is returned. ErrForbidden is returned if Authorizer rejects the user,
+// ErrMissingExpField if the exp claim is missing. It is shared by the middleware and the grpc interceptors.
+func (mw *GinJWTMiddleware) AuthorizeClaims(c *gin.Context, claims jwt.MapClaims) (any, error) {
+	// For backwards compatibility since technically exp is not required in the spec but has been in gin-jwt
+	if claims["exp"] == nil {
+		return nil, ErrMissingExpField
+	}
+
+	if !mw.NotBeforeIssuedAt.IsZero() {
+		origIat, ok := ClaimInt64(claims, "orig_iat")
+		if !ok || origIat < mw.NotBeforeIssuedAt.Unix() {
+			return nil, ErrTokenIssuedBeforeCutoff
+		}
+	}
+
+	if mw.AccessTokenBlacklist {
+		if jti, _ := claims["jti"].(string); jti != "" {
+			revoked, err := mw.IsAccessTokenRevoked(c.Request.Context(), jti)
+			if err != nil {
+				log.Printf("Failed to check revoked access token: %v", err)
+			}
+			if err != nil || revoked {
+				return nil, ErrRevokedToken
+			}
+		}
+	}
+
+	if mw.ClaimsValidator != nil {
+		if err := mw.ClaimsValidator(c, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	c.Set(PayloadContextKey, claims)
+	identity := mw.IdentityHandler(c)
+
+	if identity != nil {
+		c.Set(mw.IdentityKey, identity)
+	}
+
+	if !mw.Authorizer(c, identity) {
+		return nil, ErrForbidden
+	}
+	return identity, nil
+}
+
+// handleTokenError handles different types of JWT token validation errors
+func (mw *GinJWTMiddleware) handleTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		mw.metrics().IncRejected(RejectReasonExpired)
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(c, ErrExpiredToken))
+	case errors.Is(err, jwt.ErrInvalidType) && strings.Contains(err.Error(), "exp is invalid"):
+		mw.metrics().IncRejected(RejectReasonInvalidExp)
+		mw.unauthorized(c, http.StatusBadRequest, mw.HTTPStatusMessageFunc(c, ErrWrongFormatOfExp))
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing) && strings.Contains(err.Error(), "exp claim is required"):
+		mw.metrics().IncRejected(RejectReasonMissingExp)
+		mw.unauthorized(c, http.StatusBadRequest, mw.HTTPStatusMessageFunc(c, ErrMissingExpField))
+	default:
+		mw.metrics().IncRejected(RejectReasonInvalid)
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(c, err))
+	}
+}
+
+func (mw *GinJWTMiddleware) unauthorized(c *gin.Context, code int, message string) {
+	realm := mw.Realm
+	if mw.RealmFunc != nil {
+		if r := mw.RealmFunc(c); r != "" {
+			realm = r
+		}
+	}
+	c.Header("WWW-Authenticate", "JWT realm=\""+realm+"\"")
+	if !mw.DisabledAbort {
+		c.Abort()
+	}
+
+	mw.Unauthorized(c, code, message)
+}
+
+// GetClaimsFromJWT get claims from JWT token
+func (mw *GinJWTMiddleware) GetClaimsFromJWT(c *gin.Context) (jwt.MapClaims, error) {
+	token, err := mw.ParseToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if mw.SendAuthorization {
+		if v, ok := c.Get(TokenContextKey); ok {
+			if tokenStr, ok := v.(string); ok {
+				c.Header("Authorization", mw.TokenHeadName+" "+tokenStr)
+			}
+		}
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims type")
+	}
+
+	// Return the claims directly without unnecessary copying
+	return mapClaims, nil
+}
+
+// ParseToken parse jwt token from gin context
+func (mw *GinJWTMiddleware) ParseToken(c *gin.Context) (*jwt.Token, error) {
+	var token string
+	var err error
+
 	methods := strings.Split(mw.TokenLookup, ",")
 	for _, method := range methods {
-		if len(token) > 0 {
-			break
-		}
 		parts := strings.Split(strings.TrimSpace(method), ":")
 		k := strings.TrimSpace(parts[0])
 		v := strings.TrimSpace(parts[1])
@@ -637,29 +1407,75 @@ func (mw *GinJWTMiddleware) ParseToken(c *gin.Context) (*jwt.Token, error) {
 		case "form":
 			token, err = mw.jwtFromForm(c, v)
 		}
+		if len(token) > 0 {
+			// record the source of the token, the later sources are not checked
+			c.Set(TokenSourceContextKey, k)
+			break
+		}
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
+	if mw.isTrustedGateway(c) {
+		return mw.parseUnverified(c, token)
+	}
+
 	if mw.KeyFunc != nil {
-		return jwt.Parse(token, mw.KeyFunc, mw.ParseOptions...)
+		return jwt.Parse(token, mw.checkHeader(mw.KeyFunc), mw.parserOptions()...)
 	}
 
-	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
+	return jwt.Parse(token, mw.checkHeader(func(t *jwt.Token) (any, error) {
 		if jwt.GetSigningMethod(mw.SigningAlgorithm) != t.Method {
 			return nil, ErrInvalidSigningAlgorithm
 		}
 		if mw.usingPublicKeyAlgo() {
-			return mw.pubKey, nil
+			return mw.verificationKey(t), nil
 		}
 
 		// save token string if valid
-		c.Set("JWT_TOKEN", token)
+		c.Set(TokenContextKey, token)
 
 		return mw.Key, nil
-	}, mw.ParseOptions...)
+	}), mw.parserOptions()...)
+}
+
+// parserOptions returns the options of the token parser, ParseOptions with the options of UseJSONNumber
+// and ExpectedAudience, ParseOptions itself is not modified
+func (mw *GinJWTMiddleware) parserOptions() []jwt.ParserOption {
+	opts := slices.Clip(mw.ParseOptions)
+	if mw.UseJSONNumber {
+		opts = append(opts, jwt.WithJSONNumber())
+	}
+	if len(mw.ExpectedAudience) > 0 {
+		opts = append(opts, jwt.WithAudience(mw.ExpectedAudience...))
+	}
+	return opts
+}
+
+// isTrustedGateway returns true if TrustedUnsigned is enabled and the request carries the trusted gateway header
+func (mw *GinJWTMiddleware) isTrustedGateway(c *gin.Context) bool {
+	if !mw.TrustedUnsigned || mw.TrustedGatewayHeader == "" || mw.TrustedGatewayValue == "" {
+		return false
+	}
+	value := c.GetHeader(mw.TrustedGatewayHeader)
+	return subtle.ConstantTimeCompare([]byte(value), []byte(mw.TrustedGatewayValue)) == 1
+}
+
+// parseUnverified decodes the token without verifying the signature, the claims are still validated
+func (mw *GinJWTMiddleware) parseUnverified(c *gin.Context, tokenString string) (*jwt.Token, error) {
+	token, _, err := jwt.NewParser(mw.parserOptions()...).ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]jwt.ParserOption{jwt.WithTimeFunc(mw.TimeFunc)}, mw.parserOptions()...)
+	if err = jwt.NewValidator(opts...).Validate(token.Claims); err != nil {
+		return nil, errors.Join(jwt.ErrTokenInvalidClaims, err)
+	}
+
+	c.Set(TokenContextKey, tokenString)
+	return token, nil
 }
 
 func (mw *GinJWTMiddleware) jwtFromHeader(c *gin.Context, key string) (string, error) {
@@ -670,6 +1486,17 @@ func (mw *GinJWTMiddleware) jwtFromHeader(c *gin.Context, key string) (string, e
 	}
 
 	parts := strings.SplitN(authHeader, " ", 2)
+	if mw.TokenHeadNameCaseInsensitive {
+		if len(parts) != 2 || !strings.EqualFold(parts[0], mw.TokenHeadName) {
+			return "", ErrInvalidAuthHeader
+		}
+		token := strings.TrimLeft(parts[1], " ")
+		if token == "" {
+			return "", ErrInvalidAuthHeader
+		}
+		return token, nil
+	}
+
 	if len(parts) != 2 || parts[0] != mw.TokenHeadName {
 		return "", ErrInvalidAuthHeader
 	}
@@ -717,11 +1544,26 @@ func (mw *GinJWTMiddleware) jwtFromForm(c *gin.Context, key string) (string, err
 	return token, nil
 }
 
+// authenticate tries Authenticators in order and then Authenticator, returns the error of the last one if all fail
+func (mw *GinJWTMiddleware) authenticate(c *gin.Context) (any, error) {
+	var err error
+	for _, authenticator := range mw.Authenticators {
+		var data any
+		if data, err = authenticator(c); err == nil {
+			return data, nil
+		}
+	}
+	if mw.Authenticator != nil {
+		return mw.Authenticator(c)
+	}
+	return nil, err
+}
+
 // LoginHandler can be used by clients to get a jwt token.
 // Payload needs to be json in the form of {"username": "USERNAME", "password": "PASSWORD"}.
 // Reply will be of the form {"token": "TOKEN"}.
 func (mw *GinJWTMiddleware) LoginHandler(c *gin.Context) {
-	if mw.Authenticator == nil {
+	if mw.Authenticator == nil && len(mw.Authenticators) == 0 {
 		mw.unauthorized(
 			c,
 			http.StatusInternalServerError,
@@ -730,51 +1572,120 @@ func (mw *GinJWTMiddleware) LoginHandler(c *gin.Context) {
 		return
 	}
 
-	data, err := mw.Authenticator(c)
+	data, err := mw.authenticate(c)
 	if err != nil {
 		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(c, err))
+		if mw.OnLoginFailed != nil {
+			mw.OnLoginFailed(c, err)
+		}
 		return
 	}
 
-	// Generate complete token pair
-	tokenPair, err := mw.TokenGenerator(c.Request.Context(), data)
+	// Generate complete token pair, PayloadFunc is called once for the tokens and OnLogin
+	payload := mw.payload(data)
+	tokenPair, err := mw.tokenGenerator(mw.requestContext(c), data, payload)
 	if err != nil {
 		mw.unauthorized(
 			c,
 			http.StatusInternalServerError,
 			mw.HTTPStatusMessageFunc(c, ErrFailedTokenCreation),
 		)
+		if mw.OnLoginFailed != nil {
+			mw.OnLoginFailed(c, errors.Join(ErrFailedTokenCreation, err))
+		}
 		return
 	}
 
 	// Set cookie
 	mw.SetCookie(c, tokenPair.AccessToken)
+	mw.setRefreshCookie(c, tokenPair.RefreshToken, int(mw.RefreshTokenTimeout.Seconds()))
 
 	mw.LoginResponse(c, tokenPair)
+	if mw.OnLogin != nil {
+		mw.OnLogin(c, mw.payloadIdentity(data, payload))
+	}
 }
 
 // LogoutHandler can be used by clients to remove the jwt cookie and revoke refresh token
 func (mw *GinJWTMiddleware) LogoutHandler(c *gin.Context) {
 	// Extract JWT claims to make them available in LogoutResponse
 	// This allows developers to access user information during logout
+	var identity any
 	claims, err := mw.GetClaimsFromJWT(c)
 	if err == nil {
-		c.Set("JWT_PAYLOAD", claims)
-		identity := mw.IdentityHandler(c)
+		c.Set(PayloadContextKey, claims)
+		identity = mw.IdentityHandler(c)
 		if identity != nil {
 			c.Set(mw.IdentityKey, identity)
 		}
+
+		mw.revokeAccessTokenOnLogout(c, claims)
 	}
 
 	// Handle refresh token revocation (RFC 6749 compliant)
 	refreshToken := mw.extractRefreshToken(c)
 	if refreshToken != "" {
-		if err := mw.revokeRefreshToken(c.Request.Context(), refreshToken); err != nil {
+		if err := mw.revokeRefreshToken(mw.requestContext(c), refreshToken); err != nil {
 			log.Printf("Failed to revoke refresh token on logout: %v", err)
 		}
 	}
 
-	// delete auth cookie
+	mw.deleteAuthCookies(c)
+
+	mw.LogoutResponse(c)
+	if mw.OnLogout != nil {
+		mw.OnLogout(c, identity)
+	}
+}
+
+// LogoutAllHandler logs out all sessions of the user of the access token, every refresh token of the user
+// is revoked. The refresh token store must implement core.UserTokenIndexer, the in-memory and Redis stores
+// implement it. The stateless refresh tokens are not stored, so they can not be revoked.
+func (mw *GinJWTMiddleware) LogoutAllHandler(c *gin.Context) {
+	claims, err := mw.GetClaimsFromJWT(c)
+	if err != nil {
+		mw.handleTokenError(c, err)
+		return
+	}
+
+	c.Set(PayloadContextKey, claims)
+	identity := mw.IdentityHandler(c)
+	if identity == nil {
+		mw.unauthorized(c, http.StatusBadRequest, mw.HTTPStatusMessageFunc(c, ErrMissingIdentity))
+		return
+	}
+	c.Set(mw.IdentityKey, identity)
+
+	if err := mw.revokeAllForIdentity(mw.requestContext(c), identity); err != nil {
+		mw.unauthorized(c, http.StatusInternalServerError, mw.HTTPStatusMessageFunc(c, err))
+		return
+	}
+	mw.revokeAccessTokenOnLogout(c, claims)
+
+	mw.deleteAuthCookies(c)
+
+	mw.LogoutResponse(c)
+	if mw.OnLogout != nil {
+		mw.OnLogout(c, identity)
+	}
+}
+
+// revokeAccessTokenOnLogout revokes the access token so that it is rejected before it expires
+func (mw *GinJWTMiddleware) revokeAccessTokenOnLogout(c *gin.Context, claims jwt.MapClaims) {
+	if !mw.AccessTokenBlacklist {
+		return
+	}
+	jti, _ := claims["jti"].(string)
+	exp, ok := ClaimInt64(claims, mw.ExpField)
+	if jti != "" && ok {
+		if err := mw.RevokeAccessToken(c.Request.Context(), jti, time.Unix(exp, 0)); err != nil {
+			log.Printf("Failed to revoke access token on logout: %v", err)
+		}
+	}
+}
+
+// deleteAuthCookies deletes the access token and refresh token cookies
+func (mw *GinJWTMiddleware) deleteAuthCookies(c *gin.Context) {
 	if mw.SendCookie {
 		if mw.CookieSameSite != 0 {
 			c.SetSameSite(mw.CookieSameSite)
@@ -790,8 +1701,7 @@ func (mw *GinJWTMiddleware) LogoutHandler(c *gin.Context) {
 			mw.CookieHTTPOnly,
 		)
 	}
-
-	mw.LogoutResponse(c)
+	mw.setRefreshCookie(c, "", -1)
 }
 
 // RefreshHandler can be used to refresh a token using RFC 6749 compliant refresh tokens.
@@ -806,14 +1716,20 @@ func (mw *GinJWTMiddleware) RefreshHandler(c *gin.Context) {
 	}
 
 	// Validate refresh token
-	userData, err := mw.validateRefreshToken(c.Request.Context(), refreshToken)
+	userData, err := mw.validateRefreshToken(mw.requestContext(c), refreshToken)
 	if err != nil {
 		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(c, err))
 		return
 	}
 
-	// Generate new token pair and revoke old refresh token
-	tokenPair, err := mw.TokenGeneratorWithRevocation(c.Request.Context(), userData, refreshToken)
+	// Reuse the refresh token if it is not near expiry, otherwise generate new token pair and revoke old refresh token
+	refreshMaxAge := int(mw.RefreshTokenTimeout.Seconds())
+	tokenPair, expiry, err := mw.reuseRefreshToken(mw.requestContext(c), userData, refreshToken)
+	if tokenPair != nil {
+		refreshMaxAge = int(expiry.Sub(mw.TimeFunc()).Seconds())
+	} else if err == nil {
+		tokenPair, err = mw.TokenGeneratorWithRevocation(mw.requestContext(c), userData, refreshToken)
+	}
 	if err != nil {
 		mw.unauthorized(c, http.StatusInternalServerError, mw.HTTPStatusMessageFunc(c, err))
 		return
@@ -821,8 +1737,84 @@ func (mw *GinJWTMiddleware) RefreshHandler(c *gin.Context) {
 
 	// Set cookie
 	mw.SetCookie(c, tokenPair.AccessToken)
+	mw.setRefreshCookie(c, tokenPair.RefreshToken, refreshMaxAge)
 
+	mw.metrics().IncRefreshed()
 	mw.RefreshResponse(c, tokenPair)
+	if mw.OnRefresh != nil {
+		mw.OnRefresh(c, mw.userIdentity(userData))
+	}
+}
+
+// RefreshPeekHandler returns a handler which validates the refresh token and replies the associated user data
+// and expiry without rotating it, it is intended for debug tooling and should not be exposed publicly.
+// The peek has no side effects, the last used time is not recorded and the reuse detection is not triggered.
+// Reply will be of the form {"user_data": DATA, "expires_at": UNIX}, expires_at is omitted if the
+// RefreshTokenStore does not implement core.ExpiryGetter.
+func (mw *GinJWTMiddleware) RefreshPeekHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		refreshToken := mw.extractRefreshToken(c)
+		if refreshToken == "" {
+			mw.unauthorized(c, http.StatusBadRequest, "missing refresh_token parameter")
+			return
+		}
+
+		userData, expiry, err := mw.peekRefreshToken(mw.requestContext(c), refreshToken)
+		if err != nil {
+			mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(c, err))
+			return
+		}
+
+		response := gin.H{"user_data": userData}
+		if !expiry.IsZero() {
+			response["expires_at"] = expiry.Unix()
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// peekRefreshToken returns the user data and expiry of the refresh token like validateRefreshToken but
+// without side effects, the expiry is zero if it is unknown
+func (mw *GinJWTMiddleware) peekRefreshToken(ctx context.Context, token string) (any, time.Time, error) {
+	if mw.StatelessRefreshToken {
+		userData, err := mw.parseStatelessRefreshToken(ctx, token)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		claims, _ := mw.parseStatelessRefreshClaims(token)
+		exp, _ := ClaimInt64(claims, "exp")
+		return userData, time.Unix(exp, 0), nil
+	}
+
+	key := mw.refreshTokenKey(ctx, token)
+	userData, err := mw.storeGet(ctx, key)
+	if err != nil {
+		if errors.Is(err, core.ErrRefreshTokenNotFound) {
+			return nil, time.Time{}, ErrInvalidRefreshToken
+		}
+		return nil, time.Time{}, err
+	}
+
+	var expiry time.Time
+	if getter, ok := storeAs[core.ExpiryGetter](mw.RefreshTokenStore); ok {
+		err = mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+			var err error
+			expiry, err = getter.GetExpiry(ctx, key)
+			return err
+		})
+		if errors.Is(err, core.ErrRefreshTokenNotFound) || (err == nil && !expiry.After(mw.TimeFunc())) {
+			return nil, time.Time{}, ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	userData, err = mw.decodeRefreshData(userData)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return userData, expiry, nil
 }
 
 // TokenGeneratorWithRevocation generates a new token pair and revokes the old refresh token
@@ -831,6 +1823,13 @@ func (mw *GinJWTMiddleware) TokenGeneratorWithRevocation(
 	data any,
 	oldRefreshToken string,
 ) (*core.Token, error) {
+	// The new refresh token inherits the family of the old one
+	if mw.RefreshTokenReuseDetection {
+		if family, err := mw.refreshTokenFamily(ctx, oldRefreshToken); err == nil {
+			ctx = context.WithValue(ctx, refreshFamilyCtxKey{}, family)
+		}
+	}
+
 	// Generate new token pair
 	tokenPair, err := mw.TokenGenerator(ctx, data)
 	if err != nil {
@@ -846,37 +1845,102 @@ func (mw *GinJWTMiddleware) TokenGeneratorWithRevocation(
 	return tokenPair, nil
 }
 
+// reuseRefreshToken returns a token pair with a new access token and the existing refresh token
+// if the refresh token expires after RefreshRotateThreshold, nil token means the refresh token should be rotated
+func (mw *GinJWTMiddleware) reuseRefreshToken(ctx context.Context, data any, refreshToken string) (*core.Token, time.Time, error) {
+	if mw.RefreshRotateThreshold <= 0 {
+		return nil, time.Time{}, nil
+	}
+	getter, ok := storeAs[core.ExpiryGetter](mw.RefreshTokenStore)
+	if !ok {
+		return nil, time.Time{}, nil
+	}
+	var expiry time.Time
+	err := mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		expiry, err = getter.GetExpiry(ctx, mw.refreshTokenKey(ctx, refreshToken))
+		return err
+	})
+	if err != nil || expiry.Sub(mw.TimeFunc()) <= mw.RefreshRotateThreshold {
+		return nil, time.Time{}, nil
+	}
+
+	accessToken, expire, err := mw.generateAccessToken(data)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return &core.Token{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		RefreshToken: refreshToken,
+		ExpiresAt:    expire.Unix(),
+		CreatedAt:    mw.TimeFunc().Unix(),
+	}, expiry, nil
+}
+
 // validateRefreshToken validates a refresh token and returns associated user data
 func (mw *GinJWTMiddleware) validateRefreshToken(ctx context.Context, token string) (any, error) {
-	userData, err := mw.RefreshTokenStore.Get(ctx, token)
+	if mw.StatelessRefreshToken {
+		return mw.parseStatelessRefreshToken(ctx, token)
+	}
+
+	key := mw.refreshTokenKey(ctx, token)
+	userData, err := mw.storeGet(ctx, key)
 	if err != nil {
 		if err == core.ErrRefreshTokenNotFound {
+			if mw.RefreshTokenReuseDetection {
+				mw.detectRefreshTokenReuse(ctx, token)
+			}
 			return nil, ErrInvalidRefreshToken
 		}
 		return nil, err
 	}
-	return userData, nil
+	if mw.TrackLastUsed {
+		if tracker, ok := storeAs[core.SessionTracker](mw.RefreshTokenStore); ok {
+			err = mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+				return tracker.Touch(ctx, key, mw.TimeFunc())
+			})
+			if err != nil {
+				log.Printf("Failed to record last used time of refresh token: %v", err)
+			}
+		}
+	}
+	return mw.decodeRefreshData(userData)
 }
 
 // TokenGenerator generates a complete token pair (access + refresh) with RFC 6749 compliance
 func (mw *GinJWTMiddleware) TokenGenerator(ctx context.Context, data any) (*core.Token, error) {
+	return mw.tokenGenerator(ctx, data, mw.payload(data))
+}
+
+// tokenGenerator generates the token pair with payload, which is the result of PayloadFunc for data
+func (mw *GinJWTMiddleware) tokenGenerator(ctx context.Context, data any, payload jwt.MapClaims) (*core.Token, error) {
 	// Generate access token
-	accessToken, expire, err := mw.generateAccessToken(data)
+	accessToken, expire, err := mw.signAccessToken(data, payload)
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate refresh token
-	refreshToken, err := mw.generateRefreshToken()
-	if err != nil {
-		return nil, err
-	}
+	var refreshToken string
+	if mw.StatelessRefreshToken {
+		refreshToken, err = mw.generateStatelessRefreshToken(data)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Generate refresh token
+		refreshToken, err = mw.generateRefreshToken()
+		if err != nil {
+			return nil, err
+		}
 
-	// Store refresh token
-	if err := mw.storeRefreshToken(ctx, refreshToken, data); err != nil {
-		return nil, err
+		// Store refresh token
+		if err := mw.storeRefreshToken(ctx, refreshToken, data, mw.payloadIdentity(data, payload)); err != nil {
+			return nil, err
+		}
 	}
 
+	mw.metrics().IncIssued()
 	now := mw.TimeFunc()
 	return &core.Token{
 		AccessToken:  accessToken,
@@ -889,6 +1953,11 @@ func (mw *GinJWTMiddleware) TokenGenerator(ctx context.Context, data any) (*core
 
 // generateAccessToken method that clients can use to get a jwt token.
 func (mw *GinJWTMiddleware) generateAccessToken(data any) (string, time.Time, error) {
+	return mw.signAccessToken(data, mw.payload(data))
+}
+
+// signAccessToken creates the access token of data with the claims of payload
+func (mw *GinJWTMiddleware) signAccessToken(data any, payload jwt.MapClaims) (string, time.Time, error) {
 	// 1. Validate signing algorithm
 	signingMethod := jwt.GetSigningMethod(mw.SigningAlgorithm)
 	if signingMethod == nil {
@@ -896,6 +1965,15 @@ func (mw *GinJWTMiddleware) generateAccessToken(data any) (string, time.Time, er
 	}
 
 	token := jwt.New(signingMethod)
+	token.Header["typ"] = mw.TokenType
+	if mw.usingPublicKeyAlgo() {
+		// the kid selects the verification key, it matches the kid of the key in JWKSHandler
+		mw.keyMu.RLock()
+		if mw.privKeyID != "" {
+			token.Header["kid"] = mw.privKeyID
+		}
+		mw.keyMu.RUnlock()
+	}
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
 		return "", time.Time{}, ErrFailedTokenCreation
@@ -906,13 +1984,14 @@ func (mw *GinJWTMiddleware) generateAccessToken(data any) (string, time.Time, er
 		"exp": true, "iat": true, "nbf": true, "iss": true,
 		"aud": true, "sub": true, "jti": true, "orig_iat": true,
 	}
+	if mw.MirrorExpField != "" {
+		reservedClaims[mw.MirrorExpField] = true
+	}
 
 	// 3. Safely add custom payload, avoiding system field overwrites
-	if mw.PayloadFunc != nil {
-		for key, value := range mw.PayloadFunc(data) {
-			if !reservedClaims[key] {
-				claims[key] = value
-			}
+	for key, value := range payload {
+		if !reservedClaims[key] {
+			claims[key] = value
 		}
 	}
 
@@ -922,7 +2001,22 @@ func (mw *GinJWTMiddleware) generateAccessToken(data any) (string, time.Time, er
 	// 5. Set required system claims
 	now := mw.TimeFunc()
 	claims[mw.ExpField] = expire.Unix()
+	if mw.MirrorExpField != "" {
+		claims[mw.MirrorExpField] = expire.Unix()
+	}
 	claims["orig_iat"] = now.Unix()
+	jti, err := mw.generateJTI()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claims["jti"] = jti
+	switch len(mw.Audience) {
+	case 0:
+	case 1:
+		claims["aud"] = mw.Audience[0]
+	default:
+		claims["aud"] = mw.Audience
+	}
 
 	// 6. Sign the token
 	tokenString, err := mw.signedString(token)
@@ -937,13 +2031,44 @@ func (mw *GinJWTMiddleware) signedString(token *jwt.Token) (string, error) {
 	var tokenString string
 	var err error
 	if mw.usingPublicKeyAlgo() {
+		mw.keyMu.RLock()
 		tokenString, err = token.SignedString(mw.privKey)
+		mw.keyMu.RUnlock()
 	} else {
 		tokenString, err = token.SignedString(mw.Key)
 	}
 	return tokenString, err
 }
 
+// storeAs returns the store as the optional interface T, a wrapper store such as CachedTokenStore
+// only supports T if all the stores it wraps support T
+func storeAs[T any](s core.TokenStore) (T, bool) {
+	v, ok := s.(T)
+	if !ok {
+		return v, false
+	}
+	for {
+		u, ok := s.(core.Unwrapper)
+		if !ok {
+			return v, true
+		}
+		s = u.Unwrap()
+		if _, ok := s.(T); !ok {
+			var zero T
+			return zero, false
+		}
+	}
+}
+
+// generateJTI creates a random unique identifier of access token
+func (mw *GinJWTMiddleware) generateJTI() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
 // generateRefreshToken creates a cryptographically secure random refresh token
 func (mw *GinJWTMiddleware) generateRefreshToken() (string, error) {
 	bytes := make([]byte, mw.RefreshTokenLength)
@@ -953,21 +2078,249 @@ func (mw *GinJWTMiddleware) generateRefreshToken() (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
-// storeRefreshToken stores a refresh token with user data
+// refreshTokenType the typ claim of the stateless refresh tokens
+const refreshTokenType = "refresh"
+
+// generateStatelessRefreshToken creates a refresh JWT carrying the user data, signed with RefreshSigningKey
+func (mw *GinJWTMiddleware) generateStatelessRefreshToken(data any) (string, error) {
+	userData, err := mw.encodeRefreshData(data)
+	if err != nil {
+		return "", err
+	}
+	jti, err := mw.generateJTI()
+	if err != nil {
+		return "", err
+	}
+
+	now := mw.TimeFunc()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(mw.RefreshSigningAlgorithm), jwt.MapClaims{
+		"typ":  refreshTokenType,
+		"data": userData,
+		"exp":  now.Add(mw.RefreshTokenTimeout).Unix(),
+		"iat":  now.Unix(),
+		"jti":  jti,
+	})
+	return token.SignedString(mw.RefreshSigningKey)
+}
+
+// parseStatelessRefreshToken verifies the refresh JWT and returns the user data, the revoked JWT is rejected
+func (mw *GinJWTMiddleware) parseStatelessRefreshToken(ctx context.Context, token string) (any, error) {
+	claims, err := mw.parseStatelessRefreshClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	jti, _ := claims["jti"].(string)
+	revoked, err := mw.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidRefreshToken
+	}
+	return mw.decodeRefreshData(claims["data"])
+}
+
+// parseStatelessRefreshClaims verifies the signature and expiry of the refresh JWT with RefreshSigningKey
+func (mw *GinJWTMiddleware) parseStatelessRefreshClaims(token string) (jwt.MapClaims, error) {
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (any, error) {
+		return mw.RefreshSigningKey, nil
+	}, jwt.WithValidMethods([]string{mw.RefreshSigningAlgorithm}), jwt.WithExpirationRequired(), jwt.WithTimeFunc(mw.TimeFunc))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != refreshTokenType {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
+
+// revokeStatelessRefreshToken records the jti of the refresh JWT as revoked until it expires
+func (mw *GinJWTMiddleware) revokeStatelessRefreshToken(ctx context.Context, token string) error {
+	claims, err := mw.parseStatelessRefreshClaims(token)
+	if err != nil {
+		return err
+	}
+	jti, _ := claims["jti"].(string)
+	exp, _ := ClaimInt64(claims, "exp")
+	return mw.RevokeAccessToken(ctx, jti, time.Unix(exp, 0))
+}
+
+// storeRefreshToken stores a refresh token with user data, the token is indexed by the identity of the user
 func (mw *GinJWTMiddleware) storeRefreshToken(
 	ctx context.Context,
 	token string,
 	userData any,
+	identity any,
 ) error {
 	expiry := mw.TimeFunc().Add(mw.RefreshTokenTimeout)
-	return mw.RefreshTokenStore.Set(ctx, token, userData, expiry)
+	data, err := mw.encodeRefreshData(userData)
+	if err != nil {
+		return err
+	}
+	key := mw.refreshTokenKey(ctx, token)
+	if err = mw.storeSet(ctx, key, data, expiry); err != nil {
+		return err
+	}
+	if indexer, ok := storeAs[core.UserTokenIndexer](mw.RefreshTokenStore); ok {
+		user := mw.userIndexKey(ctx, identity)
+		err = mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+			return indexer.AddUserToken(ctx, user, key, expiry)
+		})
+		if err != nil {
+			return err
+		}
+		if mw.MaxSessionsPerUser > 0 {
+			if err = mw.evictOldestSessions(ctx, indexer, user, key); err != nil {
+				return err
+			}
+		}
+	}
+	if mw.RefreshTokenReuseDetection {
+		return mw.storeRefreshTokenFamily(ctx, key, expiry)
+	}
+	return nil
+}
+
+// evictOldestSessions revokes the oldest refresh tokens of the user beyond MaxSessionsPerUser,
+// the token just stored is always kept
+func (mw *GinJWTMiddleware) evictOldestSessions(
+	ctx context.Context,
+	indexer core.UserTokenIndexer,
+	user string,
+	current string,
+) error {
+	var sessions map[string]*core.RefreshTokenData
+	err := mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		sessions, err = indexer.ListUserTokens(ctx, user)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	delete(sessions, current)
+	excess := len(sessions) - (mw.MaxSessionsPerUser - 1)
+	if excess <= 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(sessions))
+	for key := range sessions {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return sessions[a].Created.Compare(sessions[b].Created)
+	})
+	for _, key := range keys[:excess] {
+		if err = mw.storeDelete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type refreshFamilyCtxKey struct{}
+
+const (
+	// refreshTokenFamilyPrefix the store key prefix of the family of a refresh token, it is kept after the token
+	// is rotated, so that the replay of the rotated token is detected
+	refreshTokenFamilyPrefix = "!family_of:"
+	// refreshFamilyPrefix the store key prefix of a family, the value is the key of the current refresh token
+	refreshFamilyPrefix = "!family:"
+)
+
+// storeRefreshTokenFamily record the family of the refresh token, the family is inherited from ctx on rotation,
+// otherwise a new family is created
+func (mw *GinJWTMiddleware) storeRefreshTokenFamily(ctx context.Context, key string, expiry time.Time) error {
+	family, _ := ctx.Value(refreshFamilyCtxKey{}).(string)
+	if family == "" {
+		var err error
+		if family, err = mw.generateJTI(); err != nil {
+			return err
+		}
+	}
+	if err := mw.storeSet(ctx, refreshTokenFamilyPrefix+key, family, expiry); err != nil {
+		return err
+	}
+	return mw.storeSet(ctx, refreshFamilyPrefix+family, key, expiry)
+}
+
+// refreshTokenFamily returns the family of the refresh token, the token may have been rotated
+func (mw *GinJWTMiddleware) refreshTokenFamily(ctx context.Context, token string) (string, error) {
+	value, err := mw.storeGet(ctx, refreshTokenFamilyPrefix+mw.refreshTokenKey(ctx, token))
+	if err != nil {
+		return "", err
+	}
+	family, ok := value.(string)
+	if !ok || family == "" {
+		return "", core.ErrRefreshTokenNotFound
+	}
+	return family, nil
+}
+
+// detectRefreshTokenReuse revokes the family of the refresh token if the token is known but has been rotated
+// or revoked, the current refresh token of the family can not be used either
+func (mw *GinJWTMiddleware) detectRefreshTokenReuse(ctx context.Context, token string) {
+	family, err := mw.refreshTokenFamily(ctx, token)
+	if err != nil {
+		return
+	}
+	log.Printf("Refresh token reuse detected, the token family is revoked")
+
+	if value, err := mw.storeGet(ctx, refreshFamilyPrefix+family); err == nil {
+		if current, ok := value.(string); ok && current != "" {
+			if err = mw.storeDelete(ctx, current); err != nil {
+				log.Printf("Failed to revoke refresh token family: %v", err)
+			}
+		}
+	}
+	if err := mw.storeDelete(ctx, refreshFamilyPrefix+family); err != nil {
+		log.Printf("Failed to revoke refresh token family: %v", err)
+	}
 }
 
-// SetCookie help to set the token in the cookie
+// encodeRefreshData serializes user data with RefreshDataCodec if it is set
+func (mw *GinJWTMiddleware) encodeRefreshData(userData any) (any, error) {
+	if mw.RefreshDataCodec == nil || mw.RefreshDataCodec.Marshal == nil {
+		return userData, nil
+	}
+	data, err := mw.RefreshDataCodec.Marshal(userData)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// decodeRefreshData reconstructs user data with RefreshDataCodec if it is set
+func (mw *GinJWTMiddleware) decodeRefreshData(data any) (any, error) {
+	if mw.RefreshDataCodec == nil || mw.RefreshDataCodec.Unmarshal == nil {
+		return data, nil
+	}
+	switch v := data.(type) {
+	case string:
+		return mw.RefreshDataCodec.Unmarshal([]byte(v))
+	case []byte:
+		return mw.RefreshDataCodec.Unmarshal(v)
+	}
+	return nil, ErrInvalidRefreshToken
+}
+
+// cookieMaxAge return the max age of the cookie of the current request
+func (mw *GinJWTMiddleware) cookieMaxAge(c *gin.Context) time.Duration {
+	if mw.CookieMaxAgeFunc != nil {
+		if maxAge := mw.CookieMaxAgeFunc(c); maxAge > 0 {
+			return maxAge
+		}
+	}
+	return mw.CookieMaxAge
+}
+
+// SetCookie help to set the token in the cookie, the max age is from CookieMaxAgeFunc if it is set
 func (mw *GinJWTMiddleware) SetCookie(c *gin.Context, token string) {
 	// set cookie
 	if mw.SendCookie {
-		expireCookie := mw.TimeFunc().Add(mw.CookieMaxAge)
+		expireCookie := mw.TimeFunc().Add(mw.cookieMaxAge(c))
 		maxage := int(expireCookie.Unix() - mw.TimeFunc().Unix())
 
 		if mw.CookieSameSite != 0 {
@@ -999,12 +2352,248 @@ func (mw *GinJWTMiddleware) extractRefreshToken(c *gin.Context) string {
 			token = reqBody.RefreshToken
 		}
 	}
+	if token == "" && mw.RefreshCookieName != "" {
+		token, _ = c.Cookie(mw.RefreshCookieName)
+	}
 	return token
 }
 
+// setRefreshCookie set the refresh token to the HttpOnly refresh cookie, maxAge < 0 means deleting the cookie
+func (mw *GinJWTMiddleware) setRefreshCookie(c *gin.Context, token string, maxAge int) {
+	if !mw.SendCookie || mw.RefreshCookieName == "" {
+		return
+	}
+
+	if mw.CookieSameSite != 0 {
+		c.SetSameSite(mw.CookieSameSite)
+	}
+
+	c.SetCookie(
+		mw.RefreshCookieName,
+		token,
+		maxAge,
+		"/",
+		mw.CookieDomain,
+		mw.SecureCookie,
+		true,
+	)
+}
+
 // revokeRefreshToken removes a refresh token from storage
 func (mw *GinJWTMiddleware) revokeRefreshToken(ctx context.Context, token string) error {
-	return mw.RefreshTokenStore.Delete(ctx, token)
+	if mw.StatelessRefreshToken {
+		return mw.revokeStatelessRefreshToken(ctx, token)
+	}
+	return mw.storeDelete(ctx, mw.refreshTokenKey(ctx, token))
+}
+
+// withStoreTimeout calls the store with ctx limited by StoreTimeout, the error of exceeding the deadline
+// is returned with ErrStoreTimeout. The latency of the call is recorded by the MetricsCollector.
+func (mw *GinJWTMiddleware) withStoreTimeout(ctx context.Context, call func(ctx context.Context) error) error {
+	defer mw.observeStore(time.Now())
+	if mw.StoreTimeout <= 0 {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, mw.StoreTimeout)
+	defer cancel()
+	err := call(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errors.Join(ErrStoreTimeout, err)
+	}
+	return err
+}
+
+// storeGet gets the value of key from RefreshTokenStore with withStoreTimeout
+func (mw *GinJWTMiddleware) storeGet(ctx context.Context, key string) (any, error) {
+	var value any
+	err := mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		value, err = mw.RefreshTokenStore.Get(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+// storeSet sets the value of key in RefreshTokenStore with withStoreTimeout
+func (mw *GinJWTMiddleware) storeSet(ctx context.Context, key string, value any, expiry time.Time) error {
+	return mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+		return mw.RefreshTokenStore.Set(ctx, key, value, expiry)
+	})
+}
+
+// storeDelete deletes the key from RefreshTokenStore with withStoreTimeout
+func (mw *GinJWTMiddleware) storeDelete(ctx context.Context, key string) error {
+	return mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+		return mw.RefreshTokenStore.Delete(ctx, key)
+	})
+}
+
+// revokedAccessTokenPrefix the store key prefix of revoked access tokens, it never collides with the
+// refresh tokens and the tenant prefixes because "!" is escaped in them
+const revokedAccessTokenPrefix = "!jti:"
+
+// RevokeAccessToken revokes the access token by its jti claim, the middleware rejects the token when
+// AccessTokenBlacklist is set. The revocation is kept in RefreshTokenStore until expiry, which should be
+// the exp claim of the token.
+func (mw *GinJWTMiddleware) RevokeAccessToken(ctx context.Context, jti string, expiry time.Time) error {
+	if jti == "" {
+		return ErrMissingJTI
+	}
+	if !expiry.After(mw.TimeFunc()) {
+		return nil // the token is expired already
+	}
+	return mw.storeSet(ctx, revokedAccessTokenPrefix+jti, true, expiry)
+}
+
+// IsAccessTokenRevoked returns true if the access token of the jti has been revoked by RevokeAccessToken
+func (mw *GinJWTMiddleware) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+	_, err := mw.storeGet(ctx, revokedAccessTokenPrefix+jti)
+	if errors.Is(err, core.ErrRefreshTokenNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+type tenantCtxKey struct{}
+
+// ContextWithTenant returns a copy of ctx with the tenant, the refresh tokens stored, validated and revoked
+// with the context are namespaced by the tenant, used when calling TokenGenerator outside gin handlers.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenant)
+}
+
+// requestContext returns the request context with the tenant from TenantFunc
+func (mw *GinJWTMiddleware) requestContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if mw.TenantFunc != nil {
+		ctx = ContextWithTenant(ctx, mw.TenantFunc(c))
+	}
+	return ctx
+}
+
+// tenantKeyPrefix returns the store key prefix of tenant, the tenant is escaped to avoid prefix collisions
+func tenantKeyPrefix(tenant string) string {
+	return url.QueryEscape(tenant) + ":"
+}
+
+// refreshTokenKey returns the store key of refresh token, the sha256 of the token if HashRefreshAtRest is set,
+// prefixed with the tenant in ctx if present
+func (mw *GinJWTMiddleware) refreshTokenKey(ctx context.Context, token string) string {
+	if mw.HashRefreshAtRest {
+		sum := sha256.Sum256([]byte(token))
+		token = hex.EncodeToString(sum[:])
+	}
+	if tenant, _ := ctx.Value(tenantCtxKey{}).(string); tenant != "" {
+		return tenantKeyPrefix(tenant) + token
+	}
+	return token
+}
+
+// RevokeAllForTenant revokes all refresh tokens of the tenant, returns the number of revoked tokens.
+// The refresh token store must implement core.PrefixDeleter, both built-in stores implement it.
+func (mw *GinJWTMiddleware) RevokeAllForTenant(ctx context.Context, tenant string) (int, error) {
+	if tenant == "" {
+		return 0, ErrMissingTenant
+	}
+	deleter, ok := storeAs[core.PrefixDeleter](mw.RefreshTokenStore)
+	if !ok {
+		return 0, ErrPrefixDeleteNotSupported
+	}
+	var n int
+	err := mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		n, err = deleter.DeleteByPrefix(ctx, tenantKeyPrefix(tenant))
+		return err
+	})
+	return n, err
+}
+
+// RevokeAllForUser revokes all refresh tokens of the user, userData is the data returned by Authenticator.
+// The user is identified by the IdentityKey claim of PayloadFunc, or by userData itself without the claim.
+// The refresh token store must implement core.UserTokenIndexer, the in-memory and Redis stores implement it.
+func (mw *GinJWTMiddleware) RevokeAllForUser(ctx context.Context, userData any) error {
+	return mw.revokeAllForIdentity(ctx, mw.userIdentity(userData))
+}
+
+func (mw *GinJWTMiddleware) revokeAllForIdentity(ctx context.Context, identity any) error {
+	indexer, ok := storeAs[core.UserTokenIndexer](mw.RefreshTokenStore)
+	if !ok {
+		return ErrUserIndexNotSupported
+	}
+	return mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+		_, err := indexer.DeleteByUser(ctx, mw.userIndexKey(ctx, identity))
+		return err
+	})
+}
+
+// userIdentity returns the identity of the user data, it is the IdentityKey claim of PayloadFunc if present,
+// so it matches the identity of the access tokens, otherwise the user data itself
+func (mw *GinJWTMiddleware) userIdentity(data any) any {
+	return mw.payloadIdentity(data, mw.payload(data))
+}
+
+// payloadIdentity returns the identity of the user data like userIdentity, payload is the result of PayloadFunc
+func (mw *GinJWTMiddleware) payloadIdentity(data any, payload jwt.MapClaims) any {
+	if identity, ok := payload[mw.IdentityKey]; ok {
+		return identity
+	}
+	return data
+}
+
+// payload returns the claims of PayloadFunc for the user data, nil if PayloadFunc is not set
+func (mw *GinJWTMiddleware) payload(data any) jwt.MapClaims {
+	if mw.PayloadFunc == nil {
+		return nil
+	}
+	return mw.PayloadFunc(data)
+}
+
+// userIndexKey returns the key of the user in the token index of store, prefixed with the tenant in ctx if present
+func (mw *GinJWTMiddleware) userIndexKey(ctx context.Context, identity any) string {
+	user := fmt.Sprint(identity)
+	if v, ok := identity.(float64); ok {
+		// numeric identity claims are decoded as float64, avoid the exponent format of large numbers
+		user = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if tenant, _ := ctx.Value(tenantCtxKey{}).(string); tenant != "" {
+		return tenantKeyPrefix(tenant) + user
+	}
+	return user
+}
+
+// ListSessions returns the active refresh token sessions, the key of the map is the token key in store,
+// which is hashed if HashRefreshAtRest is set. If the ctx carries a tenant, only the sessions of the tenant
+// are listed. The refresh token store must implement core.SessionTracker, both built-in stores implement it.
+func (mw *GinJWTMiddleware) ListSessions(ctx context.Context) (map[string]*core.RefreshTokenData, error) {
+	tracker, ok := storeAs[core.SessionTracker](mw.RefreshTokenStore)
+	if !ok {
+		return nil, ErrSessionTrackingNotSupported
+	}
+	prefix := ""
+	if tenant, _ := ctx.Value(tenantCtxKey{}).(string); tenant != "" {
+		prefix = tenantKeyPrefix(tenant)
+	}
+	var sessions map[string]*core.RefreshTokenData
+	err := mw.withStoreTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		sessions, err = tracker.ListSessions(ctx, prefix)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key := range sessions {
+		if strings.HasPrefix(key, "!") { // revoked access tokens and refresh token families
+			delete(sessions, key)
+		}
+	}
+	return sessions, nil
 }
 
 // CheckIfTokenExpire check if token expire
@@ -1026,17 +2615,15 @@ func (mw *GinJWTMiddleware) CheckIfTokenExpire(c *gin.Context) (jwt.MapClaims, e
 		return nil, errors.New("invalid token claims type")
 	}
 
-	origIatValue, exists := claims["orig_iat"]
-	if !exists {
+	if _, exists := claims["orig_iat"]; !exists {
 		return nil, errors.New("missing orig_iat claim")
 	}
 
-	origIatFloat, ok := origIatValue.(float64)
+	origIat, ok := ClaimInt64(claims, "orig_iat")
 	if !ok {
 		return nil, errors.New("invalid orig_iat format")
 	}
 
-	origIat := int64(origIatFloat)
 	if origIat < mw.TimeFunc().Add(-mw.MaxRefresh).Unix() {
 		return nil, ErrExpiredToken
 	}
@@ -1047,19 +2634,32 @@ func (mw *GinJWTMiddleware) CheckIfTokenExpire(c *gin.Context) (jwt.MapClaims, e
 // ParseTokenString parse jwt token string
 func (mw *GinJWTMiddleware) ParseTokenString(token string) (*jwt.Token, error) {
 	if mw.KeyFunc != nil {
-		return jwt.Parse(token, mw.KeyFunc, mw.ParseOptions...)
+		return jwt.Parse(token, mw.checkHeader(mw.KeyFunc), mw.parserOptions()...)
 	}
 
-	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
+	return jwt.Parse(token, mw.checkHeader(func(t *jwt.Token) (any, error) {
 		if jwt.GetSigningMethod(mw.SigningAlgorithm) != t.Method {
 			return nil, ErrInvalidSigningAlgorithm
 		}
-		if mw.usingPublicKeyAlgo() {
-			return mw.pubKey, nil
-		}
+		return mw.verificationKey(t), nil
+	}), mw.parserOptions()...)
+}
 
-		return mw.Key, nil
-	}, mw.ParseOptions...)
+// checkHeader wraps the key func to validate the typ and crit headers before the signature is verified
+func (mw *GinJWTMiddleware) checkHeader(keyFunc jwt.Keyfunc) jwt.Keyfunc {
+	return func(t *jwt.Token) (any, error) {
+		if crit, ok := t.Header["crit"]; ok && crit != nil {
+			// no header extension is understood, RFC 7515 requires rejecting the token
+			return nil, ErrUnsupportedCritHeader
+		}
+		if mw.RequireTyp {
+			typ, _ := t.Header["typ"].(string)
+			if !strings.EqualFold(typ, mw.TokenType) {
+				return nil, ErrInvalidTokenType
+			}
+		}
+		return keyFunc(t)
+	}
 }
 
 // ExtractClaimsFromToken help to extract the JWT claims from token
@@ -1077,9 +2677,33 @@ func ExtractClaimsFromToken(token *jwt.Token) jwt.MapClaims {
 	return mapClaims
 }
 
+// ClaimInt64 read a numeric claim as int64, numeric claims are decoded as float64
+// by default or as json.Number when UseJSONNumber is enabled
+func ClaimInt64(claims jwt.MapClaims, key string) (int64, bool) {
+	switch v := claims[key].(type) {
+	case float64:
+		return int64(v), true
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			f, err := v.Float64()
+			if err != nil {
+				return 0, false
+			}
+			return int64(f), true
+		}
+		return i, true
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	}
+	return 0, false
+}
+
 // GetToken help to get the JWT token string
 func GetToken(c *gin.Context) string {
-	token, exists := c.Get("JWT_TOKEN")
+	token, exists := c.Get(TokenContextKey)
 	if !exists {
 		return ""
 	}
@@ -1128,9 +2752,10 @@ func (mw *GinJWTMiddleware) ClearSensitiveData() {
 		mw.PrivateKeyPassphrase = ""
 	}
 
-	// Note: RSA keys (mw.privKey, mw.pubKey) are harder to clear completely
+	// Note: RSA, ECDSA and Ed25519 keys (mw.privKey, mw.pubKey) are harder to clear completely
 	// due to Go's garbage collector, but setting to nil helps
 	mw.privKey = nil
+	mw.privKeyID = ""
 	mw.pubKey = nil
 
 	// Clear refresh token store if using in-memory store
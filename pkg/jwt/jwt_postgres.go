@@ -0,0 +1,30 @@
+package jwt
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/moweilong/milady/pkg/jwt/store"
+)
+
+// EnablePostgresStore enables Postgres store on the db with optional configuration
+func (mw *GinJWTMiddleware) EnablePostgresStore(db *sql.DB, opts ...store.PostgresOption) *GinJWTMiddleware {
+	mw.PostgresDB = db
+	mw.PostgresOptions = opts
+	return mw
+}
+
+// initializePostgresStore attempts to create and initialize Postgres store
+// Falls back to in-memory store if the table cannot be created
+func (mw *GinJWTMiddleware) initializePostgresStore() {
+	if mw.PostgresDB != nil {
+		postgresStore, err := store.NewPostgresRefreshTokenStore(mw.PostgresDB, mw.PostgresOptions...)
+		if err != nil {
+			// Fallback to in-memory store
+			log.Printf("Failed to initialize Postgres store: %v, falling back to in-memory store", err)
+			mw.RefreshTokenStore = mw.inMemoryStore
+		} else {
+			mw.RefreshTokenStore = postgresStore
+		}
+	}
+}
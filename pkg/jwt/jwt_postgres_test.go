@@ -0,0 +1,32 @@
+package jwt
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/moweilong/milady/pkg/jwt/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStoreSelection(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS refresh_tokens")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mw := &GinJWTMiddleware{Realm: "test zone", Key: key}
+	mw.EnablePostgresStore(db, store.WithPostgresCleanupInterval(0))
+	require.NoError(t, mw.MiddlewareInit())
+	assert.IsType(t, &store.PostgresRefreshTokenStore{}, mw.RefreshTokenStore)
+
+	// falls back to in-memory store when the table cannot be created
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS refresh_tokens")).WillReturnError(assert.AnError)
+	mw = &GinJWTMiddleware{Realm: "test zone", Key: key}
+	mw.EnablePostgresStore(db, store.WithPostgresCleanupInterval(0))
+	require.NoError(t, mw.MiddlewareInit())
+	assert.Equal(t, mw.inMemoryStore, mw.RefreshTokenStore)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
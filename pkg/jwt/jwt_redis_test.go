@@ -114,6 +114,40 @@ func TestGinJWTMiddleware_RedisStoreFallback(t *testing.T) {
 	)
 }
 
+func TestGinJWTMiddleware_Warmup(t *testing.T) {
+	// unreachable Redis, MiddlewareInit falls back to memory store, Warmup fails fast
+	middleware := &GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           []byte("secret key"),
+		IdentityKey:   "id",
+		Authenticator: testAuthenticator,
+		UseRedisStore: true,
+		RedisConfig: &store.RedisConfig{
+			Addr: "127.0.0.1:1",
+		},
+	}
+	require.NoError(t, middleware.MiddlewareInit())
+	assert.Error(t, middleware.Warmup(context.Background()))
+
+	// memory store and loadable key
+	middleware = &GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           []byte("secret key"),
+		Authenticator: testAuthenticator,
+	}
+	require.NoError(t, middleware.MiddlewareInit())
+	assert.NoError(t, middleware.Warmup(context.Background()))
+
+	// unloadable private key
+	middleware.SigningAlgorithm = "RS256"
+	middleware.PrivKeyFile = "testdata/not-exist.key"
+	assert.ErrorIs(t, middleware.Warmup(context.Background()), ErrNoPrivKeyFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, middleware.Warmup(ctx), context.Canceled)
+}
+
 func TestGinJWTMiddleware_FunctionalOptions(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
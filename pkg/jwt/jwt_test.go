@@ -2,12 +2,23 @@ package jwt
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -21,6 +32,7 @@ import (
 	"github.com/tidwall/gjson"
 
 	"github.com/moweilong/milady/pkg/jwt/core"
+	"github.com/moweilong/milady/pkg/jwt/store"
 )
 
 // Login form structure.
@@ -59,6 +71,10 @@ func makeTokenString(SigningAlgorithm string, username string) string {
 		keyData, _ := os.ReadFile("testdata/jwtRS256.key")
 		signKey, _ := jwt.ParseRSAPrivateKeyFromPEM(keyData)
 		tokenString, _ = token.SignedString(signKey)
+	} else if SigningAlgorithm == "EdDSA" {
+		keyData, _ := os.ReadFile("testdata/jwtEd25519.key")
+		signKey, _ := jwt.ParseEdPrivateKeyFromPEM(keyData)
+		tokenString, _ = token.SignedString(signKey)
 	} else {
 		tokenString, _ = token.SignedString(key)
 	}
@@ -211,6 +227,7 @@ func ginHandler(auth *GinJWTMiddleware) *gin.Engine {
 
 	r.POST("/login", auth.LoginHandler)
 	r.POST("/logout", auth.LogoutHandler)
+	r.POST("/logout_all", auth.LogoutAllHandler)
 	r.POST("/refresh", auth.RefreshHandler)
 
 	group := r.Group("/auth")
@@ -449,6 +466,179 @@ func TestParseTokenRS256(t *testing.T) {
 		})
 }
 
+func TestParseTokenEdDSA(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		Timeout:          time.Hour,
+		MaxRefresh:       time.Hour * 24,
+		SigningAlgorithm: "EdDSA",
+		PrivKeyFile:      "testdata/jwtEd25519.key",
+		PubKeyFile:       "testdata/jwtEd25519.key.pub",
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	handler.GET("/jwks", authMiddleware.JWKSHandler)
+
+	// token generated by login
+	var accessToken string
+	gofight.New().POST("/login").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			accessToken = gjson.Get(r.Body.String(), "access_token").String()
+		})
+	token, err := authMiddleware.ParseTokenString(accessToken)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "EdDSA", token.Header["alg"])
+	}
+
+	for tokenString, code := range map[string]int{
+		accessToken:                       http.StatusOK,
+		makeTokenString("EdDSA", "admin"): http.StatusOK,
+		makeTokenString("RS256", "admin"): http.StatusUnauthorized,
+		makeTokenString("HS256", "admin"): http.StatusUnauthorized,
+	} {
+		gofight.New().GET("/auth/hello").
+			SetHeader(gofight.H{"Authorization": "Bearer " + tokenString}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code)
+			})
+	}
+
+	gofight.New().GET("/jwks").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			keys := gjson.Get(r.Body.String(), "keys").Array()
+			assert.Len(t, keys, 1)
+			assert.Equal(t, "OKP", keys[0].Get("kty").String())
+			assert.Equal(t, "Ed25519", keys[0].Get("crv").String())
+			assert.Equal(t, "EdDSA", keys[0].Get("alg").String())
+		})
+}
+
+func TestParseTokenES256(t *testing.T) {
+	privBytes, err := os.ReadFile("testdata/jwtES256.key")
+	assert.NoError(t, err)
+
+	for _, mw := range []*GinJWTMiddleware{
+		{PrivKeyFile: "testdata/jwtES256.key", PubKeyFile: "testdata/jwtES256.key.pub"},
+		{PrivKeyBytes: privBytes, PubKeyFile: "testdata/jwtES256.key.pub"},
+	} {
+		mw.Realm = "test zone"
+		mw.SigningAlgorithm = "ES256"
+		authMiddleware, err := New(mw)
+		assert.NoError(t, err)
+
+		tokenPair, err := authMiddleware.TokenGenerator(context.Background(), "admin")
+		assert.NoError(t, err)
+		token, err := authMiddleware.ParseTokenString(tokenPair.AccessToken)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "ES256", token.Header["alg"])
+		}
+
+		_, err = authMiddleware.ParseTokenString(makeTokenString("RS256", "admin"))
+		assert.Error(t, err)
+	}
+
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "ES256",
+		PrivKeyFile:      "testdata/jwtES256.key",
+		PubKeyFile:       "testdata/jwtES256.key.pub",
+	})
+	assert.NoError(t, err)
+	handler := ginHandler(authMiddleware)
+	handler.GET("/jwks", authMiddleware.JWKSHandler)
+	gofight.New().GET("/jwks").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			keys := gjson.Get(r.Body.String(), "keys").Array()
+			assert.Len(t, keys, 1)
+			assert.Equal(t, "EC", keys[0].Get("kty").String())
+			assert.Equal(t, "P-256", keys[0].Get("crv").String())
+			assert.Len(t, keys[0].Get("x").String(), 43)
+			assert.Len(t, keys[0].Get("y").String(), 43)
+		})
+
+	// the curve of the key does not match the algorithm
+	_, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "ES384",
+		PrivKeyFile:      "testdata/jwtES256.key",
+		PubKeyFile:       "testdata/jwtES256.key.pub",
+	})
+	assert.Equal(t, ErrKeyAlgorithmMismatch, err)
+
+	_, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "ES256",
+		PrivKeyFile:      "testdata/jwtRS256.key",
+		PubKeyFile:       "testdata/jwtES256.key.pub",
+	})
+	assert.Equal(t, ErrKeyAlgorithmMismatch, err)
+
+	_, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "ES256",
+		PrivKeyBytes:     []byte("Invalid_Private_Key"),
+		PubKeyFile:       "testdata/jwtES256.key.pub",
+	})
+	assert.Equal(t, ErrInvalidPrivKey, err)
+}
+
+func TestKeyFingerprints(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm: "test zone",
+		Key:   key,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ZoWbet5OaMtlBBuIp61SFN9xzc-iff8u_MgKgem6X6A"}, authMiddleware.KeyFingerprints())
+
+	// the signing key and the verification key are the same key pair
+	authMiddleware, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "EdDSA",
+		PrivKeyFile:      "testdata/jwtEd25519.key",
+		PubKeyFile:       "testdata/jwtEd25519.key.pub",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gv2yFrFqvphjmaIBvwzYHZmkYu2c0L8zZUWWqI1ZMeg"}, authMiddleware.KeyFingerprints())
+
+	authMiddleware, err = New(&GinJWTMiddleware{
+		Realm:   "test zone",
+		KeyFunc: keyFunc,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, authMiddleware.KeyFingerprints())
+}
+
+func TestKeyAlgorithmMismatch(t *testing.T) {
+	_, err := New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "EdDSA",
+		PrivKeyFile:      "testdata/jwtRS256.key",
+		PubKeyFile:       "testdata/jwtEd25519.key.pub",
+	})
+	assert.Equal(t, ErrKeyAlgorithmMismatch, err)
+
+	_, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "EdDSA",
+		PrivKeyFile:      "testdata/jwtEd25519.key",
+		PubKeyFile:       "testdata/jwtRS256.key.pub",
+	})
+	assert.Equal(t, ErrKeyAlgorithmMismatch, err)
+
+	_, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "RS256",
+		PrivKeyFile:      "testdata/jwtEd25519.key",
+		PubKeyFile:       "testdata/jwtRS256.key.pub",
+	})
+	assert.Equal(t, ErrKeyAlgorithmMismatch, err)
+}
+
 func TestParseTokenKeyFunc(t *testing.T) {
 	// the middleware to test
 	authMiddleware, _ := New(&GinJWTMiddleware{
@@ -501,6 +691,97 @@ func TestParseTokenKeyFunc(t *testing.T) {
 		})
 }
 
+func TestTrustedUnsigned(t *testing.T) {
+	_, err := New(&GinJWTMiddleware{
+		Realm:           "test zone",
+		Key:             key,
+		TrustedUnsigned: true,
+	})
+	assert.Equal(t, ErrMissingTrustedGateway, err)
+
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:                "test zone",
+		Key:                  key,
+		Authenticator:        defaultAuthenticator,
+		TrustedUnsigned:      true,
+		TrustedGatewayHeader: "X-Trusted-Gateway",
+		TrustedGatewayValue:  "mesh-secret",
+	})
+	assert.NoError(t, err)
+	handler := ginHandler(authMiddleware)
+
+	makeToken := func(exp time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"identity": "admin",
+			"exp":      exp.Unix(),
+			"orig_iat": time.Now().Unix(),
+		})
+		tokenString, _ := token.SignedString([]byte("wrong secret key"))
+		return tokenString
+	}
+	wrongSigned := makeToken(time.Now().Add(time.Hour))
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{"Authorization": "Bearer " + wrongSigned, "X-Trusted-Gateway": "mesh-secret"}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{"Authorization": "Bearer " + wrongSigned}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{"Authorization": "Bearer " + wrongSigned, "X-Trusted-Gateway": "guess"}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+
+	// exp is still enforced
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization":     "Bearer " + makeToken(time.Now().Add(-time.Hour)),
+			"X-Trusted-Gateway": "mesh-secret",
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+			assert.Equal(t, ErrExpiredToken.Error(), gjson.Get(r.Body.String(), "message").String())
+		})
+}
+
+func TestStrictKeyConfig(t *testing.T) {
+	_, err := New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		KeyFunc:          keyFunc,
+		SigningAlgorithm: "RS256",
+		PrivKeyFile:      "testdata/jwtRS256.key",
+		Authenticator:    defaultAuthenticator,
+		StrictKeyConfig:  true,
+	})
+	assert.ErrorIs(t, err, ErrConflictingKeyConfig)
+
+	// only warning without strict mode
+	_, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		KeyFunc:          keyFunc,
+		SigningAlgorithm: "RS256",
+		PrivKeyFile:      "testdata/jwtRS256.key",
+		Authenticator:    defaultAuthenticator,
+	})
+	assert.NoError(t, err)
+
+	// no conflict
+	_, err = New(&GinJWTMiddleware{
+		Realm:           "test zone",
+		KeyFunc:         keyFunc,
+		Authenticator:   defaultAuthenticator,
+		StrictKeyConfig: true,
+	})
+	assert.NoError(t, err)
+}
+
 func TestRefreshHandlerRS256(t *testing.T) {
 	// the middleware to test
 	authMiddleware, _ := New(&GinJWTMiddleware{
@@ -622,117 +903,385 @@ func TestRefreshHandler(t *testing.T) {
 	}
 }
 
-func TestValidRefreshToken(t *testing.T) {
-	// the middleware to test
-	authMiddleware, _ := New(&GinJWTMiddleware{
-		Realm:               "test zone",
-		Key:                 key,
-		Timeout:             time.Hour,
-		MaxRefresh:          2 * time.Hour,
-		RefreshTokenTimeout: 24 * time.Hour, // Long refresh token timeout
-		Authenticator:       defaultAuthenticator,
+func TestTenantRefreshTokens(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		TenantFunc: func(c *gin.Context) string {
+			return c.GetHeader("X-Tenant")
+		},
 	})
+	assert.NoError(t, err)
 
 	handler := ginHandler(authMiddleware)
-
 	r := gofight.New()
 
-	// Test that a valid refresh token still works
-	refreshToken := getRefreshTokenFromLogin(handler)
-	if refreshToken != "" {
-		r.POST("/auth/refresh_token").
-			SetJSON(gofight.D{
-				"refresh_token": refreshToken,
-			}).
+	login := func(tenant string) string {
+		var refreshToken string
+		r.POST("/login").
+			SetHeader(gofight.H{"X-Tenant": tenant}).
 			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
 				assert.Equal(t, http.StatusOK, r.Code)
+				refreshToken = gjson.Get(r.Body.String(), "refresh_token").String()
 			})
+		return refreshToken
 	}
-}
-
-func TestExpiredTokenOnRefreshHandler(t *testing.T) {
-	// the middleware to test
-	authMiddleware, _ := New(&GinJWTMiddleware{
-		Realm:               "test zone",
-		Key:                 key,
-		Timeout:             time.Hour,
-		RefreshTokenTimeout: time.Millisecond, // Very short refresh token timeout
-		Authenticator:       defaultAuthenticator,
-	})
+	tokenA, tokenB := login("A"), login("B")
+	assert.NotEmpty(t, tokenA)
+	assert.NotEmpty(t, tokenB)
 
-	handler := ginHandler(authMiddleware)
+	// tokens are namespaced, tenant B can not use the token of tenant A
+	r.POST("/auth/refresh_token").
+		SetHeader(gofight.H{"X-Tenant": "B"}).
+		SetJSON(gofight.D{"refresh_token": tokenA}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
 
-	r := gofight.New()
+	n, err := authMiddleware.RevokeAllForTenant(context.Background(), "B")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
 
-	// Get a refresh token and wait for it to expire
-	refreshToken := getRefreshTokenFromLogin(handler)
-	if refreshToken != "" {
-		// Wait for the refresh token to expire
-		time.Sleep(2 * time.Millisecond)
+	r.POST("/auth/refresh_token").
+		SetHeader(gofight.H{"X-Tenant": "A"}).
+		SetJSON(gofight.D{"refresh_token": tokenA}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+	r.POST("/auth/refresh_token").
+		SetHeader(gofight.H{"X-Tenant": "B"}).
+		SetJSON(gofight.D{"refresh_token": tokenB}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
 
-		r.POST("/auth/refresh_token").
-			SetJSON(gofight.D{
-				"refresh_token": refreshToken,
-			}).
-			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
-				assert.Equal(t, http.StatusUnauthorized, r.Code)
-			})
-	}
+	_, err = authMiddleware.RevokeAllForTenant(context.Background(), "")
+	assert.Equal(t, ErrMissingTenant, err)
 }
 
-func TestAuthorizer(t *testing.T) {
-	// the middleware to test
-	authMiddleware, _ := New(&GinJWTMiddleware{
-		Realm:         "test zone",
-		Key:           key,
-		Timeout:       time.Hour,
-		MaxRefresh:    time.Hour * 24,
-		Authenticator: defaultAuthenticator,
-		Authorizer: func(c *gin.Context, data any) bool {
-			return data.(string) == "admin"
+func TestHashRefreshAtRest(t *testing.T) {
+	tokenStore := store.NewInMemoryRefreshTokenStore()
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
 		},
+		RefreshTokenStore: tokenStore,
+		HashRefreshAtRest: true,
 	})
+	assert.NoError(t, err)
 
 	handler := ginHandler(authMiddleware)
+	refreshToken := getRefreshTokenFromLogin(handler)
+	assert.NotEmpty(t, refreshToken)
 
-	r := gofight.New()
-
-	r.GET("/auth/hello").
-		SetHeader(gofight.H{
-			"Authorization": "Bearer " + makeTokenString("HS256", "test"),
-		}).
-		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
-			assert.Equal(t, http.StatusForbidden, r.Code)
-		})
+	ctx := context.Background()
+	_, err = tokenStore.Get(ctx, refreshToken)
+	assert.ErrorIs(t, err, core.ErrRefreshTokenNotFound)
+	sum := sha256.Sum256([]byte(refreshToken))
+	userData, err := tokenStore.Get(ctx, hex.EncodeToString(sum[:]))
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", userData)
 
-	r.GET("/auth/hello").
-		SetHeader(gofight.H{
-			"Authorization": "Bearer " + makeTokenString("HS256", "admin"),
-		}).
+	gofight.New().POST("/auth/refresh_token").
+		SetJSON(gofight.D{"refresh_token": refreshToken}).
 		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
 			assert.Equal(t, http.StatusOK, r.Code)
+			newRefreshToken := gjson.Get(r.Body.String(), "refresh_token").String()
+			_, err := tokenStore.Get(ctx, newRefreshToken)
+			assert.ErrorIs(t, err, core.ErrRefreshTokenNotFound)
 		})
+
+	count, err := tokenStore.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
 }
 
-func TestParseTokenWithJsonNumber(t *testing.T) {
-	authMiddleware, _ := New(&GinJWTMiddleware{
-		Realm:         "test zone",
-		Key:           key,
-		Timeout:       time.Hour,
-		MaxRefresh:    time.Hour * 24,
-		Authenticator: defaultAuthenticator,
-		Unauthorized: func(c *gin.Context, code int, message string) {
-			c.String(code, message)
+func TestAuthenticatorsChain(t *testing.T) {
+	var calls []string
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticators: []func(c *gin.Context) (any, error){
+			func(c *gin.Context) (any, error) {
+				calls = append(calls, "password")
+				return nil, ErrFailedAuthentication
+			},
+			func(c *gin.Context) (any, error) {
+				calls = append(calls, "apikey")
+				if c.GetHeader("X-API-Key") != "secret" {
+					return nil, ErrFailedAuthentication
+				}
+				return "admin", nil
+			},
+		},
+		Authenticator: func(c *gin.Context) (any, error) {
+			calls = append(calls, "fallback")
+			return nil, ErrForbidden
 		},
-		ParseOptions: []jwt.ParserOption{jwt.WithJSONNumber()},
 	})
+	assert.NoError(t, err)
 
 	handler := ginHandler(authMiddleware)
-
 	r := gofight.New()
 
-	r.GET("/auth/hello").
-		SetHeader(gofight.H{
+	r.POST("/login").
+		SetHeader(gofight.H{"X-API-Key": "secret"}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			assert.NotEmpty(t, gjson.Get(r.Body.String(), "access_token").String())
+			assert.NotEmpty(t, gjson.Get(r.Body.String(), "refresh_token").String())
+		})
+	assert.Equal(t, []string{"password", "apikey"}, calls)
+
+	// all authenticators fail, falls back to Authenticator
+	calls = nil
+	gofight.New().POST("/login").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+			assert.Equal(t, ErrForbidden.Error(), gjson.Get(r.Body.String(), "message").String())
+		})
+	assert.Equal(t, []string{"password", "apikey", "fallback"}, calls)
+}
+
+func TestRefreshTokenCookie(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		SendCookie:        true,
+		RefreshCookieName: "refresh_token",
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	r := gofight.New()
+
+	getRefreshCookie := func(header http.Header) *http.Cookie {
+		for _, cookie := range (&http.Response{Header: header}).Cookies() {
+			if cookie.Name == "refresh_token" {
+				return cookie
+			}
+		}
+		return nil
+	}
+
+	var refreshToken string
+	r.POST("/login").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			cookie := getRefreshCookie(r.HeaderMap)
+			if assert.NotNil(t, cookie) {
+				assert.True(t, cookie.HttpOnly)
+				refreshToken, _ = url.QueryUnescape(cookie.Value)
+				assert.Equal(t, gjson.Get(r.Body.String(), "refresh_token").String(), refreshToken)
+			}
+		})
+
+	// refresh using only the refresh token cookie
+	r.POST("/auth/refresh_token").
+		SetCookie(gofight.H{
+			"refresh_token": refreshToken,
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			assert.NotEmpty(t, gjson.Get(r.Body.String(), "access_token").String())
+			cookie := getRefreshCookie(r.HeaderMap)
+			if assert.NotNil(t, cookie) {
+				newRefreshToken, _ := url.QueryUnescape(cookie.Value)
+				assert.Equal(t, gjson.Get(r.Body.String(), "refresh_token").String(), newRefreshToken)
+				assert.NotEqual(t, refreshToken, newRefreshToken)
+			}
+		})
+
+	// the old refresh token has been revoked
+	r.POST("/auth/refresh_token").
+		SetCookie(gofight.H{
+			"refresh_token": refreshToken,
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+
+	// logout deletes the refresh token cookie
+	r.POST("/logout").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			cookie := getRefreshCookie(r.HeaderMap)
+			if assert.NotNil(t, cookie) {
+				assert.Equal(t, "", cookie.Value)
+				assert.Equal(t, -1, cookie.MaxAge)
+			}
+		})
+}
+
+func TestValidRefreshToken(t *testing.T) {
+	// the middleware to test
+	authMiddleware, _ := New(&GinJWTMiddleware{
+		Realm:               "test zone",
+		Key:                 key,
+		Timeout:             time.Hour,
+		MaxRefresh:          2 * time.Hour,
+		RefreshTokenTimeout: 24 * time.Hour, // Long refresh token timeout
+		Authenticator:       defaultAuthenticator,
+	})
+
+	handler := ginHandler(authMiddleware)
+
+	r := gofight.New()
+
+	// Test that a valid refresh token still works
+	refreshToken := getRefreshTokenFromLogin(handler)
+	if refreshToken != "" {
+		r.POST("/auth/refresh_token").
+			SetJSON(gofight.D{
+				"refresh_token": refreshToken,
+			}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusOK, r.Code)
+			})
+	}
+}
+
+func TestExpiredTokenOnRefreshHandler(t *testing.T) {
+	// the middleware to test
+	authMiddleware, _ := New(&GinJWTMiddleware{
+		Realm:               "test zone",
+		Key:                 key,
+		Timeout:             time.Hour,
+		RefreshTokenTimeout: time.Millisecond, // Very short refresh token timeout
+		Authenticator:       defaultAuthenticator,
+	})
+
+	handler := ginHandler(authMiddleware)
+
+	r := gofight.New()
+
+	// Get a refresh token and wait for it to expire
+	refreshToken := getRefreshTokenFromLogin(handler)
+	if refreshToken != "" {
+		// Wait for the refresh token to expire
+		time.Sleep(2 * time.Millisecond)
+
+		r.POST("/auth/refresh_token").
+			SetJSON(gofight.D{
+				"refresh_token": refreshToken,
+			}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusUnauthorized, r.Code)
+			})
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	// the middleware to test
+	authMiddleware, _ := New(&GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           key,
+		Timeout:       time.Hour,
+		MaxRefresh:    time.Hour * 24,
+		Authenticator: defaultAuthenticator,
+		Authorizer: func(c *gin.Context, data any) bool {
+			return data.(string) == "admin"
+		},
+	})
+
+	handler := ginHandler(authMiddleware)
+
+	r := gofight.New()
+
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + makeTokenString("HS256", "test"),
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusForbidden, r.Code)
+		})
+
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + makeTokenString("HS256", "admin"),
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+}
+
+func TestForbiddenStatusCode(t *testing.T) {
+	// the middleware to test
+	authMiddleware, _ := New(&GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           key,
+		Timeout:       time.Hour,
+		Authenticator: defaultAuthenticator,
+		Authorizer: func(c *gin.Context, data any) bool {
+			return data.(string) == "admin"
+		},
+		ForbiddenStatusCode: http.StatusNotFound,
+	})
+
+	handler := ginHandler(authMiddleware)
+
+	r := gofight.New()
+
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + makeTokenString("HS256", "test"),
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusNotFound, r.Code)
+		})
+
+	// authentication failure is still unauthorized
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer invalid",
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + makeTokenString("HS256", "admin"),
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+}
+
+func TestParseTokenWithJsonNumber(t *testing.T) {
+	authMiddleware, _ := New(&GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           key,
+		Timeout:       time.Hour,
+		MaxRefresh:    time.Hour * 24,
+		Authenticator: defaultAuthenticator,
+		Unauthorized: func(c *gin.Context, code int, message string) {
+			c.String(code, message)
+		},
+		ParseOptions: []jwt.ParserOption{jwt.WithJSONNumber()},
+	})
+
+	handler := ginHandler(authMiddleware)
+
+	r := gofight.New()
+
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
 			"Authorization": "Bearer " + makeTokenString("HS256", "admin"),
 		}).
 		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
@@ -740,6 +1289,94 @@ func TestParseTokenWithJsonNumber(t *testing.T) {
 		})
 }
 
+func TestUseJSONNumber(t *testing.T) {
+	for _, useJSONNumber := range []bool{false, true} {
+		authMiddleware, err := New(&GinJWTMiddleware{
+			Realm:         "test zone",
+			Key:           key,
+			Timeout:       time.Hour,
+			Authenticator: defaultAuthenticator,
+			UseJSONNumber: useJSONNumber,
+			PayloadFunc: func(data any) jwt.MapClaims {
+				return jwt.MapClaims{"uid": int64(9007199254740993)}
+			},
+		})
+		assert.NoError(t, err)
+
+		tokenString, expire, err := authMiddleware.generateAccessToken("admin")
+		assert.NoError(t, err)
+		token, err := authMiddleware.ParseTokenString(tokenString)
+		assert.NoError(t, err)
+		claims := ExtractClaimsFromToken(token)
+
+		if useJSONNumber {
+			assert.IsType(t, json.Number(""), claims["exp"])
+		} else {
+			assert.IsType(t, float64(0), claims["exp"])
+		}
+
+		exp, ok := ClaimInt64(claims, "exp")
+		assert.True(t, ok)
+		assert.Equal(t, expire.Unix(), exp)
+
+		uid, ok := ClaimInt64(claims, "uid")
+		assert.True(t, ok)
+		if useJSONNumber {
+			assert.Equal(t, int64(9007199254740993), uid) // no precision loss
+		}
+
+		_, ok = ClaimInt64(claims, "missing")
+		assert.False(t, ok)
+	}
+}
+
+func TestUseJSONNumberParseOptions(t *testing.T) {
+	authMiddleware := &GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           key,
+		UseJSONNumber: true,
+		KeyFunc: func(*jwt.Token) (any, error) {
+			return key, nil
+		},
+		ParseOptions: []jwt.ParserOption{jwt.WithLeeway(time.Second)},
+	}
+	// the option of UseJSONNumber is not appended to ParseOptions on each init
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, authMiddleware.MiddlewareInit())
+		assert.Len(t, authMiddleware.ParseOptions, 1)
+	}
+
+	tokenString, _, err := authMiddleware.generateAccessToken("admin")
+	assert.NoError(t, err)
+	token, err := authMiddleware.ParseTokenString(tokenString)
+	assert.NoError(t, err)
+	assert.IsType(t, json.Number(""), ExtractClaimsFromToken(token)["exp"])
+}
+
+func TestClaimInt64(t *testing.T) {
+	claims := jwt.MapClaims{
+		"float":    float64(10),
+		"number":   json.Number("20"),
+		"fraction": json.Number("30.5"),
+		"invalid":  json.Number("foo"),
+		"string":   "40",
+	}
+
+	v, ok := ClaimInt64(claims, "float")
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), v)
+	v, ok = ClaimInt64(claims, "number")
+	assert.True(t, ok)
+	assert.Equal(t, int64(20), v)
+	v, ok = ClaimInt64(claims, "fraction")
+	assert.True(t, ok)
+	assert.Equal(t, int64(30), v)
+	_, ok = ClaimInt64(claims, "invalid")
+	assert.False(t, ok)
+	_, ok = ClaimInt64(claims, "string")
+	assert.False(t, ok)
+}
+
 func TestClaimsDuringAuthorization(t *testing.T) {
 	// the middleware to test
 	authMiddleware, _ := New(&GinJWTMiddleware{
@@ -1222,23 +1859,79 @@ func TestExpiredTokenOnAuth(t *testing.T) {
 		})
 }
 
-func TestBadTokenOnRefreshHandler(t *testing.T) {
-	// the middleware to test
-	authMiddleware, _ := New(&GinJWTMiddleware{
-		Realm:         "test zone",
-		Key:           key,
-		Timeout:       time.Hour,
-		Authenticator: defaultAuthenticator,
+func TestNotBeforeIssuedAt(t *testing.T) {
+	cutoff := time.Now().Add(-time.Hour)
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:             "test zone",
+		Key:               key,
+		Timeout:           time.Hour,
+		Authenticator:     defaultAuthenticator,
+		NotBeforeIssuedAt: cutoff,
 	})
+	assert.NoError(t, err)
 
-	handler := ginHandler(authMiddleware)
+	makeToken := func(origIat time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"identity": "admin",
+			"exp":      time.Now().Add(time.Hour).Unix(),
+			"orig_iat": origIat.Unix(),
+		})
+		tokenString, _ := token.SignedString(key)
+		return tokenString
+	}
 
+	handler := ginHandler(authMiddleware)
 	r := gofight.New()
 
-	// Test with bad refresh token
-	r.POST("/auth/refresh_token").
-		SetJSON(gofight.D{
-			"refresh_token": "BadToken",
+	// minted before the cutoff
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + makeToken(cutoff.Add(-time.Minute)),
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			message := gjson.Get(r.Body.String(), "message")
+			assert.Equal(t, ErrTokenIssuedBeforeCutoff.Error(), message.String())
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+
+	// minted after the cutoff
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + makeToken(cutoff.Add(time.Minute)),
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+
+	// token generated by middleware
+	tokenPair, err := authMiddleware.TokenGenerator(context.Background(), "admin")
+	assert.NoError(t, err)
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + tokenPair.AccessToken,
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+}
+
+func TestBadTokenOnRefreshHandler(t *testing.T) {
+	// the middleware to test
+	authMiddleware, _ := New(&GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           key,
+		Timeout:       time.Hour,
+		Authenticator: defaultAuthenticator,
+	})
+
+	handler := ginHandler(authMiddleware)
+
+	r := gofight.New()
+
+	// Test with bad refresh token
+	r.POST("/auth/refresh_token").
+		SetJSON(gofight.D{
+			"refresh_token": "BadToken",
 		}).
 		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
 			assert.Equal(t, http.StatusUnauthorized, r.Code)
@@ -1509,6 +2202,48 @@ func TestTokenGenerator(t *testing.T) {
 	assert.Equal(t, userData, claims["identity"])
 }
 
+func TestMirrorExpField(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		PayloadFunc: func(data any) jwt.MapClaims {
+			return jwt.MapClaims{
+				"identity":   data,
+				"expires_at": 1,
+			}
+		},
+		MirrorExpField: "expires_at",
+	})
+	assert.NoError(t, err)
+
+	tokenPair, err := authMiddleware.TokenGenerator(context.Background(), "admin")
+	assert.NoError(t, err)
+
+	token, err := authMiddleware.ParseTokenString(tokenPair.AccessToken)
+	assert.NoError(t, err)
+	assert.True(t, token.Valid)
+
+	claims := token.Claims.(jwt.MapClaims)
+	exp, ok := ClaimInt64(claims, "exp")
+	assert.True(t, ok)
+	assert.Equal(t, tokenPair.ExpiresAt, exp)
+	mirror, ok := ClaimInt64(claims, "expires_at")
+	assert.True(t, ok)
+	assert.Equal(t, exp, mirror)
+
+	handler := ginHandler(authMiddleware)
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{"Authorization": "Bearer " + tokenPair.AccessToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+}
+
 func TestTokenGeneratorWithRevocation(t *testing.T) {
 	authMiddleware, err := New(&GinJWTMiddleware{
 		Realm:      "test zone",
@@ -1846,3 +2581,1460 @@ func TestWWWAuthenticateHeaderWithDifferentRealms(t *testing.T) {
 		})
 	}
 }
+
+func TestWWWAuthenticateHeaderWithRealmFunc(t *testing.T) {
+	authMiddleware, _ := New(&GinJWTMiddleware{
+		Realm:         "default zone",
+		Key:           key,
+		Timeout:       time.Hour,
+		Authenticator: defaultAuthenticator,
+		RealmFunc: func(c *gin.Context) string {
+			if tenant, _, ok := strings.Cut(c.GetHeader("X-Forwarded-Host"), "."); ok {
+				return tenant + " zone"
+			}
+			return ""
+		},
+	})
+
+	handler := ginHandler(authMiddleware)
+
+	r := gofight.New()
+
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization":    "Bearer invalid",
+			"X-Forwarded-Host": "acme.example.com",
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+			assert.Equal(t, `JWT realm="acme zone"`, r.HeaderMap.Get("WWW-Authenticate")) //nolint:staticcheck
+		})
+
+	r.GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization":    "Bearer invalid",
+			"X-Forwarded-Host": "localhost",
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+			assert.Equal(t, `JWT realm="default zone"`, r.HeaderMap.Get("WWW-Authenticate")) //nolint:staticcheck
+		})
+}
+
+type testIdentity struct {
+	UserID int64    `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+// jsonRoundTripStore simulates a remote store which serializes user data as JSON
+type jsonRoundTripStore struct {
+	core.TokenStore
+}
+
+func (s *jsonRoundTripStore) Set(ctx context.Context, token string, userData any, expiry time.Time) error {
+	data, err := json.Marshal(userData)
+	if err != nil {
+		return err
+	}
+	return s.TokenStore.Set(ctx, token, data, expiry)
+}
+
+func (s *jsonRoundTripStore) Get(ctx context.Context, token string) (any, error) {
+	data, err := s.TokenStore.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	var userData any
+	err = json.Unmarshal(data.([]byte), &userData)
+	return userData, err
+}
+
+func TestRefreshDataCodec(t *testing.T) {
+	identity := testIdentity{UserID: 42, Roles: []string{"admin", "editor"}}
+	ctx := context.Background()
+
+	// without codec, the struct identity does not survive the store round trip
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:             "test zone",
+		Key:               key,
+		RefreshTokenStore: &jsonRoundTripStore{TokenStore: store.NewInMemoryRefreshTokenStore()},
+	})
+	assert.NoError(t, err)
+	tokenPair, err := authMiddleware.TokenGenerator(ctx, identity)
+	assert.NoError(t, err)
+	userData, err := authMiddleware.validateRefreshToken(ctx, tokenPair.RefreshToken)
+	assert.NoError(t, err)
+	assert.IsType(t, map[string]any{}, userData)
+
+	// with codec, the typed identity is reconstructed intact
+	authMiddleware, err = New(&GinJWTMiddleware{
+		Realm:             "test zone",
+		Key:               key,
+		RefreshTokenStore: &jsonRoundTripStore{TokenStore: store.NewInMemoryRefreshTokenStore()},
+		RefreshDataCodec: &RefreshDataCodec{
+			Marshal: json.Marshal,
+			Unmarshal: func(data []byte) (any, error) {
+				var v testIdentity
+				err := json.Unmarshal(data, &v)
+				return v, err
+			},
+		},
+	})
+	assert.NoError(t, err)
+	tokenPair, err = authMiddleware.TokenGenerator(ctx, identity)
+	assert.NoError(t, err)
+	userData, err = authMiddleware.validateRefreshToken(ctx, tokenPair.RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, identity, userData)
+
+	// refresh keeps the typed identity
+	newPair, err := authMiddleware.TokenGeneratorWithRevocation(ctx, userData, tokenPair.RefreshToken)
+	assert.NoError(t, err)
+	userData, err = authMiddleware.validateRefreshToken(ctx, newPair.RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, identity, userData)
+}
+
+func writeTestPubKey(t *testing.T, dir string, name string, key *rsa.PrivateKey) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
+
+func TestPubKeyDir(t *testing.T) {
+	dir := t.TempDir()
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	writeTestPubKey(t, dir, "key1.pem", key1)
+	writeTestPubKey(t, dir, "key2.pem", key2)
+
+	privBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key1)})
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "RS256",
+		PrivKeyBytes:     privBytes,
+		PubKeyDir:        dir,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, authMiddleware.pubKeys, 2)
+
+	signToken := func(signKey *rsa.PrivateKey, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"identity": "admin",
+			"exp":      time.Now().Add(time.Hour).Unix(),
+		})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		tokenString, err := token.SignedString(signKey)
+		assert.NoError(t, err)
+		return tokenString
+	}
+
+	// signed by either key, with or without kid
+	for _, tokenString := range []string{
+		signToken(key1, ""),
+		signToken(key2, ""),
+		signToken(key2, publicKeyID(&key2.PublicKey)),
+	} {
+		_, err = authMiddleware.ParseTokenString(tokenString)
+		assert.NoError(t, err)
+	}
+
+	// token generated by middleware
+	tokenPair, err := authMiddleware.TokenGenerator(context.Background(), "admin")
+	assert.NoError(t, err)
+	_, err = authMiddleware.ParseTokenString(tokenPair.AccessToken)
+	assert.NoError(t, err)
+
+	// signed by unknown key
+	key3, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, err = authMiddleware.ParseTokenString(signToken(key3, ""))
+	assert.Error(t, err)
+
+	// all keys are exposed in jwks
+	handler := ginHandler(authMiddleware)
+	handler.GET("/jwks", authMiddleware.JWKSHandler)
+	r := gofight.New()
+	r.GET("/jwks").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			keys := gjson.Get(r.Body.String(), "keys").Array()
+			assert.Len(t, keys, 2)
+			for _, k := range keys {
+				assert.Equal(t, "RSA", k.Get("kty").String())
+				assert.Equal(t, "RS256", k.Get("alg").String())
+			}
+			assert.Equal(t, publicKeyID(&key1.PublicKey)+publicKeyID(&key2.PublicKey),
+				keys[0].Get("kid").String()+keys[1].Get("kid").String())
+		})
+
+	// directory errors
+	_, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "RS256",
+		PrivKeyBytes:     privBytes,
+		PubKeyDir:        t.TempDir(),
+	})
+	assert.Equal(t, ErrNoPubKeyDir, err)
+
+	invalidDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(invalidDir, "invalid.pem"), []byte("invalid"), 0o600))
+	_, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "RS256",
+		PrivKeyBytes:     privBytes,
+		PubKeyDir:        invalidDir,
+	})
+	assert.Equal(t, ErrInvalidPubKey, err)
+}
+
+func TestRefreshRotateThreshold(t *testing.T) {
+	now := time.Now()
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		RefreshTokenTimeout:    time.Hour * 24,
+		RefreshRotateThreshold: time.Hour,
+		TimeFunc: func() time.Time {
+			return now
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	refreshToken := getRefreshTokenFromLogin(handler)
+	assert.NotEmpty(t, refreshToken)
+
+	refresh := func(token string) string {
+		var newRefreshToken string
+		gofight.New().POST("/auth/refresh_token").
+			SetJSON(gofight.D{"refresh_token": token}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusOK, r.Code)
+				assert.NotEmpty(t, gjson.Get(r.Body.String(), "access_token").String())
+				newRefreshToken = gjson.Get(r.Body.String(), "refresh_token").String()
+			})
+		return newRefreshToken
+	}
+
+	// well before the threshold the refresh token is reused
+	now = now.Add(time.Hour)
+	assert.Equal(t, refreshToken, refresh(refreshToken))
+
+	// near expiry the refresh token is rotated and the old one is revoked
+	now = now.Add(time.Hour * 22)
+	newRefreshToken := refresh(refreshToken)
+	assert.NotEmpty(t, newRefreshToken)
+	assert.NotEqual(t, refreshToken, newRefreshToken)
+	_, err = authMiddleware.RefreshTokenStore.Get(context.Background(), refreshToken)
+	assert.ErrorIs(t, err, core.ErrRefreshTokenNotFound)
+}
+
+func TestWatchKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeKeyPair := func(key *rsa.PrivateKey) {
+		privBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "jwt.key"), privBytes, 0o600))
+		writeTestPubKey(t, dir, "jwt.key.pub", key)
+	}
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	writeKeyPair(key1)
+
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "RS256",
+		PrivKeyFile:      filepath.Join(dir, "jwt.key"),
+		PubKeyFile:       filepath.Join(dir, "jwt.key.pub"),
+		WatchKeyFiles:    true,
+		Timeout:          time.Hour,
+		Authenticator:    defaultAuthenticator,
+	})
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, authMiddleware.CloseKeyWatcher()) }()
+
+	signedBy := func(key *rsa.PrivateKey) bool {
+		tokenPair, err := authMiddleware.TokenGenerator(context.Background(), "admin")
+		if err != nil {
+			return false
+		}
+		_, err = jwt.Parse(tokenPair.AccessToken, func(*jwt.Token) (any, error) {
+			return &key.PublicKey, nil
+		})
+		if err != nil {
+			return false
+		}
+		_, err = authMiddleware.ParseTokenString(tokenPair.AccessToken)
+		return err == nil
+	}
+	assert.True(t, signedBy(key1))
+
+	// rewrite the key files, subsequent signing uses the new key
+	writeKeyPair(key2)
+	assert.Eventually(t, func() bool { return signedBy(key2) }, 5*time.Second, 20*time.Millisecond)
+	assert.False(t, signedBy(key1))
+}
+
+func TestRefreshPeekHandler(t *testing.T) {
+	now := time.Now()
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		RefreshTokenTimeout: time.Hour * 24,
+		TimeFunc: func() time.Time {
+			return now
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	handler.POST("/auth/refresh_token/peek", authMiddleware.RefreshPeekHandler())
+	refreshToken := getRefreshTokenFromLogin(handler)
+	assert.NotEmpty(t, refreshToken)
+
+	// peek twice, the refresh token is not rotated
+	for i := 0; i < 2; i++ {
+		gofight.New().POST("/auth/refresh_token/peek").
+			SetJSON(gofight.D{"refresh_token": refreshToken}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusOK, r.Code)
+				assert.Equal(t, "admin", gjson.Get(r.Body.String(), "user_data").String())
+				assert.Equal(t, now.Add(time.Hour*24).Unix(), gjson.Get(r.Body.String(), "expires_at").Int())
+				assert.False(t, gjson.Get(r.Body.String(), "access_token").Exists())
+			})
+	}
+	userData, err := authMiddleware.RefreshTokenStore.Get(context.Background(), refreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", userData)
+
+	gofight.New().POST("/auth/refresh_token/peek").
+		SetJSON(gofight.D{"refresh_token": "invalid"}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+	gofight.New().POST("/auth/refresh_token/peek").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusBadRequest, r.Code)
+		})
+
+}
+
+func TestRefreshPeekHandlerNoSideEffects(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:   "test zone",
+		Key:     key,
+		Timeout: time.Hour,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		TrackLastUsed:              true,
+		RefreshTokenReuseDetection: true,
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	handler.POST("/auth/refresh_token/peek", authMiddleware.RefreshPeekHandler())
+	call := func(path string, refreshToken string, code int) string {
+		var newRefreshToken string
+		gofight.New().POST(path).
+			SetJSON(gofight.D{"refresh_token": refreshToken}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code, path)
+				newRefreshToken = gjson.Get(r.Body.String(), "refresh_token").String()
+			})
+		return newRefreshToken
+	}
+
+	oldToken := getRefreshTokenFromLogin(handler)
+	newToken := call("/auth/refresh_token", oldToken, http.StatusOK)
+
+	// the last used time is not recorded by peek
+	call("/auth/refresh_token/peek", newToken, http.StatusOK)
+	sessions, err := authMiddleware.ListSessions(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	for _, session := range sessions {
+		assert.True(t, session.LastUsed.IsZero())
+	}
+
+	// peeking the rotated token does not revoke the family
+	call("/auth/refresh_token/peek", oldToken, http.StatusUnauthorized)
+	call("/auth/refresh_token", newToken, http.StatusOK)
+}
+
+func TestCookieMaxAgeFunc(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:   "test zone",
+		Key:     key,
+		Timeout: time.Hour,
+		Authenticator: func(c *gin.Context) (any, error) {
+			var loginVals struct {
+				Username   string `json:"username"`
+				Password   string `json:"password"`
+				RememberMe bool   `json:"remember_me"`
+			}
+			if err := c.ShouldBindJSON(&loginVals); err != nil {
+				return "", ErrMissingLoginValues
+			}
+			if loginVals.Username != "admin" || loginVals.Password != "admin" {
+				return "", ErrFailedAuthentication
+			}
+			c.Set("remember_me", loginVals.RememberMe)
+			return loginVals.Username, nil
+		},
+		SendCookie: true,
+		CookieName: "jwt",
+		CookieMaxAgeFunc: func(c *gin.Context) time.Duration {
+			if c.GetBool("remember_me") {
+				return 30 * 24 * time.Hour
+			}
+			return 0
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+
+	gofight.New().POST("/login").
+		SetJSON(gofight.D{
+			"username": "admin",
+			"password": "admin",
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			//nolint:staticcheck
+			assert.True(t, strings.HasSuffix(r.HeaderMap.Get("Set-Cookie"), "; Max-Age=3600"))
+		})
+
+	gofight.New().POST("/login").
+		SetJSON(gofight.D{
+			"username":    "admin",
+			"password":    "admin",
+			"remember_me": true,
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			//nolint:staticcheck
+			assert.True(t, strings.HasSuffix(r.HeaderMap.Get("Set-Cookie"), "; Max-Age=2592000"))
+		})
+}
+
+func TestRequireTyp(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           key,
+		Timeout:       time.Hour,
+		Authenticator: defaultAuthenticator,
+		RequireTyp:    true,
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	makeToken := func(header map[string]any) string {
+		token := jwt.New(jwt.GetSigningMethod("HS256"))
+		token.Header = header
+		token.Header["alg"] = "HS256"
+		claims := token.Claims.(jwt.MapClaims)
+		claims["identity"] = "admin"
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		claims["orig_iat"] = time.Now().Unix()
+		tokenString, _ := token.SignedString(key)
+		return tokenString
+	}
+
+	// generated tokens carry typ JWT
+	tokenString, _, err := authMiddleware.generateAccessToken("admin")
+	assert.NoError(t, err)
+	token, err := authMiddleware.ParseTokenString(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "JWT", token.Header["typ"])
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{"Authorization": "Bearer " + makeToken(map[string]any{"typ": "jwt"})}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+
+	// missing typ
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{"Authorization": "Bearer " + makeToken(map[string]any{})}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+	_, err = authMiddleware.ParseTokenString(makeToken(map[string]any{}))
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+
+	// another type
+	_, err = authMiddleware.ParseTokenString(makeToken(map[string]any{"typ": "at+jwt"}))
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+
+	// unknown critical header
+	_, err = authMiddleware.ParseTokenString(makeToken(map[string]any{"typ": "JWT", "crit": []string{"exp"}}))
+	assert.ErrorIs(t, err, ErrUnsupportedCritHeader)
+
+	// custom type
+	authMiddleware.TokenType = "at+jwt"
+	tokenString, _, err = authMiddleware.generateAccessToken("admin")
+	assert.NoError(t, err)
+	_, err = authMiddleware.ParseTokenString(tokenString)
+	assert.NoError(t, err)
+}
+
+func TestTrackLastUsed(t *testing.T) {
+	now := time.Now()
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		RefreshTokenTimeout:    time.Hour * 24,
+		RefreshRotateThreshold: time.Hour,
+		TrackLastUsed:          true,
+		TimeFunc: func() time.Time {
+			return now
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	refreshToken := getRefreshTokenFromLogin(handler)
+	assert.NotEmpty(t, refreshToken)
+
+	lastUsed := func() time.Time {
+		sessions, err := authMiddleware.ListSessions(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+		session, ok := sessions[refreshToken]
+		if !assert.True(t, ok) {
+			t.FailNow()
+		}
+		assert.Equal(t, "admin", session.UserData)
+		return session.LastUsed
+	}
+	assert.True(t, lastUsed().IsZero())
+
+	refresh := func() {
+		gofight.New().POST("/auth/refresh_token").
+			SetJSON(gofight.D{"refresh_token": refreshToken}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusOK, r.Code)
+				assert.Equal(t, refreshToken, gjson.Get(r.Body.String(), "refresh_token").String())
+			})
+	}
+
+	now = now.Add(time.Minute)
+	refresh()
+	first := lastUsed()
+	assert.Equal(t, now.Unix(), first.Unix())
+
+	now = now.Add(time.Minute)
+	refresh()
+	assert.True(t, lastUsed().After(first))
+
+	// store without session tracking
+	authMiddleware.RefreshTokenStore = store.NewCachedTokenStore(struct{ core.TokenStore }{authMiddleware.RefreshTokenStore}, 0, 0)
+	_, err = authMiddleware.ListSessions(context.Background())
+	assert.ErrorIs(t, err, ErrSessionTrackingNotSupported)
+}
+
+func TestAccessTokenBlacklist(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		AccessTokenBlacklist: true,
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	login := func() string {
+		var accessToken string
+		gofight.New().POST("/login").
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusOK, r.Code)
+				accessToken = gjson.Get(r.Body.String(), "access_token").String()
+			})
+		return accessToken
+	}
+	hello := func(accessToken string, code int) {
+		gofight.New().GET("/auth/hello").
+			SetHeader(gofight.H{"Authorization": "Bearer " + accessToken}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code)
+			})
+	}
+
+	accessToken, otherToken := login(), login()
+	token, err := authMiddleware.ParseTokenString(accessToken)
+	assert.NoError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+	assert.NotEmpty(t, claims["jti"])
+	hello(accessToken, http.StatusOK)
+
+	gofight.New().POST("/logout").
+		SetHeader(gofight.H{"Authorization": "Bearer " + accessToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+	hello(accessToken, http.StatusUnauthorized)
+	hello(otherToken, http.StatusOK)
+
+	revoked, err := authMiddleware.IsAccessTokenRevoked(context.Background(), claims["jti"].(string))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// the revoked jti is not a session
+	sessions, err := authMiddleware.ListSessions(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	// the revocation expires with the token
+	assert.NoError(t, authMiddleware.RevokeAccessToken(context.Background(), "expiring", time.Now().Add(20*time.Millisecond)))
+	revoked, _ = authMiddleware.IsAccessTokenRevoked(context.Background(), "expiring")
+	assert.True(t, revoked)
+	time.Sleep(30 * time.Millisecond)
+	revoked, _ = authMiddleware.IsAccessTokenRevoked(context.Background(), "expiring")
+	assert.False(t, revoked)
+
+	assert.ErrorIs(t, authMiddleware.RevokeAccessToken(context.Background(), "", time.Now().Add(time.Hour)), ErrMissingJTI)
+}
+
+func TestJWKSHandlerKeyID(t *testing.T) {
+	jwks := func(authMiddleware *GinJWTMiddleware) gjson.Result {
+		handler := ginHandler(authMiddleware)
+		handler.GET("/jwks", authMiddleware.JWKSHandler)
+		var keys []gjson.Result
+		gofight.New().GET("/jwks").
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusOK, r.Code)
+				keys = gjson.Get(r.Body.String(), "keys").Array()
+			})
+		if !assert.Len(t, keys, 1) {
+			t.FailNow()
+		}
+		return keys[0]
+	}
+	tokenKeyID := func(authMiddleware *GinJWTMiddleware) string {
+		tokenPair, err := authMiddleware.TokenGenerator(context.Background(), "admin")
+		assert.NoError(t, err)
+		token, err := authMiddleware.ParseTokenString(tokenPair.AccessToken)
+		assert.NoError(t, err)
+		kid, _ := token.Header["kid"].(string)
+		return kid
+	}
+	decode := func(s string) *big.Int {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		assert.NoError(t, err)
+		return new(big.Int).SetBytes(b)
+	}
+
+	// RSA
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "RS256",
+		PrivKeyFile:      "testdata/jwtRS256.key",
+		PubKeyFile:       "testdata/jwtRS256.key.pub",
+	})
+	assert.NoError(t, err)
+	keyData, err := os.ReadFile("testdata/jwtRS256.key.pub")
+	assert.NoError(t, err)
+	rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(keyData)
+	assert.NoError(t, err)
+
+	key := jwks(authMiddleware)
+	assert.Equal(t, "RSA", key.Get("kty").String())
+	assert.Equal(t, "sig", key.Get("use").String())
+	assert.Equal(t, "RS256", key.Get("alg").String())
+	assert.Equal(t, 0, rsaKey.N.Cmp(decode(key.Get("n").String())))
+	assert.Equal(t, int64(rsaKey.E), decode(key.Get("e").String()).Int64())
+	assert.Equal(t, publicKeyID(rsaKey), key.Get("kid").String())
+	assert.Equal(t, key.Get("kid").String(), tokenKeyID(authMiddleware))
+
+	// EC
+	authMiddleware, err = New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		SigningAlgorithm: "ES256",
+		PrivKeyFile:      "testdata/jwtES256.key",
+		PubKeyFile:       "testdata/jwtES256.key.pub",
+	})
+	assert.NoError(t, err)
+	keyData, err = os.ReadFile("testdata/jwtES256.key.pub")
+	assert.NoError(t, err)
+	ecKey, err := jwt.ParseECPublicKeyFromPEM(keyData)
+	assert.NoError(t, err)
+
+	key = jwks(authMiddleware)
+	assert.Equal(t, "EC", key.Get("kty").String())
+	assert.Equal(t, "P-256", key.Get("crv").String())
+	assert.Equal(t, 0, ecKey.X.Cmp(decode(key.Get("x").String())))
+	assert.Equal(t, 0, ecKey.Y.Cmp(decode(key.Get("y").String())))
+	assert.Equal(t, key.Get("kid").String(), tokenKeyID(authMiddleware))
+
+	// no kid for HMAC
+	authMiddleware, err = New(&GinJWTMiddleware{
+		Realm: "test zone",
+		Key:   []byte("secret key"),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, tokenKeyID(authMiddleware))
+}
+
+func TestRefreshTokenReuseDetection(t *testing.T) {
+	for _, detection := range []bool{true, false} {
+		authMiddleware, err := New(&GinJWTMiddleware{
+			Realm:      "test zone",
+			Key:        key,
+			Timeout:    time.Hour,
+			MaxRefresh: time.Hour * 24,
+			Authenticator: func(c *gin.Context) (any, error) {
+				return "admin", nil
+			},
+			RefreshTokenReuseDetection: detection,
+		})
+		assert.NoError(t, err)
+
+		handler := ginHandler(authMiddleware)
+		refresh := func(refreshToken string, code int) string {
+			var newRefreshToken string
+			gofight.New().POST("/auth/refresh_token").
+				SetJSON(gofight.D{"refresh_token": refreshToken}).
+				Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+					assert.Equal(t, code, r.Code)
+					newRefreshToken = gjson.Get(r.Body.String(), "refresh_token").String()
+				})
+			return newRefreshToken
+		}
+
+		stolen := getRefreshTokenFromLogin(handler)
+		other := getRefreshTokenFromLogin(handler)
+		current := refresh(stolen, http.StatusOK)
+		current = refresh(current, http.StatusOK)
+
+		// the attacker replays the rotated token
+		refresh(stolen, http.StatusUnauthorized)
+		if detection {
+			// the whole family is revoked, the legitimate client has to log in again
+			refresh(current, http.StatusUnauthorized)
+		} else {
+			refresh(current, http.StatusOK)
+		}
+		// the other login is not affected
+		refresh(other, http.StatusOK)
+
+		sessions, err := authMiddleware.ListSessions(context.Background())
+		assert.NoError(t, err)
+		for key := range sessions {
+			assert.False(t, strings.HasPrefix(key, "!"))
+		}
+	}
+}
+
+func TestFallbackIdentityKeys(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:                "test zone",
+		Key:                  key,
+		Timeout:              time.Hour,
+		Authenticator:        defaultAuthenticator,
+		FallbackIdentityKeys: []string{"sub"},
+		Authorizer: func(c *gin.Context, data any) bool {
+			return data == "admin"
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "admin",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	tokenString, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + tokenString,
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+
+	// identity key takes precedence over the fallback keys
+	token = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"identity": "test",
+		"sub":      "admin",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	})
+	tokenString, err = token.SignedString(key)
+	assert.NoError(t, err)
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + tokenString,
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusForbidden, r.Code)
+		})
+}
+
+func TestClaimsValidator(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           key,
+		Timeout:       time.Hour,
+		Authenticator: defaultAuthenticator,
+		ClaimsValidator: func(c *gin.Context, claims jwt.MapClaims) error {
+			if tenantID, _ := claims["tenant_id"].(string); tenantID == "" {
+				return errors.New("missing tenant_id claim")
+			}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + makeTokenString("HS256", "admin"),
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			message := gjson.Get(r.Body.String(), "message")
+			assert.Equal(t, "missing tenant_id claim", message.String())
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"identity":  "admin",
+		"tenant_id": "tenant1",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	})
+	tokenString, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + tokenString,
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+}
+
+func TestStatelessRefreshSigningKey(t *testing.T) {
+	refreshKey := []byte("refresh key")
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:   "test zone",
+		Key:     key,
+		Timeout: time.Hour,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		StatelessRefreshToken:   true,
+		RefreshSigningKey:       refreshKey,
+		RefreshSigningAlgorithm: "HS384",
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	refresh := func(refreshToken string, code int) {
+		gofight.New().POST("/auth/refresh_token").
+			SetJSON(gofight.D{"refresh_token": refreshToken}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code)
+			})
+	}
+
+	refreshToken := getRefreshTokenFromLogin(handler)
+	parsed, err := jwt.Parse(refreshToken, func(*jwt.Token) (any, error) { return refreshKey, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "HS384", parsed.Method.Alg())
+	assert.Equal(t, "refresh", parsed.Claims.(jwt.MapClaims)["typ"])
+	count, err := authMiddleware.RefreshTokenStore.Count(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+
+	refresh(refreshToken, http.StatusOK)
+
+	// the same claims signed with the access token key are rejected
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS384, parsed.Claims)
+	forgedString, err := forged.SignedString(key)
+	assert.NoError(t, err)
+	refresh(forgedString, http.StatusUnauthorized)
+
+	// the access token is not a refresh token
+	refresh(makeTokenString("HS256", "admin"), http.StatusUnauthorized)
+
+	_, err = New(&GinJWTMiddleware{
+		Realm:                 "test zone",
+		Key:                   key,
+		StatelessRefreshToken: true,
+	})
+	assert.ErrorIs(t, err, ErrMissingRefreshSigningKey)
+}
+
+// slowStore simulates a remote store which is slow when delay is set, the calls are canceled by the context
+type slowStore struct {
+	core.TokenStore
+	delay time.Duration
+}
+
+func (s *slowStore) wait(ctx context.Context) error {
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *slowStore) Set(ctx context.Context, token string, userData any, expiry time.Time) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	return s.TokenStore.Set(ctx, token, userData, expiry)
+}
+
+func (s *slowStore) Get(ctx context.Context, token string) (any, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.TokenStore.Get(ctx, token)
+}
+
+func (s *slowStore) Delete(ctx context.Context, token string) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	return s.TokenStore.Delete(ctx, token)
+}
+
+func TestStoreTimeout(t *testing.T) {
+	ctx := context.Background()
+	slow := &slowStore{TokenStore: store.NewInMemoryRefreshTokenStore()}
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:             "test zone",
+		Key:               key,
+		Timeout:           time.Hour,
+		StoreTimeout:      20 * time.Millisecond,
+		RefreshTokenStore: slow,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+	})
+	assert.NoError(t, err)
+
+	tokenPair, err := authMiddleware.TokenGenerator(ctx, "admin")
+	assert.NoError(t, err)
+
+	slow.delay = time.Second
+	start := time.Now()
+	_, err = authMiddleware.TokenGenerator(ctx, "admin")
+	assert.ErrorIs(t, err, ErrStoreTimeout)
+	_, err = authMiddleware.validateRefreshToken(ctx, tokenPair.RefreshToken)
+	assert.ErrorIs(t, err, ErrStoreTimeout)
+	err = authMiddleware.revokeRefreshToken(ctx, tokenPair.RefreshToken)
+	assert.ErrorIs(t, err, ErrStoreTimeout)
+	err = authMiddleware.RevokeAccessToken(ctx, "jti", time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrStoreTimeout)
+	_, err = authMiddleware.IsAccessTokenRevoked(ctx, "jti")
+	assert.ErrorIs(t, err, ErrStoreTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the login handler is not blocked by the store
+	gofight.New().POST("/login").
+		SetJSON(gofight.D{"username": "admin", "password": "admin"}).
+		Run(ginHandler(authMiddleware), func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusInternalServerError, r.Code)
+		})
+
+	slow.delay = 0
+	_, err = authMiddleware.validateRefreshToken(ctx, tokenPair.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestStatelessRefreshRevocation(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:   "test zone",
+		Key:     key,
+		Timeout: time.Hour,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		StatelessRefreshToken: true,
+		RefreshSigningKey:     []byte("refresh key"),
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	refresh := func(refreshToken string, code int) string {
+		var newToken string
+		gofight.New().POST("/auth/refresh_token").
+			SetJSON(gofight.D{"refresh_token": refreshToken}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code)
+				newToken = gjson.Get(r.Body.String(), "refresh_token").String()
+			})
+		return newToken
+	}
+
+	// refresh works without any store configured
+	refreshToken := getRefreshTokenFromLogin(handler)
+	newToken := refresh(refreshToken, http.StatusOK)
+	assert.NotEmpty(t, newToken)
+	assert.NotEqual(t, refreshToken, newToken)
+
+	// the rotated refresh token is revoked by jti
+	refresh(refreshToken, http.StatusUnauthorized)
+
+	// the refresh token is revoked on logout
+	gofight.New().POST("/logout").
+		SetJSON(gofight.D{"refresh_token": newToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+	refresh(newToken, http.StatusUnauthorized)
+
+	// only the revocations are stored, they are not counted as refresh tokens
+	count, err := authMiddleware.RefreshTokenStore.Count(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	revocations, err := authMiddleware.RefreshTokenStore.(core.SessionTracker).ListSessions(context.Background(), "!")
+	assert.NoError(t, err)
+	assert.Len(t, revocations, 2)
+}
+
+func TestTokenSource(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:         "test zone",
+		Key:           key,
+		Timeout:       time.Hour,
+		Authenticator: defaultAuthenticator,
+		TokenLookup:   "header:Authorization, cookie:jwt",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	handler := gin.New()
+	handler.GET("/source", authMiddleware.MiddlewareFunc(), func(c *gin.Context) {
+		c.String(http.StatusOK, GetTokenSource(c))
+	})
+
+	// header miss, cookie hit
+	gofight.New().GET("/source").
+		SetCookie(gofight.H{
+			"jwt": makeTokenString("HS256", "admin"),
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			assert.Equal(t, "cookie", r.Body.String())
+		})
+
+	// header takes precedence over cookie
+	gofight.New().GET("/source").
+		SetHeader(gofight.H{
+			"Authorization": "Bearer " + makeTokenString("HS256", "admin"),
+		}).
+		SetCookie(gofight.H{
+			"jwt": "invalid",
+		}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			assert.Equal(t, "header", r.Body.String())
+		})
+
+	// no source has the token
+	gofight.New().GET("/source").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+}
+
+func TestLogoutAllSessions(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:   "test zone",
+		Key:     key,
+		Timeout: time.Hour,
+		Authenticator: func(c *gin.Context) (any, error) {
+			var loginVals Login
+			if err := c.ShouldBind(&loginVals); err != nil {
+				return "", ErrMissingLoginValues
+			}
+			return loginVals.Username, nil
+		},
+		PayloadFunc: func(data any) jwt.MapClaims {
+			return jwt.MapClaims{"identity": data}
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	login := func(username string) (string, string) {
+		var accessToken, refreshToken string
+		gofight.New().POST("/login").
+			SetJSON(gofight.D{"username": username, "password": "password"}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusOK, r.Code)
+				accessToken = gjson.Get(r.Body.String(), "access_token").String()
+				refreshToken = gjson.Get(r.Body.String(), "refresh_token").String()
+			})
+		return accessToken, refreshToken
+	}
+	refresh := func(refreshToken string, code int) {
+		gofight.New().POST("/auth/refresh_token").
+			SetJSON(gofight.D{"refresh_token": refreshToken}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code)
+			})
+	}
+
+	var accessToken string
+	var sessions []string
+	for range 3 {
+		var refreshToken string
+		accessToken, refreshToken = login("admin")
+		sessions = append(sessions, refreshToken)
+	}
+	_, other := login("guest")
+
+	gofight.New().POST("/logout_all").
+		SetHeader(gofight.H{"Authorization": "Bearer " + accessToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+	for _, refreshToken := range sessions {
+		refresh(refreshToken, http.StatusUnauthorized)
+	}
+	// the sessions of other users are kept
+	refresh(other, http.StatusOK)
+
+	// revoke by the user data returned by Authenticator
+	_, refreshToken := login("admin")
+	assert.NoError(t, authMiddleware.RevokeAllForUser(context.Background(), "admin"))
+	refresh(refreshToken, http.StatusUnauthorized)
+
+	// the access token is required
+	gofight.New().POST("/logout_all").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+}
+
+func TestAuthEventHooks(t *testing.T) {
+	var events []string
+	var identities []any
+	var loginErr error
+	payloadCalls := 0
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:       "test zone",
+		Key:         key,
+		Timeout:     time.Hour,
+		IdentityKey: "identity",
+		Authenticator: func(c *gin.Context) (any, error) {
+			var loginVals Login
+			if err := c.ShouldBind(&loginVals); err != nil {
+				return "", ErrMissingLoginValues
+			}
+			if loginVals.Password != "admin" {
+				return nil, ErrFailedAuthentication
+			}
+			return loginVals.Username, nil
+		},
+		PayloadFunc: func(data any) jwt.MapClaims {
+			payloadCalls++
+			return jwt.MapClaims{"identity": data}
+		},
+		OnLogin: func(c *gin.Context, identity any) {
+			events = append(events, "login")
+			identities = append(identities, identity)
+			c.Status(http.StatusTeapot) // the response is already written
+		},
+		OnLoginFailed: func(c *gin.Context, err error) {
+			events = append(events, "login_failed")
+			loginErr = err
+		},
+		OnRefresh: func(c *gin.Context, identity any) {
+			events = append(events, "refresh")
+			identities = append(identities, identity)
+		},
+		OnLogout: func(c *gin.Context, identity any) {
+			events = append(events, "logout")
+			identities = append(identities, identity)
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+
+	gofight.New().POST("/login").
+		SetJSON(gofight.D{"username": "admin", "password": "wrong"}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+	assert.ErrorIs(t, loginErr, ErrFailedAuthentication)
+
+	var accessToken, refreshToken string
+	gofight.New().POST("/login").
+		SetJSON(gofight.D{"username": "admin", "password": "admin"}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			accessToken = gjson.Get(r.Body.String(), "access_token").String()
+			refreshToken = gjson.Get(r.Body.String(), "refresh_token").String()
+		})
+	// the claims of PayloadFunc are shared by the tokens, the user index and OnLogin
+	assert.Equal(t, 1, payloadCalls)
+
+	gofight.New().POST("/auth/refresh_token").
+		SetJSON(gofight.D{"refresh_token": refreshToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+
+	gofight.New().POST("/logout").
+		SetHeader(gofight.H{"Authorization": "Bearer " + accessToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+
+	// logout without access token
+	gofight.New().POST("/logout").
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+
+	assert.Equal(t, []string{"login_failed", "login", "refresh", "logout", "logout"}, events)
+	assert.Equal(t, []any{"admin", "admin", "admin", nil}, identities)
+}
+
+func TestMaxSessionsPerUser(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:              "test zone",
+		Key:                key,
+		Timeout:            time.Hour,
+		MaxSessionsPerUser: 3,
+		Authenticator: func(c *gin.Context) (any, error) {
+			var loginVals Login
+			if err := c.ShouldBind(&loginVals); err != nil {
+				return "", ErrMissingLoginValues
+			}
+			return loginVals.Username, nil
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := ginHandler(authMiddleware)
+	login := func(username string) string {
+		var refreshToken string
+		gofight.New().POST("/login").
+			SetJSON(gofight.D{"username": username, "password": "password"}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, http.StatusOK, r.Code)
+				refreshToken = gjson.Get(r.Body.String(), "refresh_token").String()
+			})
+		return refreshToken
+	}
+	refresh := func(refreshToken string, code int) {
+		gofight.New().POST("/auth/refresh_token").
+			SetJSON(gofight.D{"refresh_token": refreshToken}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code)
+			})
+	}
+
+	var sessions []string
+	for range 4 {
+		sessions = append(sessions, login("admin"))
+	}
+	other := login("guest")
+
+	// the oldest session is evicted
+	refresh(sessions[0], http.StatusUnauthorized)
+	for _, refreshToken := range sessions[1:] {
+		refresh(refreshToken, http.StatusOK)
+	}
+	// the sessions of other users are not counted
+	refresh(other, http.StatusOK)
+
+	count, err := authMiddleware.RefreshTokenStore.Count(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
+
+func TestContextKeys(t *testing.T) {
+	// the legacy string values are kept for compatibility
+	assert.Equal(t, "JWT_PAYLOAD", PayloadContextKey)
+	assert.Equal(t, "JWT_TOKEN", TokenContextKey)
+	assert.Equal(t, "JWT_TOKEN_SOURCE", TokenSourceContextKey)
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("JWT_PAYLOAD", jwt.MapClaims{"identity": "admin"})
+	c.Set("JWT_TOKEN", "token")
+	assert.Equal(t, "admin", ExtractClaims(c)["identity"])
+	assert.Equal(t, "token", GetToken(c))
+}
+
+func TestTokenHeadNameCaseInsensitive(t *testing.T) {
+	newHandler := func(caseInsensitive bool) *gin.Engine {
+		authMiddleware, _ := New(&GinJWTMiddleware{
+			Realm:                        "test zone",
+			Key:                          key,
+			Timeout:                      time.Hour,
+			MaxRefresh:                   time.Hour * 24,
+			Authenticator:                defaultAuthenticator,
+			TokenHeadNameCaseInsensitive: caseInsensitive,
+		})
+		return ginHandler(authMiddleware)
+	}
+	hello := func(handler *gin.Engine, authorization string, code int) {
+		gofight.New().GET("/auth/hello").
+			SetHeader(gofight.H{
+				"Authorization": authorization,
+			}).
+			Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code, authorization)
+			})
+	}
+	token := makeTokenString("HS256", "admin")
+
+	handler := newHandler(true)
+	hello(handler, "Bearer "+token, http.StatusOK)
+	hello(handler, "bearer "+token, http.StatusOK)
+	hello(handler, "BEARER "+token, http.StatusOK)
+	hello(handler, "Bearer  "+token, http.StatusOK)
+	hello(handler, "Bearer   ", http.StatusUnauthorized)
+	hello(handler, "Test "+token, http.StatusUnauthorized)
+
+	// the default is strict
+	handler = newHandler(false)
+	hello(handler, "Bearer "+token, http.StatusOK)
+	hello(handler, "bearer "+token, http.StatusUnauthorized)
+	hello(handler, "BEARER "+token, http.StatusUnauthorized)
+	hello(handler, "Bearer  "+token, http.StatusUnauthorized)
+}
+
+func TestAudience(t *testing.T) {
+	newMiddleware := func(audience []string, expectedAudience []string) *GinJWTMiddleware {
+		authMiddleware, err := New(&GinJWTMiddleware{
+			Realm:            "test zone",
+			Key:              key,
+			Timeout:          time.Hour,
+			Authenticator:    defaultAuthenticator,
+			Audience:         audience,
+			ExpectedAudience: expectedAudience,
+		})
+		assert.NoError(t, err)
+		return authMiddleware
+	}
+	hello := func(authMiddleware *GinJWTMiddleware, token string, code int) {
+		gofight.New().GET("/auth/hello").
+			SetHeader(gofight.H{
+				"Authorization": "Bearer " + token,
+			}).
+			Run(ginHandler(authMiddleware), func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				assert.Equal(t, code, r.Code)
+			})
+	}
+
+	issuer := newMiddleware([]string{"order", "payment"}, nil)
+	token, err := issuer.TokenGenerator(context.Background(), "admin")
+	assert.NoError(t, err)
+	parsed, err := issuer.ParseTokenString(token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"order", "payment"}, parsed.Claims.(jwt.MapClaims)["aud"])
+
+	// matching audience
+	hello(newMiddleware(nil, []string{"payment"}), token.AccessToken, http.StatusOK)
+	hello(newMiddleware(nil, []string{"user", "order"}), token.AccessToken, http.StatusOK)
+	// non-matching audience
+	hello(newMiddleware(nil, []string{"user"}), token.AccessToken, http.StatusUnauthorized)
+	// the aud claim is not validated by default
+	hello(newMiddleware(nil, nil), token.AccessToken, http.StatusOK)
+
+	// the token without aud claim is rejected when the audience is expected
+	token, err = newMiddleware(nil, nil).TokenGenerator(context.Background(), "admin")
+	assert.NoError(t, err)
+	hello(newMiddleware(nil, []string{"order"}), token.AccessToken, http.StatusUnauthorized)
+
+	// a single audience is a string
+	token, err = newMiddleware([]string{"order"}, []string{"order"}).TokenGenerator(context.Background(), "admin")
+	assert.NoError(t, err)
+	parsed, err = issuer.ParseTokenString(token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "order", parsed.Claims.(jwt.MapClaims)["aud"])
+	hello(newMiddleware(nil, []string{"order"}), token.AccessToken, http.StatusOK)
+
+	// the option of ExpectedAudience is not appended to ParseOptions on each init
+	authMiddleware := &GinJWTMiddleware{
+		Realm:            "test zone",
+		ExpectedAudience: []string{"order"},
+		KeyFunc: func(*jwt.Token) (any, error) {
+			return key, nil
+		},
+		ParseOptions: []jwt.ParserOption{jwt.WithLeeway(time.Second)},
+	}
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, authMiddleware.MiddlewareInit())
+		assert.Len(t, authMiddleware.ParseOptions, 1)
+	}
+	_, err = authMiddleware.ParseTokenString(token.AccessToken)
+	assert.NoError(t, err)
+	authMiddleware.ExpectedAudience = []string{"user"}
+	_, err = authMiddleware.ParseTokenString(token.AccessToken)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+}
+
+func TestCachedTokenStoreWithoutOptionalInterfaces(t *testing.T) {
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:      "test zone",
+		Key:        key,
+		Timeout:    time.Hour,
+		MaxRefresh: time.Hour * 24,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+		TrackLastUsed: true,
+	})
+	assert.NoError(t, err)
+	// the wrapped store implements none of the optional interfaces
+	authMiddleware.RefreshTokenStore = store.NewCachedTokenStore(struct{ core.TokenStore }{store.NewInMemoryRefreshTokenStore()}, 0, 0)
+
+	_, ok := storeAs[core.UserTokenIndexer](authMiddleware.RefreshTokenStore)
+	assert.False(t, ok)
+	_, ok = storeAs[core.UserTokenIndexer](store.NewCachedTokenStore(store.NewInMemoryRefreshTokenStore(), 0, 0))
+	assert.True(t, ok)
+
+	handler := ginHandler(authMiddleware)
+	refreshToken := getRefreshTokenFromLogin(handler)
+	assert.NotEmpty(t, refreshToken)
+
+	gofight.New().POST("/auth/refresh_token").
+		SetJSON(gofight.D{"refresh_token": refreshToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+}
@@ -0,0 +1,125 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// the reasons of rejected tokens passed to MetricsCollector.IncRejected
+const (
+	RejectReasonExpired    = "expired"
+	RejectReasonInvalidExp = "invalid_exp"
+	RejectReasonMissingExp = "missing_exp"
+	RejectReasonInvalid    = "invalid"
+)
+
+// MetricsCollector collects the metrics of token operations, the methods must be safe for concurrent use
+type MetricsCollector interface {
+	// IncIssued is called when a token pair is issued by TokenGenerator
+	IncIssued()
+	// IncRefreshed is called when the token is refreshed by RefreshHandler
+	IncRefreshed()
+	// IncRejected is called when the access token is rejected, reason is one of the RejectReason constants
+	IncRejected(reason string)
+	// ObserveStoreLatency is called with the duration of each refresh token store call
+	ObserveStoreLatency(d time.Duration)
+}
+
+// NoopMetricsCollector a MetricsCollector which does nothing, it is used when MetricsCollector is not set
+type NoopMetricsCollector struct{}
+
+// IncIssued does nothing
+func (NoopMetricsCollector) IncIssued() {}
+
+// IncRefreshed does nothing
+func (NoopMetricsCollector) IncRefreshed() {}
+
+// IncRejected does nothing
+func (NoopMetricsCollector) IncRejected(string) {}
+
+// ObserveStoreLatency does nothing
+func (NoopMetricsCollector) ObserveStoreLatency(time.Duration) {}
+
+// PrometheusMetricsCollector a MetricsCollector which records the metrics with prometheus
+type PrometheusMetricsCollector struct {
+	issued       prometheus.Counter
+	refreshed    prometheus.Counter
+	rejected     *prometheus.CounterVec
+	storeLatency prometheus.Histogram
+}
+
+// NewPrometheusMetricsCollector creates the prometheus collector and registers the metrics to registerer,
+// namespace defaults to "jwt" and registerer defaults to prometheus.DefaultRegisterer
+func NewPrometheusMetricsCollector(namespace string, registerer prometheus.Registerer) (*PrometheusMetricsCollector, error) {
+	if namespace == "" {
+		namespace = "jwt"
+	}
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	m := &PrometheusMetricsCollector{
+		issued: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_issued_total",
+			Help:      "Total number of issued token pairs.",
+		}),
+		refreshed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_refreshed_total",
+			Help:      "Total number of refreshed tokens.",
+		}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_rejected_total",
+			Help:      "Total number of rejected access tokens by reason.",
+		}, []string{"reason"}),
+		storeLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "refresh_store_duration_seconds",
+			Help:      "Latencies of the refresh token store calls in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{m.issued, m.refreshed, m.rejected, m.storeLatency} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// IncIssued increases the issued counter
+func (m *PrometheusMetricsCollector) IncIssued() {
+	m.issued.Inc()
+}
+
+// IncRefreshed increases the refreshed counter
+func (m *PrometheusMetricsCollector) IncRefreshed() {
+	m.refreshed.Inc()
+}
+
+// IncRejected increases the rejected counter of the reason
+func (m *PrometheusMetricsCollector) IncRejected(reason string) {
+	m.rejected.WithLabelValues(reason).Inc()
+}
+
+// ObserveStoreLatency records the duration of a store call
+func (m *PrometheusMetricsCollector) ObserveStoreLatency(d time.Duration) {
+	m.storeLatency.Observe(d.Seconds())
+}
+
+// metrics returns MetricsCollector, or the no-op collector if it is not set
+func (mw *GinJWTMiddleware) metrics() MetricsCollector {
+	if mw.MetricsCollector == nil {
+		return NoopMetricsCollector{}
+	}
+	return mw.MetricsCollector
+}
+
+// observeStore records the latency of the store call started at start
+func (mw *GinJWTMiddleware) observeStore(start time.Time) {
+	mw.metrics().ObserveStoreLatency(time.Since(start))
+}
@@ -0,0 +1,128 @@
+package jwt
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/appleboy/gofight/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+type fakeMetricsCollector struct {
+	mu           sync.Mutex
+	issued       int
+	refreshed    int
+	rejected     map[string]int
+	storeCalls   int
+	storeLatency time.Duration
+}
+
+func (f *fakeMetricsCollector) IncIssued() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.issued++
+}
+
+func (f *fakeMetricsCollector) IncRefreshed() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refreshed++
+}
+
+func (f *fakeMetricsCollector) IncRejected(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rejected[reason]++
+}
+
+func (f *fakeMetricsCollector) ObserveStoreLatency(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.storeCalls++
+	f.storeLatency += d
+}
+
+func TestMetricsCollector(t *testing.T) {
+	collector := &fakeMetricsCollector{rejected: make(map[string]int)}
+	authMiddleware, err := New(&GinJWTMiddleware{
+		Realm:            "test zone",
+		Key:              key,
+		Timeout:          time.Hour,
+		MetricsCollector: collector,
+		Authenticator: func(c *gin.Context) (any, error) {
+			return "admin", nil
+		},
+	})
+	require.NoError(t, err)
+	handler := ginHandler(authMiddleware)
+
+	var refreshToken string
+	gofight.New().POST("/login").
+		SetJSON(gofight.D{"username": "admin", "password": "admin"}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+			refreshToken = gjson.Get(r.Body.String(), "refresh_token").String()
+		})
+
+	gofight.New().POST("/auth/refresh_token").
+		SetJSON(gofight.D{"refresh_token": refreshToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusOK, r.Code)
+		})
+
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{"Authorization": "Bearer invalid"}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"identity": "admin",
+		"exp":      time.Now().Add(-time.Hour).Unix(),
+	})
+	expiredToken, err := expired.SignedString(key)
+	require.NoError(t, err)
+	gofight.New().GET("/auth/hello").
+		SetHeader(gofight.H{"Authorization": "Bearer " + expiredToken}).
+		Run(handler, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code)
+		})
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	// login and refresh issue a token pair each
+	assert.Equal(t, 2, collector.issued)
+	assert.Equal(t, 1, collector.refreshed)
+	assert.Equal(t, map[string]int{RejectReasonInvalid: 1, RejectReasonExpired: 1}, collector.rejected)
+	// set and index on login, get, set, index and delete on refresh
+	assert.Equal(t, 6, collector.storeCalls)
+}
+
+func TestPrometheusMetricsCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := NewPrometheusMetricsCollector("", registry)
+	require.NoError(t, err)
+
+	collector.IncIssued()
+	collector.IncIssued()
+	collector.IncRefreshed()
+	collector.IncRejected(RejectReasonExpired)
+	collector.ObserveStoreLatency(time.Millisecond)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(collector.issued))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.refreshed))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.rejected.WithLabelValues(RejectReasonExpired)))
+	assert.Equal(t, 4, testutil.CollectAndCount(registry))
+
+	// the metrics can not be registered twice
+	_, err = NewPrometheusMetricsCollector("", registry)
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package jwt
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// VerifyPassword reports whether plaintext matches hash, it is a helper for Authenticator implementations.
+// The supported hash formats are bcrypt ($2a$, $2b$, $2y$) and argon2id in the PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=3,p=4$<base64 salt>$<base64 key>.
+// It returns false if the hash format is not supported or malformed.
+func VerifyPassword(hash, plaintext string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plaintext)) == nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, plaintext)
+	}
+	return false
+}
+
+func verifyArgon2id(hash, plaintext string) bool {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=4", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil || threads == 0 {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.Strict().DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.Strict().DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	otherKey := argon2.IDKey([]byte(plaintext), salt, iterations, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
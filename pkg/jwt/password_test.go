@@ -0,0 +1,44 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func makeArgon2idHash(password string) string {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, 1, 8*1024, 2, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, 8*1024, 1, 2,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestVerifyPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	argon2Hash := makeArgon2idHash("123456")
+
+	tests := []struct {
+		name      string
+		hash      string
+		plaintext string
+		want      bool
+	}{
+		{"bcrypt match", string(bcryptHash), "123456", true},
+		{"bcrypt not match", string(bcryptHash), "654321", false},
+		{"argon2id match", argon2Hash, "123456", true},
+		{"argon2id not match", argon2Hash, "654321", false},
+		{"argon2id malformed", "$argon2id$v=19$m=8192,t=1$c2FsdA$a2V5", "123456", false},
+		{"argon2id wrong version", "$argon2id$v=16$m=8192,t=1,p=2$c2FsdA$a2V5", "123456", false},
+		{"unsupported", "123456", "123456", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, VerifyPassword(tt.hash, tt.plaintext))
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moweilong/milady/pkg/jwt/core"
+)
+
+var (
+	_ core.TokenStore       = &CachedTokenStore{}
+	_ core.PrefixDeleter    = &CachedTokenStore{}
+	_ core.ExpiryGetter     = &CachedTokenStore{}
+	_ core.SessionTracker   = &CachedTokenStore{}
+	_ core.UserTokenIndexer = &CachedTokenStore{}
+	_ core.Unwrapper        = &CachedTokenStore{}
+)
+
+const (
+	// DefaultCacheSize default max number of cached token lookups
+	DefaultCacheSize = 1024
+	// DefaultCacheTTL default time a cached token lookup is served without hitting the store
+	DefaultCacheTTL = 30 * time.Second
+)
+
+// ErrPrefixDeleteNotSupported indicates the underlying store does not implement core.PrefixDeleter
+var ErrPrefixDeleteNotSupported = errors.New("token store does not support deleting tokens by prefix")
+
+// ErrExpiryNotSupported indicates the underlying store does not implement core.ExpiryGetter
+var ErrExpiryNotSupported = errors.New("token store does not support getting the token expiry")
+
+// ErrSessionTrackingNotSupported indicates the underlying store does not implement core.SessionTracker
+var ErrSessionTrackingNotSupported = errors.New("token store does not support tracking sessions")
+
+// ErrUserIndexNotSupported indicates the underlying store does not implement core.UserTokenIndexer
+var ErrUserIndexNotSupported = errors.New("token store does not support indexing tokens by user")
+
+// CachedTokenStore is an in-process LRU cache in front of the lookups of an opaque token store,
+// the tokens revoked by this store are removed from the cache immediately, a cached lookup never outlives
+// the expiry of the token if the underlying store implements core.ExpiryGetter.
+// Tokens revoked by other instances are served from the cache until the TTL expires,
+// so keep the TTL short when the store is shared by multiple instances.
+type CachedTokenStore struct {
+	store core.TokenStore
+	size  int
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	ll    *list.List // front is the most recently used
+	items map[string]*list.Element
+	// generation is increased by each invalidation, a lookup started before an invalidation is not cached,
+	// otherwise the token revoked during the lookup would be served from the cache
+	generation uint64
+}
+
+type cacheEntry struct {
+	token    string
+	userData any
+	expireAt time.Time
+}
+
+// NewCachedTokenStore creates a cache in front of the store lookups,
+// size <= 0 uses DefaultCacheSize and ttl <= 0 uses DefaultCacheTTL
+func NewCachedTokenStore(store core.TokenStore, size int, ttl time.Duration) *CachedTokenStore {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &CachedTokenStore{
+		store: store,
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Set stores the token in the underlying store, the cached lookup of the token is dropped
+func (s *CachedTokenStore) Set(ctx context.Context, token string, userData any, expiry time.Time) error {
+	defer s.remove(token)
+	return s.store.Set(ctx, token, userData, expiry)
+}
+
+// Get returns the cached user data of the token, the underlying store is only queried on cache miss
+func (s *CachedTokenStore) Get(ctx context.Context, token string) (any, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	if elem, ok := s.items[token]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if now.Before(entry.expireAt) {
+			s.ll.MoveToFront(elem)
+			s.mu.Unlock()
+			return entry.userData, nil
+		}
+		s.removeElement(elem)
+	}
+	generation := s.generation
+	s.mu.Unlock()
+
+	userData, err := s.store.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	expireAt := now.Add(s.ttl)
+	if getter, ok := s.store.(core.ExpiryGetter); ok {
+		expiry, err := getter.GetExpiry(ctx, token)
+		if err == nil && !expiry.IsZero() && expiry.Before(expireAt) {
+			expireAt = expiry
+		}
+	}
+	if !now.Before(expireAt) {
+		return userData, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation != generation {
+		return userData, nil
+	}
+	if elem, ok := s.items[token]; ok {
+		s.removeElement(elem)
+	}
+	s.items[token] = s.ll.PushFront(&cacheEntry{token: token, userData: userData, expireAt: expireAt})
+	for s.ll.Len() > s.size {
+		s.removeElement(s.ll.Back())
+	}
+
+	return userData, nil
+}
+
+// Delete revokes the token, it is removed from both the cache and the underlying store
+func (s *CachedTokenStore) Delete(ctx context.Context, token string) error {
+	defer s.remove(token)
+	return s.store.Delete(ctx, token)
+}
+
+// DeleteByPrefix revokes the tokens whose key starts with prefix, the underlying store must implement core.PrefixDeleter
+func (s *CachedTokenStore) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	deleter, ok := s.store.(core.PrefixDeleter)
+	if !ok {
+		return 0, ErrPrefixDeleteNotSupported
+	}
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.generation++
+		for token, elem := range s.items {
+			if strings.HasPrefix(token, prefix) {
+				s.removeElement(elem)
+			}
+		}
+	}()
+
+	return deleter.DeleteByPrefix(ctx, prefix)
+}
+
+// GetExpiry returns the expiry of the token from the underlying store, it must implement core.ExpiryGetter
+func (s *CachedTokenStore) GetExpiry(ctx context.Context, token string) (time.Time, error) {
+	getter, ok := s.store.(core.ExpiryGetter)
+	if !ok {
+		return time.Time{}, ErrExpiryNotSupported
+	}
+	return getter.GetExpiry(ctx, token)
+}
+
+// Touch records the last used time of the token in the underlying store, it must implement core.SessionTracker
+func (s *CachedTokenStore) Touch(ctx context.Context, token string, lastUsed time.Time) error {
+	tracker, ok := s.store.(core.SessionTracker)
+	if !ok {
+		return ErrSessionTrackingNotSupported
+	}
+	return tracker.Touch(ctx, token, lastUsed)
+}
+
+// ListSessions returns the sessions of the underlying store, it must implement core.SessionTracker
+func (s *CachedTokenStore) ListSessions(ctx context.Context, prefix string) (map[string]*core.RefreshTokenData, error) {
+	tracker, ok := s.store.(core.SessionTracker)
+	if !ok {
+		return nil, ErrSessionTrackingNotSupported
+	}
+	return tracker.ListSessions(ctx, prefix)
+}
+
+// AddUserToken adds the token to the user index of the underlying store, it must implement core.UserTokenIndexer
+func (s *CachedTokenStore) AddUserToken(ctx context.Context, user string, token string, expiry time.Time) error {
+	indexer, ok := s.store.(core.UserTokenIndexer)
+	if !ok {
+		return ErrUserIndexNotSupported
+	}
+	return indexer.AddUserToken(ctx, user, token, expiry)
+}
+
+// ListUserTokens returns the tokens of the user from the underlying store, it must implement core.UserTokenIndexer
+func (s *CachedTokenStore) ListUserTokens(ctx context.Context, user string) (map[string]*core.RefreshTokenData, error) {
+	indexer, ok := s.store.(core.UserTokenIndexer)
+	if !ok {
+		return nil, ErrUserIndexNotSupported
+	}
+	return indexer.ListUserTokens(ctx, user)
+}
+
+// DeleteByUser revokes the tokens of the user, the cache does not know the tokens of the user,
+// so all cached lookups are dropped
+func (s *CachedTokenStore) DeleteByUser(ctx context.Context, user string) (int, error) {
+	indexer, ok := s.store.(core.UserTokenIndexer)
+	if !ok {
+		return 0, ErrUserIndexNotSupported
+	}
+	defer s.Purge()
+	return indexer.DeleteByUser(ctx, user)
+}
+
+// Cleanup removes expired tokens from the underlying store
+func (s *CachedTokenStore) Cleanup(ctx context.Context) (int, error) {
+	return s.store.Cleanup(ctx)
+}
+
+// Count returns the number of tokens in the underlying store
+func (s *CachedTokenStore) Count(ctx context.Context) (int, error) {
+	return s.store.Count(ctx)
+}
+
+// Ping checks the underlying store if it supports ping
+func (s *CachedTokenStore) Ping() error {
+	if p, ok := s.store.(interface{ Ping() error }); ok {
+		return p.Ping()
+	}
+	return nil
+}
+
+// Unwrap returns the underlying store, the optional interfaces of the cache are only usable
+// if the underlying store implements them
+func (s *CachedTokenStore) Unwrap() core.TokenStore {
+	return s.store
+}
+
+// Purge removes all cached lookups, the underlying store is not changed
+func (s *CachedTokenStore) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	s.ll.Init()
+	s.items = make(map[string]*list.Element)
+}
+
+// remove drops the cached lookup of the token, it is called after the token is changed in the underlying store,
+// so the lookups started before the change are not cached
+func (s *CachedTokenStore) remove(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	if elem, ok := s.items[token]; ok {
+		s.removeElement(elem)
+	}
+}
+
+func (s *CachedTokenStore) removeElement(elem *list.Element) {
+	s.ll.Remove(elem)
+	delete(s.items, elem.Value.(*cacheEntry).token)
+}
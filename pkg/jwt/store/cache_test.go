@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moweilong/milady/pkg/jwt/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStore counts the lookups of the underlying store, afterGet is called after each lookup if set
+type countingStore struct {
+	*InMemoryRefreshTokenStore
+	gets     int
+	afterGet func()
+}
+
+func (s *countingStore) Get(ctx context.Context, token string) (any, error) {
+	s.gets++
+	userData, err := s.InMemoryRefreshTokenStore.Get(ctx, token)
+	if s.afterGet != nil {
+		s.afterGet()
+	}
+	return userData, err
+}
+
+func TestCachedTokenStore(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStore{InMemoryRefreshTokenStore: NewInMemoryRefreshTokenStore()}
+	cache := NewCachedTokenStore(inner, 2, time.Minute)
+	expiry := time.Now().Add(time.Hour)
+
+	assert.NoError(t, cache.Set(ctx, "token1", "user1", expiry))
+
+	// second lookup within TTL hits the cache
+	data, err := cache.Get(ctx, "token1")
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", data)
+	data, err = cache.Get(ctx, "token1")
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", data)
+	assert.Equal(t, 1, inner.gets)
+
+	// revoke clears the cached lookup
+	assert.NoError(t, cache.Delete(ctx, "token1"))
+	_, err = cache.Get(ctx, "token1")
+	assert.ErrorIs(t, err, core.ErrRefreshTokenNotFound)
+	assert.Equal(t, 2, inner.gets)
+
+	// revoke by prefix clears the cached lookups of the prefix
+	assert.NoError(t, cache.Set(ctx, "a:token", "userA", expiry))
+	assert.NoError(t, cache.Set(ctx, "b:token", "userB", expiry))
+	_, _ = cache.Get(ctx, "a:token")
+	_, _ = cache.Get(ctx, "b:token")
+	n, err := cache.DeleteByPrefix(ctx, "b:")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = cache.Get(ctx, "b:token")
+	assert.ErrorIs(t, err, core.ErrRefreshTokenNotFound)
+	data, err = cache.Get(ctx, "a:token")
+	assert.NoError(t, err)
+	assert.Equal(t, "userA", data)
+}
+
+func TestCachedTokenStore_TTLAndEviction(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStore{InMemoryRefreshTokenStore: NewInMemoryRefreshTokenStore()}
+	cache := NewCachedTokenStore(inner, 2, 20*time.Millisecond)
+	expiry := time.Now().Add(time.Hour)
+
+	for _, token := range []string{"token1", "token2", "token3"} {
+		assert.NoError(t, cache.Set(ctx, token, token, expiry))
+		_, err := cache.Get(ctx, token)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, inner.gets)
+
+	// token1 is evicted by the size limit
+	_, _ = cache.Get(ctx, "token3")
+	_, _ = cache.Get(ctx, "token1")
+	assert.Equal(t, 4, inner.gets)
+
+	// lookup after TTL hits the store
+	time.Sleep(30 * time.Millisecond)
+	_, _ = cache.Get(ctx, "token1")
+	assert.Equal(t, 5, inner.gets)
+
+	// underlying store without prefix delete
+	_, err := NewCachedTokenStore(struct{ core.TokenStore }{inner}, 0, 0).DeleteByPrefix(ctx, "a:")
+	assert.ErrorIs(t, err, ErrPrefixDeleteNotSupported)
+}
+
+func TestCachedTokenStore_RevokeDuringLookup(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStore{InMemoryRefreshTokenStore: NewInMemoryRefreshTokenStore()}
+	cache := NewCachedTokenStore(inner, 0, time.Minute)
+	expiry := time.Now().Add(time.Hour)
+
+	revokes := []func(){
+		func() { assert.NoError(t, cache.Delete(ctx, "token1")) },
+		func() {
+			_, err := cache.DeleteByPrefix(ctx, "token")
+			assert.NoError(t, err)
+		},
+		func() { cache.Purge() },
+	}
+	for _, revoke := range revokes {
+		assert.NoError(t, cache.Set(ctx, "token1", "user1", expiry))
+		inner.afterGet = func() {
+			inner.afterGet = nil
+			revoke()
+		}
+
+		// the lookup read the token before it was revoked, the result is not cached
+		data, err := cache.Get(ctx, "token1")
+		assert.NoError(t, err)
+		assert.Equal(t, "user1", data)
+		assert.NoError(t, inner.InMemoryRefreshTokenStore.Delete(ctx, "token1"))
+		_, err = cache.Get(ctx, "token1")
+		assert.ErrorIs(t, err, core.ErrRefreshTokenNotFound)
+	}
+}
+
+func TestCachedTokenStore_ExpiryCapsTTL(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStore{InMemoryRefreshTokenStore: NewInMemoryRefreshTokenStore()}
+	cache := NewCachedTokenStore(inner, 0, time.Minute)
+
+	assert.NoError(t, cache.Set(ctx, "token1", "user1", time.Now().Add(20*time.Millisecond)))
+	data, err := cache.Get(ctx, "token1")
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", data)
+
+	// the cached lookup expires with the token instead of the TTL
+	time.Sleep(30 * time.Millisecond)
+	_, err = cache.Get(ctx, "token1")
+	assert.ErrorIs(t, err, core.ErrRefreshTokenExpired)
+	assert.Equal(t, 2, inner.gets)
+}
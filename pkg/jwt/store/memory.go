@@ -3,13 +3,20 @@ package store
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/moweilong/milady/pkg/jwt/core"
 )
 
-var _ core.TokenStore = &InMemoryRefreshTokenStore{}
+var (
+	_ core.TokenStore       = &InMemoryRefreshTokenStore{}
+	_ core.PrefixDeleter    = &InMemoryRefreshTokenStore{}
+	_ core.ExpiryGetter     = &InMemoryRefreshTokenStore{}
+	_ core.SessionTracker   = &InMemoryRefreshTokenStore{}
+	_ core.UserTokenIndexer = &InMemoryRefreshTokenStore{}
+)
 
 // InMemoryRefreshTokenStore provides a simple in-memory refresh token store
 // This implementation is thread-safe and suitable for single-instance applications
@@ -17,12 +24,17 @@ var _ core.TokenStore = &InMemoryRefreshTokenStore{}
 type InMemoryRefreshTokenStore struct {
 	tokens map[string]*core.RefreshTokenData
 	mu     sync.RWMutex
+
+	userTokens map[string]map[string]struct{} // user -> tokens of the user
+	tokenUsers map[string]string              // token -> user of the token
 }
 
 // NewInMemoryRefreshTokenStore creates a new in-memory refresh token store
 func NewInMemoryRefreshTokenStore() *InMemoryRefreshTokenStore {
 	return &InMemoryRefreshTokenStore{
-		tokens: make(map[string]*core.RefreshTokenData),
+		tokens:     make(map[string]*core.RefreshTokenData),
+		userTokens: make(map[string]map[string]struct{}),
+		tokenUsers: make(map[string]string),
 	}
 }
 
@@ -51,6 +63,23 @@ func (s *InMemoryRefreshTokenStore) Set(
 
 // Get retrieves refresh token associated with a refresh token
 func (s *InMemoryRefreshTokenStore) Get(ctx context.Context, token string) (any, error) {
+	data, err := s.getData(token)
+	if err != nil {
+		return nil, err
+	}
+	return data.UserData, nil
+}
+
+// GetExpiry retrieves the expiration time of a refresh token
+func (s *InMemoryRefreshTokenStore) GetExpiry(ctx context.Context, token string) (time.Time, error) {
+	data, err := s.getData(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return data.Expiry, nil
+}
+
+func (s *InMemoryRefreshTokenStore) getData(token string) (*core.RefreshTokenData, error) {
 	if token == "" {
 		return nil, ErrRefreshTokenNotFound
 	}
@@ -66,12 +95,12 @@ func (s *InMemoryRefreshTokenStore) Get(ctx context.Context, token string) (any,
 	if data.IsExpired() {
 		// Clean up expired token
 		s.mu.Lock()
-		delete(s.tokens, token)
+		s.deleteToken(token)
 		s.mu.Unlock()
 		return nil, core.ErrRefreshTokenExpired
 	}
 
-	return data.UserData, nil
+	return data, nil
 }
 
 // Delete removes a refresh token from storage
@@ -83,10 +112,22 @@ func (s *InMemoryRefreshTokenStore) Delete(ctx context.Context, token string) er
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.tokens, token)
+	s.deleteToken(token)
 	return nil
 }
 
+// deleteToken removes the token and its user index entry, the caller must hold the lock
+func (s *InMemoryRefreshTokenStore) deleteToken(token string) {
+	delete(s.tokens, token)
+	if user, ok := s.tokenUsers[token]; ok {
+		delete(s.tokenUsers, token)
+		delete(s.userTokens[user], token)
+		if len(s.userTokens[user]) == 0 {
+			delete(s.userTokens, user)
+		}
+	}
+}
+
 // Cleanup removes expired tokens and returns the number of tokens cleaned up
 func (s *InMemoryRefreshTokenStore) Cleanup(ctx context.Context) (int, error) {
 	s.mu.Lock()
@@ -97,7 +138,7 @@ func (s *InMemoryRefreshTokenStore) Cleanup(ctx context.Context) (int, error) {
 
 	for token, data := range s.tokens {
 		if now.After(data.Expiry) {
-			delete(s.tokens, token)
+			s.deleteToken(token)
 			cleaned++
 		}
 	}
@@ -105,12 +146,111 @@ func (s *InMemoryRefreshTokenStore) Cleanup(ctx context.Context) (int, error) {
 	return cleaned, nil
 }
 
+// DeleteByPrefix removes the tokens whose key starts with prefix
+func (s *InMemoryRefreshTokenStore) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int
+	for token := range s.tokens {
+		if strings.HasPrefix(token, prefix) {
+			s.deleteToken(token)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// AddUserToken adds the token to the index of the user
+func (s *InMemoryRefreshTokenStore) AddUserToken(ctx context.Context, user string, token string, expiry time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tokens[token]; !exists {
+		return core.ErrRefreshTokenNotFound
+	}
+	if s.userTokens[user] == nil {
+		s.userTokens[user] = make(map[string]struct{})
+	}
+	s.userTokens[user][token] = struct{}{}
+	s.tokenUsers[token] = user
+	return nil
+}
+
+// ListUserTokens returns the data of the active tokens of the user
+func (s *InMemoryRefreshTokenStore) ListUserTokens(ctx context.Context, user string) (map[string]*core.RefreshTokenData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]*core.RefreshTokenData)
+	for token := range s.userTokens[user] {
+		if data, exists := s.tokens[token]; exists && !data.IsExpired() {
+			copied := *data
+			result[token] = &copied
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteByUser removes all tokens of the user
+func (s *InMemoryRefreshTokenStore) DeleteByUser(ctx context.Context, user string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int
+	for token := range s.userTokens[user] {
+		if _, exists := s.tokens[token]; exists {
+			deleted++
+		}
+		s.deleteToken(token)
+	}
+
+	return deleted, nil
+}
+
+// Touch records the time when the refresh token was last used
+func (s *InMemoryRefreshTokenStore) Touch(ctx context.Context, token string, lastUsed time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, exists := s.tokens[token]
+	if !exists || data.IsExpired() {
+		return core.ErrRefreshTokenNotFound
+	}
+	data.LastUsed = lastUsed
+	return nil
+}
+
+// ListSessions returns the data of the active tokens whose key starts with prefix
+func (s *InMemoryRefreshTokenStore) ListSessions(ctx context.Context, prefix string) (map[string]*core.RefreshTokenData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]*core.RefreshTokenData)
+	for token, data := range s.tokens {
+		if strings.HasPrefix(token, prefix) && !data.IsExpired() {
+			copied := *data
+			result[token] = &copied
+		}
+	}
+
+	return result, nil
+}
+
 // Count returns the total number of active refresh tokens
 func (s *InMemoryRefreshTokenStore) Count(ctx context.Context) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return len(s.tokens), nil
+	var count int
+	for token := range s.tokens {
+		if !isInternalKey(token) {
+			count++
+		}
+	}
+	return count, nil
 }
 
 // GetAll returns all active refresh tokens (for debugging/monitoring purposes)
@@ -123,11 +263,12 @@ func (s *InMemoryRefreshTokenStore) GetAll() map[string]*core.RefreshTokenData {
 	// Create a copy to prevent external modification
 	result := make(map[string]*core.RefreshTokenData)
 	for token, data := range s.tokens {
-		if !data.IsExpired() {
+		if !data.IsExpired() && !isInternalKey(token) {
 			result[token] = &core.RefreshTokenData{
 				UserData: data.UserData,
 				Expiry:   data.Expiry,
 				Created:  data.Created,
+				LastUsed: data.LastUsed,
 			}
 		}
 	}
@@ -142,4 +283,6 @@ func (s *InMemoryRefreshTokenStore) Clear() {
 	defer s.mu.Unlock()
 
 	s.tokens = make(map[string]*core.RefreshTokenData)
+	s.userTokens = make(map[string]map[string]struct{})
+	s.tokenUsers = make(map[string]string)
 }
@@ -276,6 +276,19 @@ func TestInMemoryRefreshTokenStore_Count(t *testing.T) {
 	if count != 5 {
 		t.Fatalf("Expected count to be 5, got %d", count)
 	}
+
+	// The revoked access tokens and refresh token families are not refresh tokens
+	_ = store.Set(context.Background(), "!jti:abc", true, expiry)
+	_ = store.Set(context.Background(), "!family:abc", "token0", expiry)
+	_ = store.Set(context.Background(), "!family_of:token0", "abc", expiry)
+
+	count, err = store.Count(context.Background())
+	if err != nil {
+		t.Fatalf("Count() returned error: %v", err)
+	}
+	if count != 5 {
+		t.Fatalf("Expected count to exclude the internal keys and be 5, got %d", count)
+	}
 }
 
 func TestInMemoryRefreshTokenStore_GetAll(t *testing.T) {
@@ -288,6 +301,7 @@ func TestInMemoryRefreshTokenStore_GetAll(t *testing.T) {
 	_ = store.Set(context.Background(), "valid1", &User{ID: "1"}, validExpiry)
 	_ = store.Set(context.Background(), "valid2", &User{ID: "2"}, validExpiry)
 	_ = store.Set(context.Background(), "expired1", &User{ID: "3"}, expiredExpiry)
+	_ = store.Set(context.Background(), "!jti:abc", true, validExpiry)
 
 	all := store.GetAll()
 
@@ -307,6 +321,10 @@ func TestInMemoryRefreshTokenStore_GetAll(t *testing.T) {
 	if _, exists := all["expired1"]; exists {
 		t.Fatal("expired1 should not be in GetAll() result")
 	}
+
+	if _, exists := all["!jti:abc"]; exists {
+		t.Fatal("!jti:abc should not be in GetAll() result")
+	}
 }
 
 func TestInMemoryRefreshTokenStore_Clear(t *testing.T) {
@@ -455,3 +473,92 @@ func BenchmarkInMemoryRefreshTokenStore_Delete(b *testing.B) {
 		_ = store.Delete(context.Background(), token)
 	}
 }
+
+func TestInMemoryRefreshTokenStore_TouchAndListSessions(t *testing.T) {
+	s := NewInMemoryRefreshTokenStore()
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+
+	assert.NoError(t, s.Set(ctx, "a:token1", "user1", expiry))
+	assert.NoError(t, s.Set(ctx, "b:token2", "user2", expiry))
+	assert.NoError(t, s.Set(ctx, "a:expired", "user3", time.Now().Add(-time.Second)))
+
+	lastUsed := time.Now()
+	assert.NoError(t, s.Touch(ctx, "a:token1", lastUsed))
+	assert.ErrorIs(t, s.Touch(ctx, "a:expired", lastUsed), ErrRefreshTokenNotFound)
+	assert.ErrorIs(t, s.Touch(ctx, "missing", lastUsed), ErrRefreshTokenNotFound)
+
+	sessions, err := s.ListSessions(ctx, "a:")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "user1", sessions["a:token1"].UserData)
+	assert.True(t, lastUsed.Equal(sessions["a:token1"].LastUsed))
+
+	sessions, err = s.ListSessions(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.True(t, sessions["b:token2"].LastUsed.IsZero())
+}
+
+func TestInMemoryRefreshTokenStore_DeleteByUser(t *testing.T) {
+	s := NewInMemoryRefreshTokenStore()
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+
+	for _, token := range []string{"token1", "token2", "token3"} {
+		assert.NoError(t, s.Set(ctx, token, "user1", expiry))
+		assert.NoError(t, s.AddUserToken(ctx, "user1", token, expiry))
+	}
+	assert.NoError(t, s.Set(ctx, "token4", "user2", expiry))
+	assert.NoError(t, s.AddUserToken(ctx, "user2", "token4", expiry))
+	assert.ErrorIs(t, s.AddUserToken(ctx, "user1", "missing", expiry), ErrRefreshTokenNotFound)
+
+	// the deleted token is removed from the index
+	assert.NoError(t, s.Delete(ctx, "token3"))
+
+	n, err := s.DeleteByUser(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	for _, token := range []string{"token1", "token2"} {
+		_, err = s.Get(ctx, token)
+		assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+	}
+
+	// the tokens of other users are kept
+	data, err := s.Get(ctx, "token4")
+	assert.NoError(t, err)
+	assert.Equal(t, "user2", data)
+
+	n, err = s.DeleteByUser(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestInMemoryRefreshTokenStore_ListUserTokens(t *testing.T) {
+	s := NewInMemoryRefreshTokenStore()
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+
+	for _, token := range []string{"token1", "token2"} {
+		assert.NoError(t, s.Set(ctx, token, "user1", expiry))
+		assert.NoError(t, s.AddUserToken(ctx, "user1", token, expiry))
+	}
+	assert.NoError(t, s.Set(ctx, "token3", "user2", expiry))
+	assert.NoError(t, s.AddUserToken(ctx, "user2", "token3", expiry))
+
+	tokens, err := s.ListUserTokens(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, "user1", tokens["token1"].UserData)
+	assert.False(t, tokens["token1"].Created.IsZero())
+
+	assert.NoError(t, s.Delete(ctx, "token1"))
+	tokens, err = s.ListUserTokens(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.Contains(t, tokens, "token2")
+
+	tokens, err = s.ListUserTokens(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, tokens)
+}
@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moweilong/milady/pkg/jwt/core"
+)
+
+var (
+	_ core.TokenStore    = (*PostgresRefreshTokenStore)(nil)
+	_ core.PrefixDeleter = (*PostgresRefreshTokenStore)(nil)
+	_ core.ExpiryGetter  = (*PostgresRefreshTokenStore)(nil)
+)
+
+const (
+	// DefaultPostgresTableName default table of the refresh tokens
+	DefaultPostgresTableName = "refresh_tokens"
+	// DefaultPostgresCleanupInterval default interval of removing the expired rows in background
+	DefaultPostgresCleanupInterval = 10 * time.Minute
+)
+
+var tableNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PostgresConfig holds the configuration for Postgres store
+type PostgresConfig struct {
+	TableName       string        // Table of the refresh tokens, it may be qualified by schema (default: "refresh_tokens")
+	CreateTable     bool          // Create the table if it does not exist (default: true)
+	CleanupInterval time.Duration // Interval of removing the expired rows, <= 0 disables the background cleanup (default: 10 minutes)
+}
+
+// PostgresOption defines a function type for configuring Postgres store
+type PostgresOption func(*PostgresConfig)
+
+// WithPostgresTableName sets the table of the refresh tokens
+func WithPostgresTableName(name string) PostgresOption {
+	return func(config *PostgresConfig) {
+		config.TableName = name
+	}
+}
+
+// WithPostgresCreateTable sets whether to create the table if it does not exist
+func WithPostgresCreateTable(create bool) PostgresOption {
+	return func(config *PostgresConfig) {
+		config.CreateTable = create
+	}
+}
+
+// WithPostgresCleanupInterval sets the interval of removing the expired rows, <= 0 disables the background cleanup
+func WithPostgresCleanupInterval(interval time.Duration) PostgresOption {
+	return func(config *PostgresConfig) {
+		config.CleanupInterval = interval
+	}
+}
+
+// DefaultPostgresConfig returns a default Postgres configuration
+func DefaultPostgresConfig() *PostgresConfig {
+	return &PostgresConfig{
+		TableName:       DefaultPostgresTableName,
+		CreateTable:     true,
+		CleanupInterval: DefaultPostgresCleanupInterval,
+	}
+}
+
+// PostgresRefreshTokenStore stores the refresh tokens in a Postgres table,
+// the user data is saved as JSON, so it is decoded to generic JSON values on Get
+type PostgresRefreshTokenStore struct {
+	db    *sql.DB
+	table string
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewPostgresRefreshTokenStore creates a new Postgres-based refresh token store,
+// the table is created if it does not exist and the expired rows are removed in background
+func NewPostgresRefreshTokenStore(db *sql.DB, opts ...PostgresOption) (*PostgresRefreshTokenStore, error) {
+	if db == nil {
+		return nil, errors.New("postgres db cannot be nil")
+	}
+
+	config := DefaultPostgresConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+	if !tableNameRegexp.MatchString(config.TableName) {
+		return nil, fmt.Errorf("invalid postgres table name: %q", config.TableName)
+	}
+
+	s := &PostgresRefreshTokenStore{
+		db:    db,
+		table: config.TableName,
+		stop:  make(chan struct{}),
+	}
+
+	if config.CreateTable {
+		if err := s.createTable(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.CleanupInterval > 0 {
+		s.wg.Add(1)
+		go s.cleanupLoop(config.CleanupInterval)
+	}
+
+	return s, nil
+}
+
+func (s *PostgresRefreshTokenStore) createTable(ctx context.Context) error {
+	query := `CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+	token TEXT PRIMARY KEY,
+	user_data JSONB NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+)`
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", s.table, err)
+	}
+	return nil
+}
+
+func (s *PostgresRefreshTokenStore) cleanupLoop(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_, _ = s.Cleanup(context.Background())
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close stops the background cleanup, the db is not closed because it is owned by the caller
+func (s *PostgresRefreshTokenStore) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+	return nil
+}
+
+// Set stores a refresh token with associated user data and expiration, an existing token is replaced
+func (s *PostgresRefreshTokenStore) Set(ctx context.Context, token string, userData any, expiry time.Time) error {
+	if token == "" {
+		return errors.New("token cannot be empty")
+	}
+	if !expiry.After(time.Now()) {
+		return errors.New("token expiry time must be in the future")
+	}
+
+	data, err := json.Marshal(userData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user data: %w", err)
+	}
+
+	query := `INSERT INTO ` + s.table + ` (token, user_data, expires_at, created_at) VALUES ($1, $2, $3, $4)
+ON CONFLICT (token) DO UPDATE SET user_data = EXCLUDED.user_data, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at`
+	if _, err := s.db.ExecContext(ctx, query, token, string(data), expiry, time.Now()); err != nil {
+		return fmt.Errorf("failed to store token in Postgres: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves user data associated with a refresh token
+func (s *PostgresRefreshTokenStore) Get(ctx context.Context, token string) (any, error) {
+	tokenData, err := s.getData(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return tokenData.UserData, nil
+}
+
+// GetExpiry retrieves the expiration time of a refresh token
+func (s *PostgresRefreshTokenStore) GetExpiry(ctx context.Context, token string) (time.Time, error) {
+	tokenData, err := s.getData(ctx, token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return tokenData.Expiry, nil
+}
+
+func (s *PostgresRefreshTokenStore) getData(ctx context.Context, token string) (*core.RefreshTokenData, error) {
+	if token == "" {
+		return nil, core.ErrRefreshTokenNotFound
+	}
+
+	var data []byte
+	tokenData := &core.RefreshTokenData{}
+	query := `SELECT user_data, expires_at, created_at FROM ` + s.table + ` WHERE token = $1`
+	err := s.db.QueryRowContext(ctx, query, token).Scan(&data, &tokenData.Expiry, &tokenData.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, core.ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get token from Postgres: %w", err)
+	}
+
+	// expired rows are removed by Cleanup
+	if tokenData.IsExpired() {
+		return nil, core.ErrRefreshTokenExpired
+	}
+
+	if err := json.Unmarshal(data, &tokenData.UserData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user data: %w", err)
+	}
+
+	return tokenData, nil
+}
+
+// Delete removes a refresh token from storage
+func (s *PostgresRefreshTokenStore) Delete(ctx context.Context, token string) error {
+	if token == "" {
+		return nil // No error for empty token deletion
+	}
+
+	query := `DELETE FROM ` + s.table + ` WHERE token = $1`
+	if _, err := s.db.ExecContext(ctx, query, token); err != nil {
+		return fmt.Errorf("failed to delete token from Postgres: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByPrefix removes the tokens whose key starts with prefix
+func (s *PostgresRefreshTokenStore) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	query := `DELETE FROM ` + s.table + ` WHERE token LIKE $1 ESCAPE '\'`
+	result, err := s.db.ExecContext(ctx, query, escapeLike(prefix)+"%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete tokens from Postgres: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted rows: %w", err)
+	}
+	return int(n), nil
+}
+
+// escapeLike escapes the pattern characters of LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Cleanup removes expired tokens and returns the number of tokens cleaned up
+func (s *PostgresRefreshTokenStore) Cleanup(ctx context.Context) (int, error) {
+	query := `DELETE FROM ` + s.table + ` WHERE expires_at <= $1`
+	result, err := s.db.ExecContext(ctx, query, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up tokens in Postgres: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted rows: %w", err)
+	}
+	return int(n), nil
+}
+
+// Count returns the total number of active refresh tokens
+func (s *PostgresRefreshTokenStore) Count(ctx context.Context) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM ` + s.table + ` WHERE expires_at > $1 AND token NOT LIKE '` + internalKeyPrefix + `%'`
+	if err := s.db.QueryRowContext(ctx, query, time.Now()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tokens in Postgres: %w", err)
+	}
+	return count, nil
+}
+
+// Ping tests the Postgres connection
+func (s *PostgresRefreshTokenStore) Ping() error {
+	return s.db.Ping()
+}
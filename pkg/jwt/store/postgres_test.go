@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/moweilong/milady/pkg/jwt/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresRefreshTokenStore(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS refresh_tokens")).WillReturnResult(sqlmock.NewResult(0, 0))
+	s, err := NewPostgresRefreshTokenStore(db, WithPostgresCleanupInterval(0))
+	require.NoError(t, err)
+	defer s.Close()
+
+	expiry := time.Now().Add(time.Hour)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO refresh_tokens (token, user_data, expires_at, created_at)")).
+		WithArgs("token1", `{"id":"user1"}`, expiry, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, s.Set(ctx, "token1", map[string]string{"id": "user1"}, expiry))
+
+	getQuery := regexp.QuoteMeta("SELECT user_data, expires_at, created_at FROM refresh_tokens WHERE token = $1")
+	mock.ExpectQuery(getQuery).WithArgs("token1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_data", "expires_at", "created_at"}).AddRow(`{"id":"user1"}`, expiry, time.Now()))
+	data, err := s.Get(ctx, "token1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": "user1"}, data)
+
+	mock.ExpectQuery(getQuery).WithArgs("token2").
+		WillReturnRows(sqlmock.NewRows([]string{"user_data", "expires_at", "created_at"}).AddRow(`"user2"`, time.Now().Add(-time.Minute), time.Now()))
+	_, err = s.Get(ctx, "token2")
+	assert.ErrorIs(t, err, core.ErrRefreshTokenExpired)
+
+	mock.ExpectQuery(getQuery).WithArgs("token3").WillReturnRows(sqlmock.NewRows([]string{"user_data", "expires_at", "created_at"}))
+	_, err = s.Get(ctx, "token3")
+	assert.ErrorIs(t, err, core.ErrRefreshTokenNotFound)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM refresh_tokens WHERE token = $1")).WithArgs("token1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, s.Delete(ctx, "token1"))
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM refresh_tokens WHERE token LIKE $1")).WithArgs(`tenant\_a:%`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	n, err := s.DeleteByPrefix(ctx, "tenant_a:")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM refresh_tokens WHERE expires_at <= $1")).WillReturnResult(sqlmock.NewResult(0, 3))
+	n, err = s.Cleanup(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM refresh_tokens WHERE expires_at > $1 AND token NOT LIKE '!%'")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	n, err = s.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRefreshTokenStore_BackgroundCleanup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM auth.tokens WHERE expires_at <= $1")).WillReturnResult(sqlmock.NewResult(0, 1))
+	s, err := NewPostgresRefreshTokenStore(db,
+		WithPostgresTableName("auth.tokens"),
+		WithPostgresCreateTable(false),
+		WithPostgresCleanupInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, s.Close())
+
+	_, err = NewPostgresRefreshTokenStore(db, WithPostgresTableName("tokens; DROP TABLE users"))
+	assert.Error(t, err)
+	_, err = NewPostgresRefreshTokenStore(nil)
+	assert.Error(t, err)
+}
@@ -6,13 +6,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/moweilong/milady/pkg/jwt/core"
 	"github.com/redis/rueidis"
 )
 
-var _ core.TokenStore = (*RedisRefreshTokenStore)(nil)
+var (
+	_ core.TokenStore       = (*RedisRefreshTokenStore)(nil)
+	_ core.PrefixDeleter    = (*RedisRefreshTokenStore)(nil)
+	_ core.ExpiryGetter     = (*RedisRefreshTokenStore)(nil)
+	_ core.SessionTracker   = (*RedisRefreshTokenStore)(nil)
+	_ core.UserTokenIndexer = (*RedisRefreshTokenStore)(nil)
+)
 
 type RedisRefreshTokenStore struct {
 	client   rueidis.Client
@@ -157,6 +164,23 @@ func (s *RedisRefreshTokenStore) Set(
 // Get retrieves user data associated with a refresh token
 // This method benefits from client-side caching for frequently accessed tokens
 func (s *RedisRefreshTokenStore) Get(ctx context.Context, token string) (any, error) {
+	tokenData, err := s.getData(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return tokenData.UserData, nil
+}
+
+// GetExpiry retrieves the expiration time of a refresh token
+func (s *RedisRefreshTokenStore) GetExpiry(ctx context.Context, token string) (time.Time, error) {
+	tokenData, err := s.getData(ctx, token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return tokenData.Expiry, nil
+}
+
+func (s *RedisRefreshTokenStore) getData(ctx context.Context, token string) (*core.RefreshTokenData, error) {
 	if token == "" {
 		return nil, core.ErrRefreshTokenNotFound
 	}
@@ -194,7 +218,7 @@ func (s *RedisRefreshTokenStore) Get(ctx context.Context, token string) (any, er
 		return nil, core.ErrRefreshTokenExpired
 	}
 
-	return tokenData.UserData, nil
+	return &tokenData, nil
 }
 
 // Delete removes a refresh token from storage
@@ -236,6 +260,9 @@ func (s *RedisRefreshTokenStore) Cleanup(ctx context.Context) (int, error) {
 
 		// Check each key for expiration
 		for _, key := range scanResult.Elements {
+			if s.isUserKey(key) {
+				continue // the user index sets expire by their ttl
+			}
 			getCmd := s.client.B().Get().Key(key).Build()
 			getResult := s.client.Do(ctx, getCmd)
 
@@ -275,6 +302,225 @@ func (s *RedisRefreshTokenStore) Cleanup(ctx context.Context) (int, error) {
 	return cleaned, nil
 }
 
+// DeleteByPrefix removes the tokens whose key starts with prefix
+func (s *RedisRefreshTokenStore) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	pattern := s.buildKey(escapeGlob(prefix) + "*")
+	var deleted int
+	var cursor uint64
+
+	for {
+		cmd := s.client.B().Scan().Cursor(cursor).Match(pattern).Count(100).Build()
+		result := s.client.Do(ctx, cmd)
+
+		if result.Error() != nil {
+			return deleted, fmt.Errorf("failed to scan Redis keys: %w", result.Error())
+		}
+
+		scanResult, err := result.AsScanEntry()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to parse scan result: %w", err)
+		}
+
+		if len(scanResult.Elements) > 0 {
+			n, err := s.client.Do(ctx, s.client.B().Del().Key(scanResult.Elements...).Build()).AsInt64()
+			if err != nil {
+				return deleted, fmt.Errorf("failed to delete Redis keys: %w", err)
+			}
+			deleted += int(n)
+		}
+
+		cursor = scanResult.Cursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return deleted, nil
+}
+
+// buildUserKey creates the Redis key of the set holding the tokens of the user,
+// it starts with the internal key prefix so the set is not counted as a refresh token
+func (s *RedisRefreshTokenStore) buildUserKey(user string) string {
+	return s.prefix + internalKeyPrefix + "user:" + user
+}
+
+// isUserKey returns true if the key is the index set of a user instead of a refresh token
+func (s *RedisRefreshTokenStore) isUserKey(key string) bool {
+	return strings.HasPrefix(key, s.buildUserKey(""))
+}
+
+// AddUserToken adds the token to the index set of the user, the ttl of the set is extended to the expiry of the token
+func (s *RedisRefreshTokenStore) AddUserToken(ctx context.Context, user string, token string, expiry time.Time) error {
+	ttl := int64(time.Until(expiry).Seconds())
+	if ttl <= 0 {
+		return errors.New("token expiry time must be in the future")
+	}
+
+	key := s.buildUserKey(user)
+	results := s.client.DoMulti(ctx,
+		s.client.B().Sadd().Key(key).Member(token).Build(),
+		s.client.B().Expire().Key(key).Seconds(ttl).Nx().Build(),
+		s.client.B().Expire().Key(key).Seconds(ttl).Gt().Build(),
+	)
+	for _, result := range results {
+		if err := result.Error(); err != nil {
+			return fmt.Errorf("failed to index token in Redis: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListUserTokens returns the data of the active tokens in the index set of the user,
+// the tokens which no longer exist are removed from the set
+func (s *RedisRefreshTokenStore) ListUserTokens(ctx context.Context, user string) (map[string]*core.RefreshTokenData, error) {
+	key := s.buildUserKey(user)
+	tokens, err := s.client.Do(ctx, s.client.B().Smembers().Key(key).Build()).AsStrSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tokens from Redis: %w", err)
+	}
+
+	result := make(map[string]*core.RefreshTokenData)
+	if len(tokens) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(tokens))
+	for i, token := range tokens {
+		keys[i] = s.buildKey(token)
+	}
+	values, err := s.client.Do(ctx, s.client.B().Mget().Key(keys...).Build()).ToArray()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tokens from Redis: %w", err)
+	}
+
+	var stale []string
+	for i, value := range values {
+		data, err := value.ToString()
+		if err != nil {
+			stale = append(stale, tokens[i]) // deleted or expired
+			continue
+		}
+		var tokenData core.RefreshTokenData
+		if err := json.Unmarshal([]byte(data), &tokenData); err != nil || tokenData.IsExpired() {
+			continue
+		}
+		result[tokens[i]] = &tokenData
+	}
+
+	if len(stale) > 0 {
+		if err := s.client.Do(ctx, s.client.B().Srem().Key(key).Member(stale...).Build()).Error(); err != nil {
+			return nil, fmt.Errorf("failed to remove user tokens from Redis: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteByUser removes all tokens in the index set of the user and the set
+func (s *RedisRefreshTokenStore) DeleteByUser(ctx context.Context, user string) (int, error) {
+	key := s.buildUserKey(user)
+	tokens, err := s.client.Do(ctx, s.client.B().Smembers().Key(key).Build()).AsStrSlice()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user tokens from Redis: %w", err)
+	}
+
+	var deleted int
+	if len(tokens) > 0 {
+		keys := make([]string, len(tokens))
+		for i, token := range tokens {
+			keys[i] = s.buildKey(token)
+		}
+		n, err := s.client.Do(ctx, s.client.B().Del().Key(keys...).Build()).AsInt64()
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete Redis keys: %w", err)
+		}
+		deleted = int(n)
+	}
+
+	if err := s.client.Do(ctx, s.client.B().Del().Key(key).Build()).Error(); err != nil {
+		return deleted, fmt.Errorf("failed to delete user tokens from Redis: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Touch records the time when the refresh token was last used, the ttl of the key is kept
+func (s *RedisRefreshTokenStore) Touch(ctx context.Context, token string, lastUsed time.Time) error {
+	tokenData, err := s.getData(ctx, token)
+	if err != nil {
+		return err
+	}
+	tokenData.LastUsed = lastUsed
+
+	data, err := json.Marshal(tokenData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token data: %w", err)
+	}
+	// XX does not recreate the key if it expires or is deleted in the meantime
+	cmd := s.client.B().Set().Key(s.buildKey(token)).Value(string(data)).Xx().Keepttl().Build()
+	if err := s.client.Do(ctx, cmd).Error(); err != nil {
+		if rueidis.IsRedisNil(err) {
+			return core.ErrRefreshTokenNotFound
+		}
+		return fmt.Errorf("failed to update token in Redis: %w", err)
+	}
+	return nil
+}
+
+// ListSessions returns the data of the active tokens whose key starts with prefix
+func (s *RedisRefreshTokenStore) ListSessions(ctx context.Context, prefix string) (map[string]*core.RefreshTokenData, error) {
+	pattern := s.buildKey(escapeGlob(prefix) + "*")
+	result := make(map[string]*core.RefreshTokenData)
+	var cursor uint64
+
+	for {
+		cmd := s.client.B().Scan().Cursor(cursor).Match(pattern).Count(100).Build()
+		scanResult, err := s.client.Do(ctx, cmd).AsScanEntry()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan Redis keys: %w", err)
+		}
+
+		if len(scanResult.Elements) > 0 {
+			values, err := s.client.Do(ctx, s.client.B().Mget().Key(scanResult.Elements...).Build()).ToArray()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get tokens from Redis: %w", err)
+			}
+			for i, value := range values {
+				data, err := value.ToString()
+				if err != nil {
+					continue // deleted after scanned
+				}
+				var tokenData core.RefreshTokenData
+				if err := json.Unmarshal([]byte(data), &tokenData); err != nil || tokenData.IsExpired() {
+					continue
+				}
+				result[strings.TrimPrefix(scanResult.Elements[i], s.prefix)] = &tokenData
+			}
+		}
+
+		cursor = scanResult.Cursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// escapeGlob escapes the glob-style pattern characters of SCAN MATCH
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 // Count returns the total number of active refresh tokens
 func (s *RedisRefreshTokenStore) Count(ctx context.Context) (int, error) {
 	pattern := s.buildKey("*")
@@ -294,7 +540,11 @@ func (s *RedisRefreshTokenStore) Count(ctx context.Context) (int, error) {
 			return 0, fmt.Errorf("failed to parse scan result: %w", err)
 		}
 
-		count += len(scanResult.Elements)
+		for _, key := range scanResult.Elements {
+			if !isInternalKey(strings.TrimPrefix(key, s.prefix)) {
+				count++
+			}
+		}
 		cursor = scanResult.Cursor
 
 		if cursor == 0 {
@@ -78,6 +78,14 @@ func TestRedisRefreshTokenStore_Integration(t *testing.T) {
 	t.Run("ClientSideCache", func(t *testing.T) {
 		testClientSideCache(t, store)
 	})
+
+	t.Run("DeleteByUser", func(t *testing.T) {
+		testDeleteByUser(t, store)
+	})
+
+	t.Run("ListUserTokens", func(t *testing.T) {
+		testListUserTokens(t, store)
+	})
 }
 
 func testBasicOperations(t *testing.T, store *RedisRefreshTokenStore) {
@@ -173,6 +181,7 @@ func testCleanup(t *testing.T, store *RedisRefreshTokenStore) {
 	futureExpiry := time.Now().Add(time.Hour)
 	err := store.Set(ctx, tokens[2], userData, futureExpiry)
 	assert.NoError(t, err, "Set should not return error")
+	assert.NoError(t, store.AddUserToken(ctx, "cleanup-user", tokens[2], futureExpiry))
 
 	// Run cleanup
 	cleaned, err := store.Cleanup(ctx)
@@ -183,6 +192,14 @@ func testCleanup(t *testing.T, store *RedisRefreshTokenStore) {
 	_, err = store.Get(ctx, tokens[2])
 	assert.NoError(t, err, "Non-expired token should still exist after cleanup")
 
+	// The user index set is not a refresh token, it is kept by cleanup
+	assert.True(t, store.isUserKey(store.buildUserKey("cleanup-user")))
+	assert.False(t, store.isUserKey(store.buildKey(tokens[2])))
+	userTokens, err := store.ListUserTokens(ctx, "cleanup-user")
+	assert.NoError(t, err, "ListUserTokens should not return error")
+	assert.Contains(t, userTokens, tokens[2])
+	_ = store.client.Do(ctx, store.client.B().Del().Key(store.buildUserKey("cleanup-user")).Build())
+
 	// Clean up test data
 	for _, token := range tokens {
 		_ = store.client.Do(ctx, store.client.B().Del().Key(store.buildKey(token)).Build())
@@ -216,6 +233,17 @@ func testCount(t *testing.T, store *RedisRefreshTokenStore) {
 	assert.NoError(t, err, "Count should not return error")
 	assert.GreaterOrEqual(t, newCount, initialCount+len(keys), "Count should include new tokens")
 
+	// the keys written by the middleware and the user index sets are not refresh tokens
+	assert.NoError(t, store.AddUserToken(ctx, "count-user", "count-token-1", expiry))
+	assert.NoError(t, store.Set(ctx, "!jti:count-jti", true, expiry))
+	assert.NoError(t, store.Set(ctx, "!family:count-family", "count-token-1", expiry))
+	internalCount, err := store.Count(ctx)
+	assert.NoError(t, err, "Count should not return error")
+	assert.Equal(t, newCount, internalCount, "Count should exclude the internal keys")
+	assert.NoError(t, store.Delete(ctx, "!jti:count-jti"))
+	assert.NoError(t, store.Delete(ctx, "!family:count-family"))
+	_ = store.client.Do(ctx, store.client.B().Del().Key(store.buildUserKey("count-user")).Build())
+
 	// Clean up test data
 	for _, token := range keys {
 		err := store.Delete(ctx, token)
@@ -254,6 +282,48 @@ func testClientSideCache(t *testing.T, store *RedisRefreshTokenStore) {
 	_ = store.client.Do(ctx, store.client.B().Del().Key(store.buildKey(token)).Build())
 }
 
+func testDeleteByUser(t *testing.T, store *RedisRefreshTokenStore) {
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+
+	tokens := []string{"user-token-1", "user-token-2", "user-token-3"}
+	for _, token := range tokens {
+		require.NoError(t, store.Set(ctx, token, "user1", expiry))
+		require.NoError(t, store.AddUserToken(ctx, "user1", token, expiry))
+	}
+
+	n, err := store.DeleteByUser(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, len(tokens), n)
+	for _, token := range tokens {
+		_, err = store.Get(ctx, token)
+		assert.ErrorIs(t, err, core.ErrRefreshTokenNotFound)
+	}
+
+	n, err = store.DeleteByUser(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func testListUserTokens(t *testing.T, store *RedisRefreshTokenStore) {
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+
+	for _, token := range []string{"list-token-1", "list-token-2"} {
+		require.NoError(t, store.Set(ctx, token, "user2", expiry))
+		require.NoError(t, store.AddUserToken(ctx, "user2", token, expiry))
+	}
+	require.NoError(t, store.Delete(ctx, "list-token-1"))
+
+	tokens, err := store.ListUserTokens(ctx, "user2")
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.Contains(t, tokens, "list-token-2")
+
+	_, err = store.DeleteByUser(ctx, "user2")
+	assert.NoError(t, err)
+}
+
 func TestRedisRefreshTokenStore_ConnectionFailure(t *testing.T) {
 	// Test with invalid Redis configuration
 	config := &RedisConfig{
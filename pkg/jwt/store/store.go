@@ -1,6 +1,10 @@
 package store
 
-import "github.com/moweilong/milady/pkg/jwt/core"
+import (
+	"strings"
+
+	"github.com/moweilong/milady/pkg/jwt/core"
+)
 
 // Re-export types from core for backward compatibility
 type RefreshTokenStorer = core.TokenStore
@@ -12,6 +16,14 @@ var (
 	ErrRefreshTokenExpired  = core.ErrRefreshTokenExpired
 )
 
+// internalKeyPrefix the keys starting with it are written by the middleware, such as the revoked access tokens
+// and the refresh token families, they are not refresh tokens and excluded from Count and GetAll
+const internalKeyPrefix = "!"
+
+func isInternalKey(token string) bool {
+	return strings.HasPrefix(token, internalKeyPrefix)
+}
+
 // Default creates a default memory-based token store
 // This is the recommended way to create a store with sensible defaults
 func Default() core.TokenStore {
@@ -4,6 +4,7 @@ package query
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -93,6 +94,27 @@ var logicMap = map[string]string{
 	"or:)":  OR,
 }
 
+// SupportedExps return all expressions accepted by the exp field of Column in sorted order,
+// including the aliases, example: eq, =
+func SupportedExps() []string {
+	return sortedKeys(expMap)
+}
+
+// SupportedLogics return all logical types accepted by the logic field of Column in sorted order,
+// including the aliases and grouping forms, example: and, &, and:(
+func SupportedLogics() []string {
+	return sortedKeys(logicMap)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ---------------------------------------------------------------------------
 
 type rulerOptions struct {
@@ -433,13 +455,7 @@ func buildFilterWithStack(columns []Column) (bson.M, error) {
 
 			var combined bson.M
 			if currentGroup.operator == "$and" {
-				merged := bson.M{}
-				for _, f := range currentGroup.filters {
-					for k, v := range f {
-						merged[k] = v
-					}
-				}
-				combined = merged
+				combined = mergeAndFilters(currentGroup.filters)
 			} else {
 				combined = bson.M{currentGroup.operator: currentGroup.filters}
 			}
@@ -473,6 +489,57 @@ func buildFilterWithStack(columns []Column) (bson.M, error) {
 	return finalFilter, nil
 }
 
+// rangeOperators operators that can be combined into one field document
+var rangeOperators = map[string]bool{"$gt": true, "$gte": true, "$lt": true, "$lte": true}
+
+// mergeAndFilters merge the filters of an and group into one document, range conditions
+// of the same field are combined into {field: {$gte: a, $lte: b}} so that the index can be used,
+// fall back to $and if the conditions of the same field cannot be combined.
+func mergeAndFilters(filters []bson.M) bson.M {
+	merged := bson.M{}
+	for _, f := range filters {
+		for k, v := range f {
+			existing, ok := merged[k]
+			if !ok {
+				merged[k] = v
+				continue
+			}
+			combined, ok := mergeRangeCondition(existing, v)
+			if !ok {
+				return bson.M{"$and": filters}
+			}
+			merged[k] = combined
+		}
+	}
+	return merged
+}
+
+// mergeRangeCondition combine two range conditions of the same field, e.g. {$gte: a} and {$lte: b}
+func mergeRangeCondition(a interface{}, b interface{}) (bson.M, bool) {
+	ma, ok := a.(bson.M)
+	if !ok {
+		return nil, false
+	}
+	mb, ok := b.(bson.M)
+	if !ok {
+		return nil, false
+	}
+
+	combined := bson.M{}
+	for _, m := range []bson.M{ma, mb} {
+		for op, v := range m {
+			if !rangeOperators[op] {
+				return nil, false
+			}
+			if _, exists := combined[op]; exists {
+				return nil, false
+			}
+			combined[op] = v
+		}
+	}
+	return combined, true
+}
+
 // use precedence rules to handle flat lists (AND has higher precedence than OR)
 func buildFilterWithPrecedence(columns []Column) (bson.M, error) {
 	orGroups := [][]*Column{}
@@ -625,3 +692,25 @@ func (c *Conditions) ConvertToMongo(opts ...RulerOption) (bson.M, error) {
 	p := &Params{Columns: c.Columns}
 	return p.ConvertToMongoFilter(opts...)
 }
+
+// OrConditions combine the independently built condition sets with OR, each set is converted by ConvertToMongo,
+// return filter is {"$or": [set1, set2, ...]}, an empty set is invalid because it would match all documents
+func OrConditions(sets ...Conditions) (bson.M, error) {
+	if len(sets) == 0 {
+		return nil, fmt.Errorf("condition sets cannot be empty")
+	}
+
+	filters := make([]bson.M, 0, len(sets))
+	for i := range sets {
+		if err := sets[i].CheckValid(); err != nil {
+			return nil, fmt.Errorf("condition set %d: %v", i, err)
+		}
+		filter, err := sets[i].ConvertToMongo()
+		if err != nil {
+			return nil, fmt.Errorf("condition set %d: %v", i, err)
+		}
+		filters = append(filters, filter)
+	}
+
+	return bson.M{"$or": filters}, nil
+}
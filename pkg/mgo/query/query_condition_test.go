@@ -554,6 +554,38 @@ func TestParams_ConvertToMongoFilter(t *testing.T) {
 			wantErr: false,
 		},
 
+		{
+			name: "parentheses group merge range",
+			args: args{
+				columns: []Column{
+					{Name: "age", Exp: ">=", Value: 20, Logic: "and:("},
+					{Name: "age", Exp: "<=", Value: 30, Logic: "or:)"},
+					{Name: "age", Exp: ">", Value: 50, Logic: "and:("},
+					{Name: "age", Exp: "<", Value: 60, Logic: "and:)"},
+				},
+			},
+			want: bson.M{
+				"$or": []bson.M{
+					{"age": bson.M{"$gte": 20, "$lte": 30}},
+					{"age": bson.M{"$gt": 50, "$lt": 60}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "parentheses group same field not merged",
+			args: args{
+				columns: []Column{
+					{Name: "age", Exp: ">=", Value: 20, Logic: "and:("},
+					{Name: "age", Exp: "!=", Value: 25, Logic: "and:)"},
+				},
+			},
+			want: bson.M{
+				"$and": []bson.M{{"age": bson.M{"$gte": 20}}, {"age": bson.M{"$ne": 25}}},
+			},
+			wantErr: false,
+		},
+
 		// --------------------------- datetime condition  ------------------------------
 
 		{
@@ -804,6 +836,31 @@ func TestConditions_ConvertToMongo(t *testing.T) {
 	}
 }
 
+func TestOrConditions(t *testing.T) {
+	set1 := Conditions{
+		Columns: []Column{
+			{Name: "name", Value: "ZhangSan"},
+			{Name: "gender", Value: "male"},
+		}}
+	set2 := Conditions{
+		Columns: []Column{
+			{Name: "age", Value: 20, Exp: Gt},
+		}}
+	got, err := OrConditions(set1, set2)
+	assert.NoError(t, err)
+	want := bson.M{"$or": []bson.M{
+		{"$and": []bson.M{{"name": "ZhangSan"}, {"gender": "male"}}},
+		{"age": bson.M{"$gt": 20}},
+	}}
+	assert.Equal(t, want, got)
+
+	_, err = OrConditions()
+	assert.Error(t, err)
+
+	_, err = OrConditions(set1, Conditions{})
+	assert.Error(t, err)
+}
+
 func TestConditions_checkValid(t *testing.T) {
 	// empty error
 	c := Conditions{}
@@ -1119,3 +1176,26 @@ func Test_convertValue(t *testing.T) {
 		})
 	}
 }
+
+func TestSupportedExpsAndLogics(t *testing.T) {
+	exps := SupportedExps()
+	for _, exp := range []string{"=", "!=", ">", ">=", "<", "<=", "like", "in", "nin", "not in", "isnull", "is not null"} {
+		assert.Contains(t, exps, exp)
+	}
+	logics := SupportedLogics()
+	for _, logic := range []string{"and", "&", "&&", "or", "|", "||", "and:(", "or:)"} {
+		assert.Contains(t, logics, logic)
+	}
+
+	// every listed value is accepted by CheckValid
+	for _, exp := range exps {
+		c := &Conditions{Columns: []Column{{Name: "name", Exp: exp, Value: "foo"}}}
+		assert.NoError(t, c.CheckValid(), exp)
+	}
+	for _, logic := range logics {
+		c := &Conditions{Columns: []Column{{Name: "name", Value: "foo", Logic: logic}}}
+		assert.NoError(t, c.CheckValid(), logic)
+	}
+	c := &Conditions{Columns: []Column{{Name: "name", Exp: "between", Value: "foo"}}}
+	assert.Error(t, c.CheckValid())
+}
@@ -0,0 +1,87 @@
+package sgorm
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type auditUserKey struct{}
+
+// WithAuditUser returns a context carrying the user recorded in the audit fields,
+// pass it to db.WithContext before creating or updating the records
+func WithAuditUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, auditUserKey{}, user)
+}
+
+// AuditUserFromContext returns the user set by WithAuditUser, it is empty if not set
+func AuditUserFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	user, _ := ctx.Value(auditUserKey{}).(string)
+	return user
+}
+
+// AuditUser embedded structs recording the users who created and updated the record,
+// the users are read from the context of the db session by the create and update hooks,
+// it is used with Model which already has the time fields
+type AuditUser struct {
+	CreatedBy string `gorm:"column:created_by;type:varchar(64)" json:"createdBy"`
+	UpdatedBy string `gorm:"column:updated_by;type:varchar(64)" json:"updatedBy"`
+}
+
+// BeforeCreate set created_by and updated_by to the user of the context
+func (a *AuditUser) BeforeCreate(tx *gorm.DB) error {
+	return setAuditUser(tx, true)
+}
+
+// BeforeUpdate set updated_by to the user of the context, it also works with the map updates
+func (a *AuditUser) BeforeUpdate(tx *gorm.DB) error {
+	return setAuditUser(tx, false)
+}
+
+// AuditUser2 embedded structs, json tag named is snake case
+type AuditUser2 struct {
+	CreatedBy string `gorm:"column:created_by;type:varchar(64)" json:"created_by"`
+	UpdatedBy string `gorm:"column:updated_by;type:varchar(64)" json:"updated_by"`
+}
+
+// BeforeCreate set created_by and updated_by to the user of the context
+func (a *AuditUser2) BeforeCreate(tx *gorm.DB) error {
+	return setAuditUser(tx, true)
+}
+
+// BeforeUpdate set updated_by to the user of the context, it also works with the map updates
+func (a *AuditUser2) BeforeUpdate(tx *gorm.DB) error {
+	return setAuditUser(tx, false)
+}
+
+// Audit embedded structs recording who and when created and updated the record,
+// add `gorm:"embedded"` when defining table structs
+type Audit struct {
+	AuditUser `gorm:"embedded"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// Audit2 embedded structs, json tag named is snake case
+type Audit2 struct {
+	AuditUser2 `gorm:"embedded"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// setAuditUser set the audit user columns of the statement, nothing is changed if the context has no user
+func setAuditUser(tx *gorm.DB, isCreate bool) error {
+	user := AuditUserFromContext(tx.Statement.Context)
+	if user == "" {
+		return nil
+	}
+	if isCreate {
+		tx.Statement.SetColumn("created_by", user, true)
+	}
+	tx.Statement.SetColumn("updated_by", user, true)
+	return nil
+}
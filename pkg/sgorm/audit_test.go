@@ -0,0 +1,51 @@
+package sgorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type auditExample struct {
+	ID    uint64 `gorm:"column:id;primary_key"`
+	Audit `gorm:"embedded"`
+
+	Name string `gorm:"column:name"`
+}
+
+func TestAudit(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&auditExample{}))
+
+	ctx := WithAuditUser(context.Background(), "alice")
+	assert.Equal(t, "alice", AuditUserFromContext(ctx))
+
+	record := &auditExample{Name: "foo"}
+	require.NoError(t, db.WithContext(ctx).Create(record).Error)
+	assert.Equal(t, "alice", record.CreatedBy)
+	assert.Equal(t, "alice", record.UpdatedBy)
+	assert.False(t, record.CreatedAt.IsZero())
+
+	// map updates set updated_by too
+	ctx = WithAuditUser(context.Background(), "bob")
+	err = db.WithContext(ctx).Model(&auditExample{}).Where("id = ?", record.ID).
+		Updates(map[string]interface{}{"name": "bar"}).Error
+	require.NoError(t, err)
+
+	got := &auditExample{}
+	require.NoError(t, db.First(got, record.ID).Error)
+	assert.Equal(t, "bar", got.Name)
+	assert.Equal(t, "alice", got.CreatedBy)
+	assert.Equal(t, "bob", got.UpdatedBy)
+
+	// the context without user keeps the audit users
+	err = db.WithContext(context.Background()).Model(got).Update("name", "baz").Error
+	require.NoError(t, err)
+	require.NoError(t, db.First(got, record.ID).Error)
+	assert.Equal(t, "bob", got.UpdatedBy)
+}
@@ -84,6 +84,7 @@ var logicMap = map[string]string{
 type rulerOptions struct {
 	whitelistNames map[string]bool
 	validateFn     func(columns []Column) error
+	stringFields   map[string]bool
 }
 
 // RulerOption set the parameters of ruler options
@@ -109,6 +110,17 @@ func WithValidateFn(fn func(columns []Column) error) RulerOption {
 	}
 }
 
+// WithStringFields set names of columns whose string values are kept as is, the numeric strings
+// such as external ids "00123" are not converted to numbers
+func WithStringFields(names []string) RulerOption {
+	return func(o *rulerOptions) {
+		o.stringFields = make(map[string]bool, len(names))
+		for _, name := range names {
+			o.stringFields[name] = true
+		}
+	}
+}
+
 // -----------------------------------------------------------------------------
 
 // Params query parameters
@@ -253,7 +265,7 @@ func (p *Params) ConvertToGormConditions(opts ...RulerOption) (string, []interfa
 			if v != " IS NULL " && v != " IS NOT NULL " {
 				return "", nil, fmt.Errorf("field 'value' cannot be nil")
 			}
-		} else {
+		} else if !o.stringFields[column.Name] {
 			column.Value = convertValue(column.Value)
 		}
 
@@ -784,6 +784,28 @@ func TestConditions_ConvertToGormConditions_Error(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestConditions_ConvertToGormConditions_StringFields(t *testing.T) {
+	p := &Params{
+		Columns: []Column{
+			{
+				Name:  "ref_id",
+				Value: "00123",
+			},
+			{
+				Name:  "age",
+				Value: "10",
+			},
+		}}
+
+	_, values, err := p.ConvertToGormConditions()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{123, 10}, values)
+
+	_, values, err = p.ConvertToGormConditions(WithStringFields([]string{"ref_id"}))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"00123", 10}, values)
+}
+
 func TestConditions_ConvertToGorm(t *testing.T) {
 	c := Conditions{
 		Columns: []Column{
@@ -0,0 +1,245 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// checkRule a simple range rule parsed from CHECK constraint, example: age >= 0
+type checkRule struct {
+	Op    string // gt, gte, lt, lte
+	Value string // number
+}
+
+var (
+	createTableRegexp  = regexp.MustCompile("(?is)create\\s+table\\s+(?:if\\s+not\\s+exists\\s+)?([`\"\\w.]+)")
+	constraintRegexp   = regexp.MustCompile("(?i)constraint\\s+[`\"\\w]+\\s*$")
+	enforcedRegexp     = regexp.MustCompile(`(?i)^\s+(?:not\s+)?enforced\b`)
+	checkBetweenRegexp = regexp.MustCompile("(?i)^`?(\\w+)`?\\s+between\\s+(-?\\d+(?:\\.\\d+)?)\\s+and\\s+(-?\\d+(?:\\.\\d+)?)$")
+	checkCompareRegexp = regexp.MustCompile("^`?(\\w+)`?\\s*(>=|<=|>|<)\\s*(-?\\d+(?:\\.\\d+)?)$")
+	checkReverseRegexp = regexp.MustCompile("^(-?\\d+(?:\\.\\d+)?)\\s*(>=|<=|>|<)\\s*`?(\\w+)`?$")
+	checkAndRegexp     = regexp.MustCompile(`(?i)\s+and\s+`)
+
+	compareOps = map[string]string{">=": "gte", ">": "gt", "<=": "lte", "<": "lt"}
+	reverseOps = map[string]string{">=": "lte", ">": "lt", "<=": "gte", "<": "gt"}
+)
+
+// parseCheckConstraints remove the CHECK constraints from sql which are not supported by the sql parser,
+// and return the simple range rules of them, the key of rules is table name and column name in lower case.
+func parseCheckConstraints(sql string) (string, map[string]map[string][]checkRule) {
+	rules := make(map[string]map[string][]checkRule)
+	tables := createTableRegexp.FindAllStringSubmatchIndex(sql, -1)
+	tableName := func(pos int) string {
+		name := ""
+		for _, loc := range tables {
+			if loc[0] > pos {
+				break
+			}
+			name = strings.Trim(sql[loc[2]:loc[3]], "`\"")
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = strings.Trim(name[i+1:], "`\"")
+			}
+		}
+		return strings.ToLower(name)
+	}
+
+	var builder strings.Builder
+	last := 0
+	for i := 0; i < len(sql); i++ {
+		if c := sql[i]; c == '\'' || c == '"' || c == '`' {
+			i = skipQuoted(sql, i)
+			continue
+		}
+		if !isCheckKeyword(sql, i) {
+			continue
+		}
+		open := i + len("check")
+		for open < len(sql) && isSpace(sql[open]) {
+			open++
+		}
+		if open >= len(sql) || sql[open] != '(' {
+			continue
+		}
+		end := matchParen(sql, open)
+		if end < 0 {
+			break
+		}
+		expr := sql[open+1 : end]
+
+		// remove "[, ] [constraint name] check (expr) [[not] enforced]"
+		start := i
+		if loc := constraintRegexp.FindStringIndex(sql[last:i]); loc != nil {
+			start = last + loc[0]
+		}
+		if j := strings.LastIndexFunc(sql[last:start], func(r rune) bool { return !isSpace(byte(r)) }); j >= 0 && sql[last+j] == ',' {
+			start = last + j
+		}
+		end++
+		if loc := enforcedRegexp.FindStringIndex(sql[end:]); loc != nil {
+			end += loc[1]
+		}
+
+		if table := tableName(i); table != "" {
+			for col, colRules := range parseCheckExpr(expr) {
+				if rules[table] == nil {
+					rules[table] = make(map[string][]checkRule)
+				}
+				rules[table][col] = append(rules[table][col], colRules...)
+			}
+		}
+		builder.WriteString(sql[last:start])
+		last = end
+		i = end - 1
+	}
+	builder.WriteString(sql[last:])
+
+	return builder.String(), rules
+}
+
+// parseCheckExpr parse simple range expressions joined with AND, example: age >= 0 and age <= 150,
+// score between 0 and 100, return nil if the expression is not supported.
+func parseCheckExpr(expr string) map[string][]checkRule {
+	expr = strings.TrimSpace(expr)
+	for strings.HasPrefix(expr, "(") && matchParen(expr, 0) == len(expr)-1 {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+
+	rules := make(map[string][]checkRule)
+	if m := checkBetweenRegexp.FindStringSubmatch(expr); m != nil {
+		col := strings.ToLower(m[1])
+		rules[col] = append(rules[col], checkRule{Op: "gte", Value: m[2]}, checkRule{Op: "lte", Value: m[3]})
+		return rules
+	}
+
+	for _, part := range checkAndRegexp.Split(expr, -1) {
+		part = strings.TrimSpace(part)
+		for strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")") {
+			part = strings.TrimSpace(part[1 : len(part)-1])
+		}
+		if m := checkCompareRegexp.FindStringSubmatch(part); m != nil {
+			col := strings.ToLower(m[1])
+			rules[col] = append(rules[col], checkRule{Op: compareOps[m[2]], Value: m[3]})
+			continue
+		}
+		if m := checkReverseRegexp.FindStringSubmatch(part); m != nil {
+			col := strings.ToLower(m[3])
+			rules[col] = append(rules[col], checkRule{Op: reverseOps[m[2]], Value: m[1]})
+			continue
+		}
+		return nil
+	}
+	return rules
+}
+
+// BindingTag return the gin binding tag of check rules, example: gte=0,lte=150
+func (t tmplField) BindingTag() string {
+	if len(t.checkRules) == 0 || strings.HasPrefix(t.GoType, "sql.") {
+		return ""
+	}
+	tags := make([]string, 0, len(t.checkRules))
+	for _, rule := range t.checkRules {
+		tags = append(tags, rule.Op+"="+rule.Value)
+	}
+	return strings.Join(tags, ",")
+}
+
+// UpdateBindingTag return the gin binding tag of check rules for update request, zero value means not updated
+func (t tmplField) UpdateBindingTag() string {
+	tag := t.BindingTag()
+	if tag == "" {
+		return ""
+	}
+	return "omitempty," + tag
+}
+
+// protoCheckRules return the proto validate rules of check rules,
+// example: (validate.rules).int32.gte = 0, (validate.rules).int32.lte = 150
+func (t tmplField) protoCheckRules() []string {
+	isInt, isUnsigned := false, false
+	switch t.GoType {
+	case "int32", "int64", "sint32", "sint64", "sfixed32", "sfixed64":
+		isInt = true
+	case "uint32", "uint64", "fixed32", "fixed64":
+		isInt, isUnsigned = true, true
+	case "float", "double":
+	default:
+		return nil
+	}
+
+	var rules []string
+	for _, rule := range t.checkRules {
+		if isInt {
+			v, err := strconv.ParseInt(rule.Value, 10, 64)
+			if err != nil || (isUnsigned && v < 0) {
+				continue
+			}
+		}
+		rules = append(rules, fmt.Sprintf("(validate.rules).%s.%s = %s", t.GoType, rule.Op, rule.Value))
+	}
+	return rules
+}
+
+// AddOneWithCheck counter and add validate rules of check constraints and openapi example
+func (t tmplField) AddOneWithCheck(i int) string {
+	rules := t.protoCheckRules()
+	if example := t.protoExampleOption(); example != "" {
+		rules = append(rules, example)
+	}
+	if len(rules) == 0 {
+		return strconv.Itoa(i + 1)
+	}
+	return fmt.Sprintf("%d [%s]", i+1, strings.Join(rules, ", "))
+}
+
+func isCheckKeyword(sql string, i int) bool {
+	if i+len("check") > len(sql) || !strings.EqualFold(sql[i:i+len("check")], "check") {
+		return false
+	}
+	isWordChar := func(c byte) bool {
+		return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+	}
+	if i > 0 && isWordChar(sql[i-1]) {
+		return false
+	}
+	return i+len("check") == len(sql) || !isWordChar(sql[i+len("check")])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// skipQuoted return the index of the closing quote which matches the quote at index i
+func skipQuoted(s string, i int) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == '\\' && quote != '`' {
+			j++
+			continue
+		}
+		if s[j] == quote {
+			return j
+		}
+	}
+	return len(s)
+}
+
+// matchParen return the index of the closing parenthesis which matches the one at index open, -1 if not found
+func matchParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(s, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQL_CheckConstraint(t *testing.T) {
+	sql := `create table user (
+    id    bigint unsigned auto_increment,
+    name  varchar(50) not null comment 'check (name) is ignored',
+    age   int         not null check (age >= 0),
+    score double      not null,
+    level int         not null,
+    primary key (id),
+    constraint chk_score check (score between 0 and 100),
+    check (age <= 150) enforced
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithNullStyle(NullDisable))
+	assert.NoError(t, err)
+
+	handler := codes[CodeTypeHandler]
+	assert.Contains(t, handler, `json:"age" binding:"gte=0,lte=150"`)
+	assert.Contains(t, handler, `json:"score" binding:"gte=0,lte=100"`)
+	assert.Contains(t, handler, `json:"age" binding:"omitempty,gte=0,lte=150"`)
+	assert.Contains(t, handler, `json:"level" binding:""`)
+
+	proto := codes[CodeTypeProto]
+	assert.Contains(t, proto, "int32 age = 2 [(validate.rules).int32.gte = 0, (validate.rules).int32.lte = 150];")
+	assert.Contains(t, proto, "double score = 3 [(validate.rules).double.gte = 0, (validate.rules).double.lte = 100];")
+	assert.Contains(t, codes[CodeTypeModel], "check (name) is ignored")
+}
+
+func Test_parseCheckExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		want map[string][]checkRule
+	}{
+		{"age >= 0", map[string][]checkRule{"age": {{"gte", "0"}}}},
+		{"(`age` > 0 AND age < 150)", map[string][]checkRule{"age": {{"gt", "0"}, {"lt", "150"}}}},
+		{"0 <= score", map[string][]checkRule{"score": {{"gte", "0"}}}},
+		{"price BETWEEN 0.5 AND 99.9", map[string][]checkRule{"price": {{"gte", "0.5"}, {"lte", "99.9"}}}},
+		{"age >= 0 or age = -1", nil},
+		{"status in (1, 2)", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseCheckExpr(tt.expr))
+		})
+	}
+}
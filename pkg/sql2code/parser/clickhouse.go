@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClickHouseField clickhouse field
+type ClickHouseField struct {
+	Name         string `gorm:"column:name;" json:"name"`
+	Type         string `gorm:"column:type;" json:"type"`
+	Comment      string `gorm:"column:comment;" json:"comment"`
+	IsPrimaryKey bool   `gorm:"column:is_in_primary_key;" json:"is_primary_key"`
+}
+
+// ClickHouseFields clickhouse fields
+type ClickHouseFields []*ClickHouseField
+
+// ConvertToSQLByClickHouseFields convert to mysql table ddl, the returned map is column name to clickhouse type,
+// it is used as the field types option when the db driver is clickhouse
+func ConvertToSQLByClickHouseFields(tableName string, fields ClickHouseFields) (string, map[string]string) {
+	fieldStr := ""
+	chTypeMap := make(map[string]string) // name:type
+	if len(fields) == 0 {
+		return "", chTypeMap
+	}
+
+	for _, field := range fields {
+		chTypeMap[field.Name] = field.Type
+		notnullStr := "not null"
+		if isClickHouseNullable(field.Type) {
+			notnullStr = "null"
+		}
+		comment := strings.ReplaceAll(field.Comment, "'", "\\'")
+		fieldStr += fmt.Sprintf("    `%s` %s %s comment '%s',\n", field.Name, field.getMysqlType(), notnullStr, comment)
+	}
+
+	primaryField := fields.getPrimaryField()
+	if primaryField != nil {
+		fieldStr += fmt.Sprintf("    PRIMARY KEY (`%s`)\n", primaryField.Name)
+	} else {
+		fieldStr = strings.TrimSuffix(fieldStr, ",\n")
+	}
+	sqlStr := fmt.Sprintf("CREATE TABLE `%s` (\n%s\n);", tableName, fieldStr)
+	return sqlStr, chTypeMap
+}
+
+var clickHouseToMysqlType = map[string]string{
+	"UInt8":   "tinyint unsigned",
+	"UInt16":  "smallint unsigned",
+	"UInt32":  "int unsigned",
+	"UInt64":  "bigint unsigned",
+	"Int8":    "tinyint",
+	"Int16":   "smallint",
+	"Int32":   "int",
+	"Int64":   "bigint",
+	"Float32": "float",
+	"Float64": "double",
+	"Bool":    "bool",
+	"String":  "text",
+	"UUID":    "char(36)",
+	"Date":    "date",
+	"Date32":  "date",
+	"IPv4":    "varchar(15)",
+	"IPv6":    "varchar(39)",
+}
+
+var clickHouseToGoType = map[string]string{
+	"UInt8":   "uint8",
+	"UInt16":  "uint16",
+	"UInt32":  "uint32",
+	"UInt64":  "uint64",
+	"Int8":    "int8",
+	"Int16":   "int16",
+	"Int32":   "int32",
+	"Int64":   "int64",
+	"Float32": "float32",
+	"Float64": "float64",
+	"Bool":    "bool",
+}
+
+// getMysqlType convert clickhouse type to mysql type
+func (field *ClickHouseField) getMysqlType() string {
+	chType := unwrapClickHouseType(field.Type)
+	if mysqlType, ok := clickHouseToMysqlType[chType]; ok {
+		return mysqlType
+	}
+
+	name, args := splitClickHouseType(chType)
+	switch name {
+	case "DateTime", "DateTime64":
+		return "datetime"
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		return "decimal(38, 10)"
+	case "FixedString":
+		return fmt.Sprintf("char(%s)", args)
+	case "Enum8", "Enum16":
+		return "varchar(100)"
+	case "Array", "Map", "Tuple":
+		return "json"
+	}
+
+	// unknown type convert to varchar
+	return "varchar(100)"
+}
+
+// getPrimaryField get primary key field
+func (fields ClickHouseFields) getPrimaryField() *ClickHouseField {
+	for _, field := range fields {
+		if field.IsPrimaryKey || field.Name == "id" {
+			return field
+		}
+	}
+	return nil
+}
+
+// clickHouseGoType return the go type which can not be derived from the mysql type, empty string means using
+// the go type of the mysql type, example: Bool --> bool, Array(String) --> []string, Array(Nullable(UInt64)) --> []uint64
+func clickHouseGoType(chType string) string {
+	name, args := splitClickHouseType(unwrapClickHouseType(chType))
+	if name == "Bool" {
+		return "bool"
+	}
+	if name != "Array" {
+		return ""
+	}
+
+	elem := unwrapClickHouseType(args)
+	switch elemName, _ := splitClickHouseType(elem); elemName {
+	case "Array":
+		return "[]" + clickHouseGoType(elem)
+	case "Date", "Date32", "DateTime", "DateTime64":
+		return "[]time.Time"
+	default:
+		if goType, ok := clickHouseToGoType[elemName]; ok {
+			return "[]" + goType
+		}
+	}
+	return "[]string"
+}
+
+// unwrapClickHouseType remove the Nullable and LowCardinality wrappers, example: LowCardinality(Nullable(String)) --> String
+func unwrapClickHouseType(chType string) string {
+	chType = strings.TrimSpace(chType)
+	for {
+		name, args := splitClickHouseType(chType)
+		if name != "Nullable" && name != "LowCardinality" {
+			return chType
+		}
+		chType = strings.TrimSpace(args)
+	}
+}
+
+func isClickHouseNullable(chType string) bool {
+	name, args := splitClickHouseType(strings.TrimSpace(chType))
+	if name == "LowCardinality" {
+		name, _ = splitClickHouseType(strings.TrimSpace(args))
+	}
+	return name == "Nullable"
+}
+
+// splitClickHouseType split the type name and arguments, example: Decimal(18, 2) --> Decimal, 18, 2
+func splitClickHouseType(chType string) (string, string) {
+	i := strings.Index(chType, "(")
+	if i < 0 || !strings.HasSuffix(chType, ")") {
+		return chType, ""
+	}
+	return chType[:i], chType[i+1 : len(chType)-1]
+}
@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQL_ClickHouse(t *testing.T) {
+	fields := ClickHouseFields{
+		{Name: "id", Type: "UInt64", IsPrimaryKey: true},
+		{Name: "event_time", Type: "DateTime", Comment: "event time"},
+		{Name: "name", Type: "LowCardinality(String)"},
+		{Name: "amount", Type: "Decimal(18, 2)"},
+		{Name: "tags", Type: "Array(String)"},
+		{Name: "scores", Type: "Array(Nullable(UInt32))"},
+		{Name: "is_valid", Type: "Bool"},
+		{Name: "remark", Type: "Nullable(String)"},
+	}
+	sql, fieldTypes := ConvertToSQLByClickHouseFields("user_event", fields)
+	assert.Contains(t, sql, "`remark` text null")
+	assert.Equal(t, "Array(String)", fieldTypes["tags"])
+
+	codes, err := ParseSQL(sql, WithDBDriver(DBDriverClickHouse), WithFieldTypes(fieldTypes),
+		WithJSONTag(0), WithNullStyle(NullDisable), WithRestore(), WithCreateBatch())
+	assert.NoError(t, err)
+	model := codes[CodeTypeModel]
+	assert.Regexp(t, `ID\s+uint64\s`, model)
+	assert.Regexp(t, `EventTime\s+\*time.Time\s`, model)
+	assert.Regexp(t, `Name\s+string\s`, model)
+	assert.Regexp(t, `Amount\s+string\s`, model)
+	assert.Regexp(t, `Tags\s+\[\]string\s`, model)
+	assert.Regexp(t, `Scores\s+\[\]uint32\s`, model)
+	assert.Regexp(t, `IsValid\s+bool\s`, model)
+
+	dao := codes[CodeTypeDAOExtend]
+	assert.Contains(t, dao, "func (d *userEventDao) GetByID(ctx context.Context, id uint64) (*model.UserEvent, error) {")
+	assert.Contains(t, dao, "func (d *userEventDao) List(ctx context.Context, params *query.Params) ([]*model.UserEvent, int64, error) {")
+	assert.Contains(t, dao, "func (d *userEventDao) Count(ctx context.Context, params *query.Params) (int64, error) {")
+	assert.NotContains(t, dao, "CreateBatch")
+	assert.Empty(t, codes[CodeTypeDAO])
+}
+
+func Test_clickHouseGoType(t *testing.T) {
+	tests := map[string]string{
+		"Bool":                          "bool",
+		"Nullable(Bool)":                "bool",
+		"Array(Int64)":                  "[]int64",
+		"Array(LowCardinality(String))": "[]string",
+		"Array(DateTime64(3))":          "[]time.Time",
+		"Array(Array(Float64))":         "[][]float64",
+		"String":                        "",
+		"UInt64":                        "",
+	}
+	for chType, want := range tests {
+		assert.Equal(t, want, clickHouseGoType(chType), chType)
+	}
+}
@@ -14,7 +14,7 @@ var (
 // Create{{.TableName}}Request request params
 type Create{{.TableName}}Request struct {
 {{- range .Fields}}
-	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}" binding:""` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
+	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}" binding:"{{.BindingTag}}"{{.ExampleTag}}` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
 {{- end}}
 }
 `
@@ -24,7 +24,7 @@ type Create{{.TableName}}Request struct {
 // Update{{.TableName}}By{{.CrudInfo.ColumnNameCamel}}Request request params
 type Update{{.TableName}}By{{.CrudInfo.ColumnNameCamel}}Request struct {
 {{- range .Fields}}
-	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}" binding:""` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
+	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}" binding:"{{.UpdateBindingTag}}"{{.ExampleTag}}` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
 {{- end}}
 }
 `
@@ -620,7 +620,7 @@ message List{{.TableName}}Reply {
 	protoMessageCreateCommonTmpl    *template.Template
 	protoMessageCreateCommonTmplRaw = `message Create{{.TableName}}Request {
 {{- range $i, $v := .Fields}}
-	{{$v.GoType}} {{$v.JSONName}} = {{$v.AddOne $i}}; {{if $v.Comment}} // {{$v.Comment}}{{end}}
+	{{$v.GoType}} {{$v.JSONName}} = {{$v.AddOneWithCheck $i}}; {{if $v.Comment}} // {{$v.Comment}}{{end}}
 {{- end}}
 }`
 
@@ -0,0 +1,224 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// convertField the conversion statements of a field between model and proto message
+type convertField struct {
+	ToPB    string // statement converting model field to proto field
+	ToModel string // statement converting proto field to model field
+
+	ColName   string // table column name
+	FieldName string // model field name
+	PBName    string // proto field name
+}
+
+// ConvertFields return the conversion statements of the fields in proto detail message
+func (d extendTmplData) ConvertFields() []convertField {
+	pbFields := goTypeToProto(d.Fields, d.Opt.JSONNamedType, false)
+
+	var fields []convertField
+	for i, field := range d.Fields {
+		if isIgnoreFields(field.ColName, columnID, columnCreatedAt, columnUpdatedAt) {
+			continue
+		}
+		pbType := protoToGoType(pbFields[i].GoType)
+		if d.IsMongo() && strings.ToLower(field.Name) == "id" {
+			pbType = "string"
+		}
+		cf := newConvertField(field, pbFields[i], d.modelFieldType(field), pbType)
+		cf.ColName, cf.FieldName, cf.PBName = field.ColName, field.Name, pbFields[i].JSONName
+		fields = append(fields, cf)
+	}
+	return fields
+}
+
+// UpdateMaskFields return the conversion statements of the fields which can be specified in update_mask,
+// they are the fields of update request message except the primary key
+func (d extendTmplData) UpdateMaskFields() []convertField {
+	isPrimaryKey := make(map[string]bool)
+	for _, field := range d.Fields {
+		if field.IsPrimaryKey {
+			isPrimaryKey[field.ColName] = true
+		}
+	}
+
+	var fields []convertField
+	for _, field := range d.ConvertFields() {
+		if isIgnoreFields(field.ColName) || field.ColName == _columnID || isPrimaryKey[field.ColName] {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// modelFieldType return the go type of field in generated model struct, same as getModelStructCode
+func (d extendTmplData) modelFieldType(field tmplField) string {
+	if d.Opt.IsEmbed {
+		switch field.ColName {
+		case columnID:
+			return "uint64"
+		case columnCreatedAt, columnUpdatedAt:
+			return "time.Time"
+		}
+	}
+	if strings.Contains(field.GoType, "time.Time") {
+		return "*time.Time"
+	}
+	if d.IsMongo() {
+		if field.Name == "ID" {
+			return goTypeOID
+		}
+		return field.GoType
+	}
+	if field.Name == "ID" {
+		if d.isCommonStyle(d.Opt.IsEmbed) {
+			return d.CrudInfo.GoType
+		}
+		return "uint64"
+	}
+	if field.rewriterField != nil {
+		switch field.rewriterField.goType {
+		case jsonTypeName, decimalTypeName, boolTypeName, boolTypeTinyName:
+			return "*" + field.rewriterField.goType
+		}
+	}
+	return field.GoType
+}
+
+// protoToGoType convert proto type to the go type generated by protoc-gen-go
+func protoToGoType(protoType string) string {
+	if strings.HasPrefix(protoType, "repeated ") {
+		return "[]" + protoToGoType(strings.TrimPrefix(protoType, "repeated "))
+	}
+	protoType = strings.TrimPrefix(protoType, "*")
+	switch protoType {
+	case "int":
+		return "int32"
+	case "uint":
+		return "uint32"
+	case "double":
+		return "float64"
+	case "float":
+		return "float32"
+	}
+	return protoType
+}
+
+// protoGoFieldName return the go field name generated by protoc-gen-go, example: user_id -> UserId
+func protoGoFieldName(name string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && isLower(name[i+1]):
+			// skip the underscore, the next letter is upper case
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && isLower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+var convertBasicTypes = map[string]bool{
+	"string": true, "bool": true, "int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "float32": true, "float64": true,
+}
+
+// newConvertField generate conversion statements according to the model type and proto type
+func newConvertField(field tmplField, pbField tmplField, modelType string, pbType string) convertField {
+	f, p := "record."+field.Name, "pb."+protoGoFieldName(pbField.JSONName)
+	valueName := customToCamel(field.ColName) + "Value"
+	pbName := pbField.JSONName
+	cast := func(toType string, v string, fromType string) string {
+		if toType == fromType {
+			return v
+		}
+		return toType + "(" + v + ")"
+	}
+
+	switch {
+	case modelType == pbType:
+		return convertField{
+			ToPB:    fmt.Sprintf("%s = %s", p, f),
+			ToModel: fmt.Sprintf("%s = %s", f, p),
+		}
+
+	case pbType == "string" && (modelType == "*time.Time" || modelType == "time.Time"):
+		toPB := fmt.Sprintf("if %s != nil {\n%s = %s.Format(time.RFC3339)\n}", f, p, f)
+		ref := "&"
+		if modelType == "time.Time" {
+			toPB = fmt.Sprintf("if !%s.IsZero() {\n%s = %s.Format(time.RFC3339)\n}", f, p, f)
+			ref = ""
+		}
+		return convertField{
+			ToPB: toPB,
+			ToModel: fmt.Sprintf("if %s != \"\" {\n%s, err := time.Parse(time.RFC3339, %s)\nif err != nil {\nreturn nil, fmt.Errorf(\"invalid %s: %%v\", err)\n}\n%s = %s%s\n}",
+				p, valueName, p, pbName, f, ref, valueName),
+		}
+
+	case field.protoEnum != "" && pbType == field.protoEnum && (modelType == "string" || modelType == "*string"):
+		return newEnumConvertField(field, f, p, modelType == "*string")
+
+	case pbType == "string" && modelType == goTypeOID:
+		return convertField{
+			ToPB: fmt.Sprintf("%s = %s.Hex()", p, f),
+			ToModel: fmt.Sprintf("if %s != \"\" {\n%s, err := primitive.ObjectIDFromHex(%s)\nif err != nil {\nreturn nil, fmt.Errorf(\"invalid %s: %%v\", err)\n}\n%s = %s\n}",
+				p, valueName, p, pbName, f, valueName),
+		}
+
+	case pbType == "string" && modelType == "*"+jsonTypeName:
+		return convertField{
+			ToPB:    fmt.Sprintf("if %s != nil {\n%s = string(*%s)\n}", f, p, f),
+			ToModel: fmt.Sprintf("if %s != \"\" {\n%s := %s(%s)\n%s = &%s\n}", p, valueName, jsonTypeName, p, f, valueName),
+		}
+
+	case pbType == "string" && modelType == "*"+decimalTypeName:
+		return convertField{
+			ToPB: fmt.Sprintf("if %s != nil {\n%s = %s.String()\n}", f, p, f),
+			ToModel: fmt.Sprintf("if %s != \"\" {\n%s, err := decimal.NewFromString(%s)\nif err != nil {\nreturn nil, fmt.Errorf(\"invalid %s: %%v\", err)\n}\n%s = &%s\n}",
+				p, valueName, p, pbName, f, valueName),
+		}
+
+	case pbType == "bool" && (modelType == "*"+boolTypeName || modelType == "*"+boolTypeTinyName):
+		return convertField{
+			ToPB:    fmt.Sprintf("if %s != nil {\n%s = bool(*%s)\n}", f, p, f),
+			ToModel: fmt.Sprintf("%s := %s(%s)\n%s = &%s", valueName, modelType[1:], p, f, valueName),
+		}
+
+	case pbType == "string" && modelType == "[]byte":
+		return convertField{
+			ToPB:    fmt.Sprintf("%s = string(%s)", p, f),
+			ToModel: fmt.Sprintf("%s = []byte(%s)", f, p),
+		}
+
+	case convertBasicTypes[modelType] && convertBasicTypes[pbType]:
+		return convertField{
+			ToPB:    fmt.Sprintf("%s = %s", p, cast(pbType, f, modelType)),
+			ToModel: fmt.Sprintf("%s = %s", f, cast(modelType, p, pbType)),
+		}
+
+	case strings.HasPrefix(modelType, "*") && convertBasicTypes[modelType[1:]] && convertBasicTypes[pbType]:
+		return convertField{
+			ToPB:    fmt.Sprintf("if %s != nil {\n%s = %s\n}", f, p, cast(pbType, "*"+f, modelType[1:])),
+			ToModel: fmt.Sprintf("%s := %s\n%s = &%s", valueName, cast(modelType[1:], p, pbType), f, valueName),
+		}
+	}
+
+	todo := fmt.Sprintf("// todo: convert %s (%s) and %s (%s) manually", f, modelType, p, pbType)
+	return convertField{ToPB: todo, ToModel: todo}
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// enumConst a value of enum column, the go constant and the proto enum value
+type enumConst struct {
+	Name      string // go constant name, example: UserOrderStatusPending
+	Value     string // column value, example: pending
+	ProtoName string // proto enum value name, example: USER_ORDER_STATUS_PENDING
+}
+
+// enumValueWord convert the enum value to snake case word which can be used in identifiers,
+// example: 'in-progress' -> in_progress
+func enumValueWord(value string) string {
+	words := strings.FieldsFunc(strings.ToLower(value), func(c rune) bool {
+		return !('a' <= c && c <= 'z') && !('0' <= c && c <= '9')
+	})
+	if len(words) == 0 {
+		return "empty"
+	}
+	return strings.Join(words, "_")
+}
+
+// EnumConsts return the constants of the values of enum column, it is empty if the option enum const is disabled
+func (t tmplField) EnumConsts() []enumConst {
+	if t.protoEnum == "" {
+		return nil
+	}
+	prefix := strings.ToUpper(customToSnake(t.protoEnum))
+	consts := make([]enumConst, 0, len(t.enumValues))
+	for _, value := range t.enumValues {
+		word := enumValueWord(value)
+		consts = append(consts, enumConst{
+			Name:      t.protoEnum + toCamel(word),
+			Value:     value,
+			ProtoName: prefix + "_" + strings.ToUpper(word),
+		})
+	}
+	return consts
+}
+
+// EnumFields return the enum columns which generate the go constants and proto enum
+func (d extendTmplData) EnumFields() []tmplField {
+	var fields []tmplField
+	for _, field := range d.Fields {
+		if field.protoEnum != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// newEnumConvertField generate the conversion statements between the enum column and proto enum by the constants,
+// the unknown column value is converted to UNSPECIFIED, and UNSPECIFIED is converted to the zero value
+func newEnumConvertField(field tmplField, f string, p string, isPointer bool) convertField {
+	pbEnumValue := func(name string) string {
+		return "serverNameExampleV1." + field.protoEnum + "_" + name
+	}
+
+	var toPB, toModel strings.Builder
+	value := f
+	if isPointer {
+		value = "*" + f
+		fmt.Fprintf(&toPB, "if %s != nil {\n", f)
+	}
+	fmt.Fprintf(&toPB, "switch %s {\n", value)
+	fmt.Fprintf(&toModel, "switch %s {\n", p)
+	for _, c := range field.EnumConsts() {
+		fmt.Fprintf(&toPB, "case model.%s:\n%s = %s\n", c.Name, p, pbEnumValue(c.ProtoName))
+		if isPointer {
+			fmt.Fprintf(&toModel, "case %s:\n%sValue := model.%s\n%s = &%sValue\n",
+				pbEnumValue(c.ProtoName), customToCamel(field.ColName), c.Name, f, customToCamel(field.ColName))
+		} else {
+			fmt.Fprintf(&toModel, "case %s:\n%s = model.%s\n", pbEnumValue(c.ProtoName), f, c.Name)
+		}
+	}
+	fmt.Fprintf(&toPB, "default:\n%s = %s\n}", p, pbEnumValue(strings.ToUpper(customToSnake(field.protoEnum))+"_UNSPECIFIED"))
+	if isPointer {
+		toPB.WriteString("\n}")
+	}
+	toModel.WriteString("}")
+
+	return convertField{ToPB: toPB.String(), ToModel: toModel.String()}
+}
+
+// getProtoEnumCode generate the proto3 enums of the enum columns, the zero value is UNSPECIFIED
+func getProtoEnumCode(fields []tmplField) string {
+	var code strings.Builder
+	for _, field := range fields {
+		if field.protoEnum == "" {
+			continue
+		}
+		fmt.Fprintf(&code, "enum %s {\n", field.protoEnum)
+		fmt.Fprintf(&code, "  %s_UNSPECIFIED = 0;\n", strings.ToUpper(customToSnake(field.protoEnum)))
+		for i, c := range field.EnumConsts() {
+			fmt.Fprintf(&code, "  %s = %d; // %s\n", c.ProtoName, i+1, c.Value)
+		}
+		code.WriteString("}\n\n")
+	}
+	return code.String()
+}
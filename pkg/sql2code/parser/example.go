@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// exampleHintRegexp match the example hint in column comment, example: 'status, example: active', 'e.g. 18'
+var exampleHintRegexp = regexp.MustCompile(`(?i)(?:\bexample\s*[:：=]|\be\.g\.)\s*([^,;，；\s]+)`)
+
+// getCommentExample return the example value hinted in column comment
+func getCommentExample(comment string) string {
+	if m := exampleHintRegexp.FindStringSubmatch(comment); m != nil {
+		return strings.Trim(m[1], `'"`)
+	}
+	return ""
+}
+
+// ExampleTag return the swagger example tag of handler struct field, example: ` example:"active"`
+func (t tmplField) ExampleTag() string {
+	if t.example == "" || strings.ContainsAny(t.example, "`\"") {
+		return ""
+	}
+	return ` example:"` + t.example + `"`
+}
+
+// protoExampleOption return the openapiv2_field option with example in json format
+func (t tmplField) protoExampleOption() string {
+	if t.example == "" {
+		return ""
+	}
+
+	value := t.example
+	switch t.GoType {
+	case "string":
+		value = strconv.Quote(value)
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return ""
+		}
+	case "int32", "int64", "uint32", "uint64", "sint32", "sint64", "fixed32", "fixed64", "sfixed32", "sfixed64", "float", "double":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return ""
+		}
+	default:
+		return ""
+	}
+	return "(grpc.gateway.protoc_gen_openapiv2.options.openapiv2_field) = {example: " + strconv.Quote(value) + "}"
+}
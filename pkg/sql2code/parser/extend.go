@@ -0,0 +1,816 @@
+package parser
+
+import (
+	"fmt"
+	"go/format"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/zhufuyi/sqlparser/dependency/mysql"
+	"github.com/zhufuyi/sqlparser/dependency/types"
+)
+
+// extendTmplData template data for optional codes, the options that enable the codes are also available in template
+type extendTmplData struct {
+	tmplData
+	Opt options
+}
+
+// IsMongo return true if the db driver is mongodb
+func (d extendTmplData) IsMongo() bool {
+	return d.DBDriver == DBDriverMongodb
+}
+
+// HasField return true if the table has the field, name is in pascal case, example: ID
+func (d extendTmplData) HasField(name string) bool {
+	for _, field := range d.Fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PKParam return the variable name of primary key, example: id
+func (d extendTmplData) PKParam() string {
+	if d.IsMongo() || d.CrudInfo == nil {
+		return columnID
+	}
+	return d.CrudInfo.ColumnNameCamelFCL
+}
+
+// PKGoType return the go type of primary key used in dao and handler
+func (d extendTmplData) PKGoType() string {
+	if d.IsMongo() {
+		return "string"
+	}
+	if d.CrudInfo == nil || d.CrudInfo.isIDPrimaryKey() {
+		return "uint64"
+	}
+	return d.CrudInfo.GoType
+}
+
+// PKMethodSuffix return the suffix of method name, example: ByID
+func (d extendTmplData) PKMethodSuffix() string {
+	if d.IsMongo() || d.CrudInfo == nil {
+		return "ByID"
+	}
+	return "By" + d.CrudInfo.ColumnNameCamel
+}
+
+// ProtoPKField return the primary key field code of proto request message
+func (d extendTmplData) ProtoPKField() string {
+	protoType, name, rule := "uint64", columnID, "[(validate.rules).uint64.gt = 0"
+	if d.IsMongo() {
+		protoType, rule = "string", "[(validate.rules).string.min_len = 6"
+	} else if d.CrudInfo != nil && !d.CrudInfo.isIDPrimaryKey() {
+		protoType, name = d.CrudInfo.ProtoType, d.CrudInfo.ColumnNameCamelFCL
+		if protoType == "string" {
+			rule = "[(validate.rules).string.min_len = 1"
+		} else {
+			rule = fmt.Sprintf("[(validate.rules).%s.gt = 0", protoType)
+		}
+	}
+	if d.Opt.IsWebProto {
+		rule += fmt.Sprintf(`, (tagger.tags) = "uri:\"%s\""`, name)
+	}
+	return fmt.Sprintf("%s %s = 1 %s];", protoType, name, rule)
+}
+
+// PKFromPathCode return the code of getting primary key from gin path parameter, abort if invalid
+func (d extendTmplData) PKFromPathCode() string {
+	name := d.PKParam()
+	var parse string
+	switch goType := d.PKGoType(); goType {
+	case "string":
+		return fmt.Sprintf(`	%s := c.Param("%s")
+	if %s == "" {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+`, name, name, name)
+	case "uint64", "uint32", "uint":
+		parse = fmt.Sprintf(`	v, parseErr := strconv.ParseUint(c.Param("%s"), 10, 64)
+	%s := %s(v)`, name, name, goType)
+	default:
+		parse = fmt.Sprintf(`	v, parseErr := strconv.ParseInt(c.Param("%s"), 10, 64)
+	%s := %s(v)`, name, name, goType)
+	}
+	return parse + `
+	if parseErr != nil || ` + name + ` == 0 {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+`
+}
+
+// HasColumn return true if the table has the column, name is the raw column name, example: deleted_at
+func (d extendTmplData) HasColumn(name string) bool {
+	for _, field := range d.Fields {
+		if field.ColName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DistinctFields return the fields specified by distinct columns option, the go type is dereferenced
+func (d extendTmplData) DistinctFields() []tmplField {
+	var fields []tmplField
+	for _, colName := range d.Opt.DistinctColumns {
+		for _, field := range d.Fields {
+			if field.ColName != colName || field.IsPrimaryKey {
+				continue
+			}
+			field.GoType = strings.TrimPrefix(field.GoType, "*")
+			fields = append(fields, field)
+			break
+		}
+	}
+	return fields
+}
+
+// SearchFields return the string fields specified by search columns option
+func (d extendTmplData) SearchFields() []tmplField {
+	var fields []tmplField
+	for _, colName := range d.Opt.SearchColumns {
+		for _, field := range d.Fields {
+			if field.ColName == colName && strings.TrimPrefix(field.GoType, "*") == "string" {
+				fields = append(fields, field)
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// SearchWhereCode return the code of LIKE clause matching the keyword against the search columns, example:
+// db = db.Where("(name LIKE ? OR email LIKE ?)", like, like)
+func (d extendTmplData) SearchWhereCode() string {
+	fields := d.SearchFields()
+	exprs := make([]string, 0, len(fields))
+	args := make([]string, 0, len(fields))
+	for _, field := range fields {
+		exprs = append(exprs, field.ColName+" LIKE ?")
+		args = append(args, "like")
+	}
+	return fmt.Sprintf("db = db.Where(%q, %s)", "("+strings.Join(exprs, " OR ")+")", strings.Join(args, ", "))
+}
+
+// joinField the key column of a join table
+type joinField struct {
+	Name    string // field name, example: UserID
+	ColName string // column name, example: user_id
+	GoType  string // go type, the pointer is dereferenced
+	Param   string // parameter name, example: userID
+}
+
+// JoinFields return the two key fields of the join table in the order of the primary key
+func (d extendTmplData) JoinFields() []joinField {
+	var fields []joinField
+	for _, colName := range d.JoinKeys {
+		for _, field := range d.Fields {
+			if field.ColName == colName {
+				fields = append(fields, joinField{
+					Name:    field.Name,
+					ColName: field.ColName,
+					GoType:  strings.TrimPrefix(field.GoType, "*"),
+					Param:   customToCamel(field.ColName),
+				})
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// CSVFields return the fields exported to csv, the soft delete column is ignored
+func (d extendTmplData) CSVFields() []tmplField {
+	var fields []tmplField
+	for _, field := range d.Fields {
+		if field.ColName != columnDeletedAt {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// FilterFields return the fields of typed filter, the go type is not a pointer,
+// the fields whose type is not comparable in query conditions are ignored
+func (d extendTmplData) FilterFields() []tmplField {
+	var fields []tmplField
+	for _, field := range d.Fields {
+		if field.ColName == columnDeletedAt {
+			continue
+		}
+		goType := strings.TrimPrefix(d.modelFieldType(field), "*")
+		switch {
+		case goType == goTypeOID:
+			goType = "string"
+		case goType == "time.Time" || convertBasicTypes[goType]:
+		default:
+			continue
+		}
+		field.GoType = goType
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// UpdateFieldsCode return the code which collects the non-zero fields to update, reused from the dao template
+func (d extendTmplData) UpdateFieldsCode() (string, error) {
+	return getUpdateFieldsCode(d.tmplData, d.Opt.IsEmbed, d.IsAudit(), d.Opt.IsExplicitNullUpdates)
+}
+
+// isSoftDelete return true if the table supports soft delete
+func (d extendTmplData) isSoftDelete() bool {
+	return d.Opt.IsEmbed || d.HasColumn(columnDeletedAt)
+}
+
+// hasUpdatedAt return true if the table has updated_at timestamp column
+func (d extendTmplData) hasUpdatedAt() bool {
+	return d.Opt.IsEmbed || d.IsAudit() || d.HasColumn(columnUpdatedAt)
+}
+
+// IsAudit return true if the model embeds the audit struct
+func (d extendTmplData) IsAudit() bool {
+	return d.Opt.IsAuditFields && !d.IsMongo()
+}
+
+// AuditEmbed return the name of the audit struct embedded in model, example: Audit2
+func (d extendTmplData) AuditEmbed() string {
+	return getAuditEmbed(d.Opt.IsEmbed, d.Opt.JSONNamedType)
+}
+
+// PKFieldName return the primary key field name of model, example: ID
+func (d extendTmplData) PKFieldName() string {
+	if d.IsMongo() || d.CrudInfo == nil || d.CrudInfo.isIDPrimaryKey() {
+		return "ID"
+	}
+	for _, field := range d.Fields {
+		if field.ColName == d.CrudInfo.ColumnName {
+			return field.Name
+		}
+	}
+	return d.CrudInfo.ColumnNameCamel
+}
+
+// PKZeroValue return the zero value of primary key go type, example: 0
+func (d extendTmplData) PKZeroValue() string {
+	if d.PKGoType() == "string" {
+		return `""`
+	}
+	return "0"
+}
+
+// CursorParseCode return the code of parsing the decoded cursor bytes raw to the primary key
+func (d extendTmplData) CursorParseCode() string {
+	switch goType := d.PKGoType(); goType {
+	case "string":
+		return "\treturn string(raw), nil"
+	case "uint64", "uint32", "uint":
+		return fmt.Sprintf(`	v, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor: %%w", err)
+	}
+	return %s(v), nil`, goType)
+	default:
+		return fmt.Sprintf(`	v, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor: %%w", err)
+	}
+	return %s(v), nil`, goType)
+	}
+}
+
+// UpdatedAtUnixNanoCode return the code of getting unix nano of updated_at from the record variable
+func (d extendTmplData) UpdatedAtUnixNanoCode(record string) string {
+	goType := "time.Time"
+	for _, field := range d.Fields {
+		if field.ColName == columnUpdatedAt {
+			goType = field.GoType
+			break
+		}
+	}
+	switch goType {
+	case "*time.Time":
+		return fmt.Sprintf(`var updatedAt int64
+	if %s.UpdatedAt != nil {
+		updatedAt = %s.UpdatedAt.UnixNano()
+	}`, record, record)
+	case "sql.NullTime":
+		return fmt.Sprintf("updatedAt := %s.UpdatedAt.Time.UnixNano()", record)
+	}
+	return fmt.Sprintf("updatedAt := %s.UpdatedAt.UnixNano()", record)
+}
+
+// MetricsName return the table name used in metric names, example: foo_bar
+func (d extendTmplData) MetricsName() string {
+	return customToSnake(d.TableName)
+}
+
+// FixtureItems return the field assignments of the sample fixtures, primary key, timestamp and
+// non-basic type fields are skipped, the value is the example value of the column if it is valid
+func (d extendTmplData) FixtureItems() [][]string {
+	const size = 2
+	items := make([][]string, size)
+	for _, field := range d.Fields {
+		if field.IsPrimaryKey || field.Name == "ID" || field.ColName == columnCreatedAt ||
+			field.ColName == columnUpdatedAt || field.ColName == columnDeletedAt {
+			continue
+		}
+		goType := d.modelFieldType(field)
+		if !convertBasicTypes[goType] {
+			continue
+		}
+		for i := range items {
+			items[i] = append(items[i], field.Name+": "+fixtureValue(field, goType, i+1)+",")
+		}
+	}
+	return items
+}
+
+func fixtureValue(field tmplField, goType string, n int) string {
+	switch goType {
+	case "string":
+		if field.example != "" {
+			return strconv.Quote(field.example)
+		}
+		return strconv.Quote(field.ColName + "_" + strconv.Itoa(n))
+	case "bool":
+		if _, err := strconv.ParseBool(field.example); err == nil {
+			return field.example
+		}
+		return "true"
+	}
+	if _, err := strconv.ParseFloat(field.example, 64); err == nil {
+		return field.example
+	}
+	return strconv.Itoa(n)
+}
+
+// UpsertConflictColumns return the columns of the unique key used as the conflict target of upsert
+func (d extendTmplData) UpsertConflictColumns() []string {
+	for _, index := range d.Indexes {
+		if index.IsUnique {
+			return index.Columns
+		}
+	}
+	return nil
+}
+
+// UpsertUpdateColumns return the columns updated when upsert conflicts, the primary key, conflict key,
+// created_at and deleted_at columns are excluded
+func (d extendTmplData) UpsertUpdateColumns() []string {
+	isConflictColumn := make(map[string]bool)
+	for _, col := range d.UpsertConflictColumns() {
+		isConflictColumn[col] = true
+	}
+
+	var columns []string
+	for _, field := range d.Fields {
+		if field.IsPrimaryKey || field.ColName == columnID || isConflictColumn[field.ColName] ||
+			field.ColName == columnCreatedAt || field.ColName == columnDeletedAt {
+			continue
+		}
+		columns = append(columns, field.ColName)
+	}
+	return columns
+}
+
+// isReadOnly return true if the model is a view or the models of the db driver are read-only,
+// the write methods are not generated
+func (d extendTmplData) isReadOnly() bool {
+	return d.Opt.isView || d.DBDriver == DBDriverClickHouse
+}
+
+// IsSortValidation return true if the sort columns of list params are validated against the column whitelist
+func (d extendTmplData) IsSortValidation() bool {
+	return d.Opt.IsSortValidation && !d.Opt.NoColumnWhitelist
+}
+
+// hasCreatedAt return true if the table has created_at timestamp column
+func (d extendTmplData) hasCreatedAt() bool {
+	return d.Opt.IsEmbed || d.IsAudit() || d.HasColumn(columnCreatedAt)
+}
+
+// WrapErr wrap the error expression according to the options, used to return errors in the generated code
+func (d extendTmplData) WrapErr(expr string) string {
+	if d.Opt.IsTypedErrors {
+		expr = "convert" + d.TableName + "Error(" + expr + ")"
+	}
+	if d.Opt.IsDaoErrorWrapping {
+		// the operation is replaced by the layer and method name after the code is generated
+		expr = "wrap" + d.TableName + "Error(\"" + errorOperationMark + "\", " + expr + ")"
+	}
+	return expr
+}
+
+// errorOperationMark the mark of the operation of wrapped errors in the generated code
+const errorOperationMark = "__operation__"
+
+// funcNameRegexp match the name of the function or method declared in the line
+var funcNameRegexp = regexp.MustCompile(`^func (?:\([^)]*\) )?(\w+)`)
+
+// replaceErrorOperation replace the operation marks with the layer and the name of the enclosing function,
+// example: dao.GetByID
+func replaceErrorOperation(code string, layer string) string {
+	if !strings.Contains(code, errorOperationMark) {
+		return code
+	}
+	lines := strings.Split(code, "\n")
+	funcName := ""
+	for i, line := range lines {
+		if match := funcNameRegexp.FindStringSubmatch(line); match != nil {
+			funcName = match[1]
+		}
+		lines[i] = strings.ReplaceAll(line, errorOperationMark, layer+"."+funcName)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getExtendCodes generate the optional codes enabled by options, the key of the map is code type
+func getExtendCodes(data tmplData, opt options) (map[string]string, error) {
+	eData := extendTmplData{tmplData: data, Opt: opt}
+	isWritable := !eData.isReadOnly()
+	isRestore := opt.IsRestore && eData.isSoftDelete() && isWritable
+	isFieldMask := opt.IsFieldMask && isWritable
+	isDistinct := len(eData.DistinctFields()) > 0
+	isCountByGroup := opt.IsCountByGroup && !opt.NoColumnWhitelist
+	isSortValidation := eData.IsSortValidation()
+	isSearch := len(eData.SearchFields()) > 0
+	isJoinTable := len(eData.JoinFields()) == 2 && isWritable && !eData.IsMongo() && !opt.isSQLORM()
+	// the standard crud methods are generated to replace the methods of dao template when the options change them
+	isStandardCRUD := (isSortValidation || opt.IsTypedErrors || opt.IsDaoErrorWrapping || opt.QueryTimeout > 0) && isWritable && !isJoinTable && !opt.isSQLORM()
+	// mongodb has no row locking and sqlite does not support SELECT ... FOR UPDATE, it locks the whole database
+	isForUpdate := opt.IsForUpdate && isWritable && !eData.IsMongo() && eData.DBDriver != DBDriverSqlite
+
+	codeTmpls := []struct {
+		codeType string
+		tmpls    []extendTmpl
+	}{
+		{CodeTypeGRPCRegister, []extendTmpl{
+			{opt.IsGRPCRegister, "grpcRegisterTmpl", grpcRegisterTmpl},
+		}},
+		{CodeTypeGRPCClient, []extendTmpl{
+			{opt.ClientRetries > 0, "grpcClientRetryTmpl", grpcClientRetryTmpl},
+		}},
+		{CodeTypeConvert, []extendTmpl{
+			{opt.IsConvertPB, "convertTmpl", convertTmpl},
+		}},
+		{CodeTypeServiceExtend, []extendTmpl{
+			{isFieldMask, "serviceUpdateMaskTmpl", serviceUpdateMaskTmpl},
+		}},
+		{CodeTypeError, []extendTmpl{
+			{opt.IsTypedErrors, "errorTmpl", errorTmpl},
+			{opt.IsDaoErrorWrapping, "errorWrapTmpl", errorWrapTmpl},
+			{opt.IsGatewayErrorHandler, "gatewayErrorHandlerTmpl", gatewayErrorHandlerTmpl},
+		}},
+		{CodeTypeDAOExtend, []extendTmpl{
+			{opt.QueryTimeout > 0, "daoQueryTimeoutTmpl", daoQueryTimeoutTmpl},
+			{eData.isReadOnly(), "daoReadOnlyTmpl", daoReadOnlyTmpl},
+			{opt.IsCreateBatch && isWritable, "daoCreateBatchTmpl", daoCreateBatchTmpl},
+			{isRestore, "daoRestoreTmpl", daoRestoreTmpl},
+			{isDistinct, "daoDistinctTmpl", daoDistinctTmpl},
+			{opt.IsRWSplit && !eData.IsMongo() && isWritable, "daoRWSplitTmpl", daoRWSplitTmpl},
+			{opt.IsTypedFilter, "daoFilterTmpl", daoFilterTmpl},
+			{opt.IsListByTimeRange && eData.hasCreatedAt(), "daoListByTimeRangeTmpl", daoListByTimeRangeTmpl},
+			{isFieldMask, "daoUpdateColumnsTmpl", daoUpdateColumnsTmpl},
+			{isCountByGroup, "daoCountByGroupTmpl", daoCountByGroupTmpl},
+			{opt.IsRepository && !eData.IsMongo() && isWritable, "daoRepositoryTmpl", daoRepositoryTmpl},
+			{opt.IsDaoMetrics && !eData.IsMongo() && isWritable, "daoMetricsTmpl", daoMetricsTmpl},
+			{opt.IsSeed && isWritable, "daoSeedTmpl", daoSeedTmpl},
+			{isForUpdate, "daoForUpdateTmpl", daoForUpdateTmpl},
+			{isSearch, "daoSearchTmpl", daoSearchTmpl},
+			{isJoinTable, "daoAssociationTmpl", daoAssociationTmpl},
+			{opt.IsCursorList && !isJoinTable, "daoCursorListTmpl", daoCursorListTmpl},
+			{opt.IsUpdateByCondition && isWritable, "daoUpdateByConditionTmpl", daoUpdateByConditionTmpl},
+			{opt.IsUpsert && isWritable && !eData.IsMongo() && len(eData.UpsertConflictColumns()) > 0, "daoUpsertTmpl", daoUpsertTmpl},
+			{isSortValidation, "daoSortValidationTmpl", daoSortValidationTmpl},
+			{isStandardCRUD, "daoCRUDTmpl", daoCRUDTmpl},
+			{opt.isSQLORM() && isWritable && !eData.IsMongo(), "daoSQLTmpl", daoSQLTmpl},
+		}},
+		{CodeTypeHandlerExtend, []extendTmpl{
+			{isRestore, "handlerRestoreTmpl", handlerRestoreTmpl},
+			{opt.IsListNDJSON, "handlerListNDJSONTmpl", handlerListNDJSONTmpl},
+			{isSearch, "handlerSearchTmpl", handlerSearchTmpl},
+			{opt.IsCSVExport, "handlerCSVExportTmpl", handlerCSVExportTmpl},
+			{opt.MaxBodyBytes > 0 && isWritable, "handlerMaxBodyBytesTmpl", handlerMaxBodyBytesTmpl},
+			{opt.IsFieldErrors && isWritable, "handlerFieldErrorsTmpl", handlerFieldErrorsTmpl},
+			{opt.IsETag && eData.hasUpdatedAt(), "handlerETagTmpl", handlerETagTmpl},
+			{opt.IsAuthorizationHook && isWritable, "handlerAuthorizeTmpl", handlerAuthorizeTmpl},
+		}},
+	}
+
+	codes := make(map[string]string)
+	for _, ct := range codeTmpls {
+		code, err := executeExtendTmpls(eData, ct.tmpls)
+		if err != nil {
+			return nil, err
+		}
+		if code != "" {
+			code = replaceErrorOperation(code, strings.TrimSuffix(ct.codeType, "_extend"))
+			if opt.Package != "" && opt.Package != "model" {
+				code = modelQualifierRegexp.ReplaceAllString(code, "${1}"+opt.Package+".")
+			}
+			codes[ct.codeType] = code
+		}
+	}
+
+	return codes, nil
+}
+
+// modelQualifierRegexp match the qualifier of model types in the extended codes
+var modelQualifierRegexp = regexp.MustCompile(`(^|[^\w.])model\.`)
+
+// layerCodeTypes the code types of each layer which are complete go files except the package clause
+var layerCodeTypes = map[string][]string{
+	CodeTypeDAO:     {CodeTypeDAOExtend},
+	CodeTypeHandler: {CodeTypeHandler, CodeTypeHandlerExtend},
+	CodeTypeService: {CodeTypeServiceExtend, CodeTypeGRPCRegister},
+}
+
+// addLayerPackageClauses add the package clause of the layer to its codes, the codes are not changed if the
+// package of the layer is not set
+func addLayerPackageClauses(codes map[string]string, layerPackages map[string]string) {
+	for layer, pkg := range layerPackages {
+		if pkg == "" {
+			continue
+		}
+		for _, codeType := range layerCodeTypes[layer] {
+			if code, ok := codes[codeType]; ok && code != "" {
+				codes[codeType] = "package " + pkg + "\n\n" + strings.TrimLeft(code, "\n")
+			}
+		}
+	}
+}
+
+// getModelHookCode generate the gorm hooks and methods of model enabled by options, return code and import paths
+func getModelHookCode(data tmplData, opt options) (string, []string, error) {
+	eData := extendTmplData{tmplData: data, Opt: opt}
+	var codes, importPaths []string
+
+	if opt.IsUUIDHook && !opt.IsEmbed && !eData.IsMongo() {
+		for _, field := range data.Fields {
+			if !field.IsPrimaryKey || !field.IsUUID || field.GoType != "string" {
+				continue
+			}
+			code, err := executeGoTmpl(modelUUIDHookTmpl, eData)
+			if err != nil {
+				return "", nil, fmt.Errorf("modelUUIDHookTmpl error: %v", err)
+			}
+			codes = append(codes, code)
+			importPaths = append(importPaths, "github.com/google/uuid", "gorm.io/gorm")
+			break
+		}
+	}
+
+	if opt.IsCacheKey && len(data.JoinKeys) == 0 {
+		code, err := executeGoTmpl(modelCacheKeyTmpl, eData)
+		if err != nil {
+			return "", nil, fmt.Errorf("modelCacheKeyTmpl error: %v", err)
+		}
+		codes = append(codes, code)
+		if !eData.IsMongo() {
+			importPaths = append(importPaths, "fmt")
+		}
+	}
+
+	if len(eData.EnumFields()) > 0 {
+		code, err := executeGoTmpl(modelEnumConstTmpl, eData)
+		if err != nil {
+			return "", nil, fmt.Errorf("modelEnumConstTmpl error: %v", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if len(eData.MaskedFields()) > 0 {
+		code, err := executeGoTmpl(modelMaskTmpl, eData)
+		if err != nil {
+			return "", nil, fmt.Errorf("modelMaskTmpl error: %v", err)
+		}
+		codes = append(codes, code)
+		importPaths = append(importPaths, "encoding/json", "strings")
+	}
+
+	return strings.Join(codes, ""), importPaths, nil
+}
+
+// QueryTimeoutCode return the statements wrapping the context with query timeout at the beginning of dao method
+func (d extendTmplData) QueryTimeoutCode() string {
+	if d.Opt.QueryTimeout <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\tctx, cancel := with%sQueryTimeout(ctx)\n\tdefer cancel()", d.TableName)
+}
+
+// QueryTimeoutValue return the go expression of query timeout, example: 3 * time.Second
+func (d extendTmplData) QueryTimeoutValue() string {
+	return durationCode(d.Opt.QueryTimeout)
+}
+
+// ClientRetryBackoffValue return the go expression of the retry interval of grpc client, example: 100 * time.Millisecond
+func (d extendTmplData) ClientRetryBackoffValue() string {
+	return durationCode(d.Opt.ClientRetryBackoff)
+}
+
+// durationCode return the go expression of the duration
+func durationCode(d time.Duration) string {
+	switch {
+	case d%time.Second == 0:
+		return fmt.Sprintf("%d * time.Second", d/time.Second)
+	case d%time.Millisecond == 0:
+		return fmt.Sprintf("%d * time.Millisecond", d/time.Millisecond)
+	}
+	return fmt.Sprintf("time.Duration(%d)", int64(d))
+}
+
+// MaskedFields return the string fields specified by masked columns option
+func (d extendTmplData) MaskedFields() []tmplField {
+	var fields []tmplField
+	for _, colName := range d.Opt.MaskedColumns {
+		for _, field := range d.Fields {
+			if field.ColName != colName {
+				continue
+			}
+			if goType := d.modelFieldType(field); goType == "string" || goType == "*string" {
+				field.GoType = goType
+				fields = append(fields, field)
+			}
+			break
+		}
+	}
+	return fields
+}
+
+// isUUIDColumn return true if the column type is uuid or char(36)
+func isUUIDColumn(tp *types.FieldType, fieldType string) bool {
+	if strings.ToLower(fieldType) == "uuid" {
+		return true
+	}
+	if tp == nil {
+		return false
+	}
+	switch tp.Tp {
+	case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString:
+		return tp.Flen == 36
+	}
+	return false
+}
+
+// addExtendProtoCode add the rpc and messages enabled by options to the proto file code
+func addExtendProtoCode(data tmplData, opt options, protoCode string) (string, error) {
+	eData := extendTmplData{tmplData: data, Opt: opt}
+
+	protoTmpls := []struct {
+		enable      bool
+		name        string
+		rpcTmpl     *template.Template
+		messageTmpl *template.Template
+	}{
+		{opt.IsRestore && eData.isSoftDelete() && !eData.isReadOnly(), "protoRestoreTmpl", protoRestoreRPCTmpl, protoRestoreMessageTmpl},
+		{opt.IsListByTimeRange && eData.hasCreatedAt(), "protoListByTimeRangeTmpl", protoListByTimeRangeRPCTmpl, protoListByTimeRangeMessageTmpl},
+		{opt.IsCountByGroup && !opt.NoColumnWhitelist, "protoCountByGroupTmpl", protoCountByGroupRPCTmpl, protoCountByGroupMessageTmpl},
+		{len(eData.SearchFields()) > 0, "protoSearchTmpl", protoSearchRPCTmpl, protoSearchMessageTmpl},
+	}
+
+	rpcCodes, messageCodes := "", ""
+	for _, t := range protoTmpls {
+		if !t.enable {
+			continue
+		}
+		builder := strings.Builder{}
+		if err := t.rpcTmpl.Execute(&builder, eData); err != nil {
+			return "", fmt.Errorf("%s error: %v", t.name, err)
+		}
+		rpcCodes += builder.String()
+		builder.Reset()
+		if err := t.messageTmpl.Execute(&builder, eData); err != nil {
+			return "", fmt.Errorf("%s error: %v", t.name, err)
+		}
+		messageCodes += builder.String()
+	}
+	if rpcCodes != "" {
+		protoCode = insertProtoServiceRPC(protoCode, data.TName, rpcCodes) + messageCodes
+	}
+	if opt.IsFieldMask && !eData.isReadOnly() {
+		protoCode = addProtoUpdateMask(protoCode, "Update"+data.TableName+eData.PKMethodSuffix()+"Request")
+	}
+	if opt.IsWebProto && opt.IsSwaggerTags {
+		protoCode = addProtoSwaggerTags(protoCode, data.TName)
+	}
+
+	return protoCode, nil
+}
+
+// addProtoUpdateMask add the update_mask field to the update request message, and import field_mask.proto
+func addProtoUpdateMask(protoCode string, messageName string) string {
+	start := strings.Index(protoCode, "message "+messageName+" {")
+	if start < 0 {
+		return protoCode
+	}
+	end := strings.Index(protoCode[start:], "\n}")
+	if end < 0 {
+		return protoCode
+	}
+	end += start
+
+	maxNumber := 0
+	for _, line := range strings.Split(protoCode[start:end], "\n") {
+		_, after, ok := strings.Cut(line, " = ")
+		if !ok {
+			continue
+		}
+		var number int
+		if _, err := fmt.Sscanf(after, "%d", &number); err == nil && number > maxNumber {
+			maxNumber = number
+		}
+	}
+	field := fmt.Sprintf("\n\tgoogle.protobuf.FieldMask update_mask = %d; // the fields to be updated, all fields are updated if empty", maxNumber+1)
+	protoCode = protoCode[:end] + field + protoCode[end:]
+
+	importCode := `import "google/protobuf/field_mask.proto";`
+	if !strings.Contains(protoCode, importCode) {
+		validateImport := `import "validate/validate.proto";`
+		protoCode = strings.Replace(protoCode, validateImport, validateImport+"\n"+importCode, 1)
+	}
+	return protoCode
+}
+
+// addProtoSwaggerTags add the openapiv2_operation tags option after the http option of each rpc in service block
+func addProtoSwaggerTags(protoCode string, tag string) string {
+	start := strings.Index(protoCode, "service "+tag+" {")
+	if start < 0 {
+		return protoCode
+	}
+	end := strings.Index(protoCode[start:], "\n}")
+	if end < 0 {
+		return protoCode
+	}
+	end += start
+
+	httpOptionEnd := "\n    };\n  }"
+	tagsOption := fmt.Sprintf(`
+    };
+    option (grpc.gateway.protoc_gen_openapiv2.options.openapiv2_operation) = {
+      tags: "%s"
+    };
+  }`, tag)
+	service := strings.ReplaceAll(protoCode[start:end], httpOptionEnd, tagsOption)
+
+	return protoCode[:start] + service + protoCode[end:]
+}
+
+// insertProtoServiceRPC insert rpc code at the end of the service block
+func insertProtoServiceRPC(protoCode string, serviceName string, rpcCode string) string {
+	start := strings.Index(protoCode, "service "+serviceName+" {")
+	if start < 0 {
+		return protoCode
+	}
+	end := strings.Index(protoCode[start:], "\n}")
+	if end < 0 {
+		return protoCode
+	}
+	end += start
+	return protoCode[:end] + "\n" + strings.TrimRight(rpcCode, "\n") + protoCode[end:]
+}
+
+// extendTmpl go code template which is executed only when enabled by options
+type extendTmpl struct {
+	enable bool
+	name   string
+	tmpl   *template.Template
+}
+
+// executeExtendTmpls execute the enabled templates in order and join the codes
+func executeExtendTmpls(data extendTmplData, tmpls []extendTmpl) (string, error) {
+	codes := make([]string, 0, len(tmpls))
+	for _, t := range tmpls {
+		if !t.enable {
+			continue
+		}
+		code, err := executeGoTmpl(t.tmpl, data)
+		if err != nil {
+			return "", fmt.Errorf("%s error: %v", t.name, err)
+		}
+		codes = append(codes, code)
+	}
+	return strings.Join(codes, "\n"), nil
+}
+
+// executeGoTmpl execute template and format the output as go source code
+func executeGoTmpl(tmpl *template.Template, data interface{}) (string, error) {
+	builder := strings.Builder{}
+	err := tmpl.Execute(&builder, data)
+	if err != nil {
+		return "", fmt.Errorf("tmpl.Execute error: %v", err)
+	}
+	code, err := format.Source([]byte(builder.String()))
+	if err != nil {
+		return "", fmt.Errorf("format.Source error: %v", err)
+	}
+	return string(code), nil
+}
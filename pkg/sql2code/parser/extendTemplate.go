@@ -0,0 +1,2267 @@
+package parser
+
+import (
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// nolint
+var (
+	// grpcRegisterTmpl register the table's grpc service to the server, reflection is optional
+	grpcRegisterTmpl    *template.Template
+	grpcRegisterTmplRaw = `
+// Register{{.TableName}}Server register {{.TName}} service to grpc server
+func Register{{.TableName}}Server(server *grpc.Server, svc serverNameExampleV1.{{.TableName}}Server) {
+	serverNameExampleV1.Register{{.TableName}}Server(server, svc)
+{{- if .Opt.IsGRPCReflection}}
+	// reflection service can only be registered once for each grpc server
+	reflection.Register(server)
+{{- end}}
+}
+`
+
+	// daoQueryTimeoutTmpl the default query timeout and the context wrapper used by dao methods
+	daoQueryTimeoutTmpl    *template.Template
+	daoQueryTimeoutTmplRaw = `
+// default{{.TableName}}QueryTimeout default timeout of each query in dao methods
+var default{{.TableName}}QueryTimeout = {{.QueryTimeoutValue}}
+
+type {{.TName}}QueryTimeoutKey struct{}
+
+// With{{.TableName}}QueryTimeout set the query timeout of a call, it overrides the default timeout,
+// timeout <= 0 means no timeout
+func With{{.TableName}}QueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, {{.TName}}QueryTimeoutKey{}, timeout)
+}
+
+// with{{.TableName}}QueryTimeout wrap the context with the query timeout before executing
+func with{{.TableName}}QueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := default{{.TableName}}QueryTimeout
+	if v, ok := ctx.Value({{.TName}}QueryTimeoutKey{}).(time.Duration); ok {
+		timeout = v
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+`
+
+	// daoCreateBatchTmpl batch create records with chunked inserts
+	daoCreateBatchTmpl    *template.Template
+	daoCreateBatchTmplRaw = `
+// default{{.TableName}}BatchSize default chunk size of CreateBatch
+const default{{.TableName}}BatchSize = 100
+
+// CreateBatch create records in batches, chunkSize <= 0 means using the default chunk size
+func (d *{{.TName}}Dao) CreateBatch(ctx context.Context, items []*model.{{.TableName}}, chunkSize int) error {
+{{- .QueryTimeoutCode}}
+	if len(items) == 0 {
+		return nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = default{{.TableName}}BatchSize
+	}
+{{- if .IsMongo}}
+
+	for start := 0; start < len(items); start += chunkSize {
+		end := start + chunkSize
+		if end > len(items) {
+			end = len(items)
+		}
+		docs := make([]interface{}, 0, end-start)
+		for _, item := range items[start:end] {
+{{- if .HasField "ID"}}
+			if item.ID.IsZero() {
+				item.ID = primitive.NewObjectID()
+			}
+{{- end}}
+			docs = append(docs, item)
+		}
+		_, err := d.collection.InsertMany(ctx, docs)
+		if err != nil {
+			return {{.WrapErr "err"}}
+		}
+	}
+	return nil
+{{- else}}
+
+	return {{.WrapErr "d.db.WithContext(ctx).CreateInBatches(items, chunkSize).Error"}}
+{{- end}}
+}
+`
+
+	// errorTmpl typed error sentinels and the driver error converter
+	errorTmpl    *template.Template
+	errorTmplRaw = `
+// typed errors of {{.TName}}, callers can use errors.Is to check them
+var (
+	// Err{{.TableName}}NotFound {{.TName}} record not found
+	Err{{.TableName}}NotFound = errors.New("{{.TName}} not found")
+	// Err{{.TableName}}AlreadyExists {{.TName}} record already exists
+	Err{{.TableName}}AlreadyExists = errors.New("{{.TName}} already exists")
+	// Err{{.TableName}}InvalidParams invalid {{.TName}} parameters
+	Err{{.TableName}}InvalidParams = errors.New("invalid {{.TName}} parameters")
+)
+
+// convert{{.TableName}}Error convert database driver errors to {{.TName}} typed errors
+func convert{{.TableName}}Error(err error) error {
+	if err == nil {
+		return nil
+	}
+{{- if .IsMongo}}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w: %v", Err{{.TableName}}NotFound, err)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %v", Err{{.TableName}}AlreadyExists, err)
+	}
+{{- else}}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %v", Err{{.TableName}}NotFound, err)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return fmt.Errorf("%w: %v", Err{{.TableName}}AlreadyExists, err)
+	}
+{{- end}}
+	return err
+}
+`
+
+	daoRestoreTmpl    *template.Template
+	daoRestoreTmplRaw = `
+// Restore{{.PKMethodSuffix}} restore a soft deleted record, clear the deleted_at column
+func (d *{{.TName}}Dao) Restore{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) error {
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+	oid, err := primitive.ObjectIDFromHex({{.PKParam}})
+	if err != nil {
+		return {{.WrapErr "err"}}
+	}
+	_, err = d.collection.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{"deleted_at": nil}})
+	return {{.WrapErr "err"}}
+{{- else}}
+	err := d.db.WithContext(ctx).Unscoped().Model(&model.{{.TableName}}{}).
+		Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).Update("deleted_at", nil).Error
+	return {{.WrapErr "err"}}
+{{- end}}
+}
+`
+
+	handlerRestoreTmpl    *template.Template
+	handlerRestoreTmplRaw = `
+// Restore{{.PKMethodSuffix}} restore a soft deleted {{.TName}}
+// @Summary Restore a soft deleted {{.TName}}
+// @Description Restores a soft deleted {{.TName}} by {{.CrudInfo.ColumnNameCamelFCL}}
+// @Tags {{.TName}}
+// @Param {{.CrudInfo.ColumnNameCamelFCL}} path string true "{{.CrudInfo.ColumnNameCamelFCL}}"
+// @Produce json
+// @Success 200 {object} types.Result{}
+// @Router /api/v1/{{.TName}}/{{"{"}}{{.CrudInfo.ColumnNameCamelFCL}}{{"}"}}/restore [post]
+// @Security BearerAuth
+func (h *{{.TName}}Handler) Restore{{.PKMethodSuffix}}(c *gin.Context) {
+{{.PKFromPathCode}}
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.Restore{{.PKMethodSuffix}}(ctx, {{.PKParam}})
+	if err != nil {
+		logger.Error("Restore{{.PKMethodSuffix}} error", logger.Err(err), logger.Any("{{.PKParam}}", {{.PKParam}}), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	response.Success(c)
+}
+`
+
+	protoRestoreRPCTmpl    *template.Template
+	protoRestoreRPCTmplRaw = `
+  // Restore a soft deleted {{.TName}} by {{.CrudInfo.ColumnNameCamelFCL}}
+  rpc Restore{{.PKMethodSuffix}}(Restore{{.TableName}}{{.PKMethodSuffix}}Request) returns (Restore{{.TableName}}{{.PKMethodSuffix}}Reply) {
+{{- if .Opt.IsWebProto}}
+    option (google.api.http) = {
+      post: "/api/v1/{{.TName}}/{{"{"}}{{.CrudInfo.ColumnNameCamelFCL}}{{"}"}}/restore"
+      body: "*"
+    };
+  }
+{{- else}}}{{end}}
+`
+
+	protoRestoreMessageTmpl    *template.Template
+	protoRestoreMessageTmplRaw = `
+message Restore{{.TableName}}{{.PKMethodSuffix}}Request {
+  {{.ProtoPKField}}
+}
+
+message Restore{{.TableName}}{{.PKMethodSuffix}}Reply {
+
+}
+`
+
+	modelUUIDHookTmpl    *template.Template
+	modelUUIDHookTmplRaw = `
+// BeforeCreate generate a uuid for the primary key if it is empty
+func (m *{{.TableName}}) BeforeCreate(tx *gorm.DB) error {
+	if m.{{.CrudInfo.ColumnNameCamel}} == "" {
+		m.{{.CrudInfo.ColumnNameCamel}} = uuid.NewString()
+	}
+{{- if .IsAudit}}
+	// the hook of the embedded audit struct is shadowed by this method
+	return m.{{.AuditEmbed}}.BeforeCreate(tx)
+{{- else}}
+	return nil
+{{- end}}
+}
+`
+
+	daoDistinctTmpl    *template.Template
+	daoDistinctTmplRaw = `
+{{- range .DistinctFields}}
+// Distinct{{.Name}} get the distinct values of column {{.ColName}}
+func (d *{{$.TName}}Dao) Distinct{{.Name}}(ctx context.Context) ([]{{.GoType}}, error) {
+{{- $.QueryTimeoutCode}}
+{{- if $.IsMongo}}
+	result, err := d.collection.Distinct(ctx, "{{.ColName}}", mgo.ExcludeDeleted(bson.M{}))
+	if err != nil {
+		return nil, {{$.WrapErr "err"}}
+	}
+	values := make([]{{.GoType}}, 0, len(result))
+	for _, v := range result {
+		if value, ok := v.({{.GoType}}); ok {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+{{- else}}
+	var values []{{.GoType}}
+	err := d.db.WithContext(ctx).Model(&model.{{$.TableName}}{}).Distinct("{{.ColName}}").Pluck("{{.ColName}}", &values).Error
+	if err != nil {
+		return nil, {{$.WrapErr "err"}}
+	}
+	return values, nil
+{{- end}}
+}
+{{end}}`
+
+	handlerListNDJSONTmpl    *template.Template
+	handlerListNDJSONTmplRaw = `
+// default{{.TableName}}StreamPageSize default page size of querying rows in ListNDJSON
+const default{{.TableName}}StreamPageSize = 100
+
+// ListNDJSON stream the list of {{.TName}} as newline-delimited JSON
+// @Summary Stream list of {{.TName}}
+// @Description Streams all {{.TName}} matching the query parameters as newline-delimited JSON, one record per line
+// @Tags {{.TName}}
+// @Accept json
+// @Produce application/x-ndjson
+// @Param data body query.Params true "query parameters"
+// @Success 200 {object} model.{{.TableName}}{}
+// @Router /api/v1/{{.TName}}/list/ndjson [post]
+// @Security BearerAuth
+func (h *{{.TName}}Handler) ListNDJSON(c *gin.Context) {
+	params := &query.Params{}
+	err := c.ShouldBindJSON(params)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	if params.Limit <= 0 {
+		params.Limit = default{{.TableName}}StreamPageSize
+	}
+
+	ctx := middleware.WrapCtx(c)
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	for {
+		records, _, err := h.iDao.GetByColumns(ctx, params)
+		if err != nil {
+			// the response header has been sent, the error can only be logged
+			logger.Error("GetByColumns error", logger.Err(err), logger.Any("params", params), middleware.GCtxRequestIDField(c))
+			return
+		}
+		for _, record := range records {
+			if err = encoder.Encode(record); err != nil {
+				logger.Warn("write ndjson error", logger.Err(err), middleware.GCtxRequestIDField(c))
+				return
+			}
+			c.Writer.Flush()
+		}
+		if len(records) < params.Limit {
+			return
+		}
+		params.Page++
+	}
+}
+`
+
+	convertTmpl    *template.Template
+	convertTmplRaw = `
+// Convert{{.TableName}}ToPB convert model.{{.TableName}} to serverNameExampleV1.{{.TableName}}
+func Convert{{.TableName}}ToPB(record *model.{{.TableName}}) *serverNameExampleV1.{{.TableName}} {
+	if record == nil {
+		return nil
+	}
+
+	pb := &serverNameExampleV1.{{.TableName}}{}
+{{- range .ConvertFields}}
+	{{.ToPB}}
+{{- end}}
+	return pb
+}
+
+// ConvertPBTo{{.TableName}} convert serverNameExampleV1.{{.TableName}} to model.{{.TableName}}
+func ConvertPBTo{{.TableName}}(pb *serverNameExampleV1.{{.TableName}}) (*model.{{.TableName}}, error) {
+	if pb == nil {
+		return nil, nil
+	}
+
+	record := &model.{{.TableName}}{}
+{{- range .ConvertFields}}
+	{{.ToModel}}
+{{- end}}
+	return record, nil
+}
+`
+
+	daoRWSplitTmpl    *template.Template
+	daoRWSplitTmplRaw = `
+// {{.TName}}RWDao read write split dao of {{.TName}}, the read methods use the replica db,
+// the write methods use the primary db
+type {{.TName}}RWDao struct {
+	db     *gorm.DB // primary db, used by write methods
+	readDB *gorm.DB // replica db, used by read methods
+}
+
+// New{{.TableName}}RWDao creating the read write split dao, readDB is nil means reading from the primary db
+func New{{.TableName}}RWDao(db *gorm.DB, readDB *gorm.DB) *{{.TName}}RWDao {
+	if readDB == nil {
+		readDB = db
+	}
+	return &{{.TName}}RWDao{db: db, readDB: readDB}
+}
+
+// Create a record in the primary db
+func (d *{{.TName}}RWDao) Create(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+	return {{.WrapErr "d.db.WithContext(ctx).Create(table).Error"}}
+}
+
+// Delete{{.PKMethodSuffix}} delete a record by {{.CrudInfo.ColumnNameCamelFCL}} in the primary db
+func (d *{{.TName}}RWDao) Delete{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) error {
+{{- .QueryTimeoutCode}}
+	err := d.db.WithContext(ctx).Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).Delete(&model.{{.TableName}}{}).Error
+	return {{.WrapErr "err"}}
+}
+
+// Update{{.PKMethodSuffix}} update the non-zero fields of a record by {{.CrudInfo.ColumnNameCamelFCL}} in the primary db
+func (d *{{.TName}}RWDao) Update{{.PKMethodSuffix}}(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+	err := d.db.WithContext(ctx).Model(&model.{{.TableName}}{}).
+		Where("{{.CrudInfo.ColumnName}} = ?", table.{{.CrudInfo.ColumnNameCamel}}).Updates(table).Error
+	return {{.WrapErr "err"}}
+}
+
+// Get{{.PKMethodSuffix}} get a record by {{.CrudInfo.ColumnNameCamelFCL}} from the replica db
+func (d *{{.TName}}RWDao) Get{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) (*model.{{.TableName}}, error) {
+{{- .QueryTimeoutCode}}
+	record := &model.{{.TableName}}{}
+	err := d.readDB.WithContext(ctx).Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).First(record).Error
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return record, nil
+}
+
+// List{{.PKMethodSuffix}}s list records by batch {{.CrudInfo.ColumnNameCamelFCL}} from the replica db
+func (d *{{.TName}}RWDao) List{{.PKMethodSuffix}}s(ctx context.Context, {{.PKParam}}s []{{.PKGoType}}) ([]*model.{{.TableName}}, error) {
+{{- .QueryTimeoutCode}}
+	var records []*model.{{.TableName}}
+	err := d.readDB.WithContext(ctx).Where("{{.CrudInfo.ColumnName}} IN (?)", {{.PKParam}}s).Find(&records).Error
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return records, nil
+}
+`
+
+	modelMaskTmpl    *template.Template
+	modelMaskTmplRaw = `
+// MarshalJSON mask the values of sensitive fields in json output
+func (m {{.TableName}}) MarshalJSON() ([]byte, error) {
+	type {{.TName}}Alias {{.TableName}}
+	v := {{.TName}}Alias(m)
+{{- range .MaskedFields}}
+{{- if eq .GoType "*string"}}
+	if v.{{.Name}} != nil {
+		masked := mask{{$.TableName}}Value(*v.{{.Name}})
+		v.{{.Name}} = &masked
+	}
+{{- else}}
+	v.{{.Name}} = mask{{$.TableName}}Value(v.{{.Name}})
+{{- end}}
+{{- end}}
+	return json.Marshal(v)
+}
+
+// mask{{.TableName}}Value keep the first and last characters, the others are replaced by *
+func mask{{.TableName}}Value(s string) string {
+	runes := []rune(s)
+	if len(runes) <= 2 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[0]) + strings.Repeat("*", len(runes)-2) + string(runes[len(runes)-1])
+}
+`
+
+	daoFilterTmpl    *template.Template
+	daoFilterTmplRaw = `
+// {{.TableName}}Filter typed filter of {{.TName}}, the nil fields are ignored, the others are joined with and
+type {{.TableName}}Filter struct {
+{{- range .FilterFields}}
+	{{.Name}} *{{.GoType}} ` + "`" + `json:"{{.JSONName}},omitempty"` + "`" + `
+{{- end}}
+}
+
+// ToConditions convert the filter to query conditions
+func (f *{{.TableName}}Filter) ToConditions() *query.Conditions {
+	columns := []query.Column{}
+{{- range .FilterFields}}
+	if f.{{.Name}} != nil {
+		columns = append(columns, query.Column{Name: "{{.ColName}}", Value: *f.{{.Name}}})
+	}
+{{- end}}
+	return &query.Conditions{Columns: columns}
+}
+`
+
+	daoListByTimeRangeTmpl    *template.Template
+	daoListByTimeRangeTmplRaw = `
+// ListByCreatedAtRange list the records whose created_at is in [from, to), order by created_at desc, page starts from 0
+func (d *{{.TName}}Dao) ListByCreatedAtRange(ctx context.Context, from time.Time, to time.Time, page int, limit int) ([]*model.{{.TableName}}, int64, error) {
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+	filter := mgo.ExcludeDeleted(bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})
+	total, err := d.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	if total == 0 {
+		return []*model.{{.TableName}}{}, 0, nil
+	}
+
+	findOpts := options.Find().SetSort(bson.M{"created_at": -1}).SetSkip(int64(page * limit)).SetLimit(int64(limit))
+	cursor, err := d.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	records := []*model.{{.TableName}}{}
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	return records, total, nil
+{{- else}}
+	var total int64
+	err := d.db.WithContext(ctx).Model(&model.{{.TableName}}{}).
+		Where("created_at >= ? AND created_at < ?", from, to).Count(&total).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	if total == 0 {
+		return []*model.{{.TableName}}{}, 0, nil
+	}
+
+	records := []*model.{{.TableName}}{}
+	err = d.db.WithContext(ctx).Where("created_at >= ? AND created_at < ?", from, to).
+		Order("created_at DESC").Offset(page * limit).Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	return records, total, nil
+{{- end}}
+}
+`
+
+	protoListByTimeRangeRPCTmpl    *template.Template
+	protoListByTimeRangeRPCTmplRaw = `
+  // List {{.TName}} whose created_at is in the time range
+  rpc ListByCreatedAtRange(List{{.TableName}}ByCreatedAtRangeRequest) returns (List{{.TableName}}ByCreatedAtRangeReply) {
+{{- if .Opt.IsWebProto}}
+    option (google.api.http) = {
+      post: "/api/v1/{{.TName}}/list/createdAtRange"
+      body: "*"
+    };
+  }
+{{- else}}}{{end}}
+`
+	protoListByTimeRangeMessageTmpl    *template.Template
+	protoListByTimeRangeMessageTmplRaw = `
+message List{{.TableName}}ByCreatedAtRangeRequest {
+  string from = 1 [(validate.rules).string.min_len = 1]; // start time in RFC3339 format, inclusive
+  string to = 2 [(validate.rules).string.min_len = 1]; // end time in RFC3339 format, exclusive
+  uint32 page = 3; // page number, starting from 0
+  uint32 limit = 4 [(validate.rules).uint32.gt = 0]; // limit size per page
+}
+
+message List{{.TableName}}ByCreatedAtRangeReply {
+  int64 total = 1;
+  repeated {{.TableName}} {{.CrudInfo.TableNamePluralCamelFCL}} = 2;
+}
+`
+
+	daoUpdateColumnsTmpl    *template.Template
+	daoUpdateColumnsTmplRaw = `
+// UpdateColumns{{.PKMethodSuffix}} update the specified columns of a record by {{.CrudInfo.ColumnNameCamelFCL}}, including zero values
+func (d *{{.TName}}Dao) UpdateColumns{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}, columns map[string]interface{}) error {
+	if len(columns) == 0 {
+		return nil
+	}
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+	oid, err := primitive.ObjectIDFromHex({{.PKParam}})
+	if err != nil {
+		return {{.WrapErr "err"}}
+	}
+	columns["updated_at"] = time.Now()
+	_, err = d.collection.UpdateOne(ctx, mgo.ExcludeDeleted(bson.M{"_id": oid}), bson.M{"$set": columns})
+	return {{.WrapErr "err"}}
+{{- else}}
+	err := d.db.WithContext(ctx).Model(&model.{{.TableName}}{}).
+		Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).Updates(columns).Error
+	return {{.WrapErr "err"}}
+{{- end}}
+}
+`
+
+	serviceUpdateMaskTmpl    *template.Template
+	serviceUpdateMaskTmplRaw = `
+// {{.TName}}UpdateMaskColumns return the columns and values of the fields in update_mask, only the masked fields
+// are updated, including zero values, all fields are updated if update_mask is empty
+func {{.TName}}UpdateMaskColumns(pb *serverNameExampleV1.Update{{.TableName}}{{.PKMethodSuffix}}Request) (map[string]interface{}, error) {
+	paths := pb.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = []string{ {{- range $i, $v := .UpdateMaskFields}}{{if $i}}, {{end}}"{{$v.PBName}}"{{end}} }
+	}
+
+	record := &model.{{.TableName}}{}
+	columns := make(map[string]interface{}, len(paths))
+	for _, path := range paths {
+		switch path {
+{{- range .UpdateMaskFields}}
+		case "{{.PBName}}":
+			{{.ToModel}}
+			columns["{{.ColName}}"] = record.{{.FieldName}}
+{{- end}}
+		default:
+			return nil, fmt.Errorf("invalid update_mask path: %s", path)
+		}
+	}
+	return columns, nil
+}
+`
+
+	// gatewayErrorHandlerTmpl grpc-gateway error handler which maps typed errors to http status
+	gatewayErrorHandlerTmpl    *template.Template
+	gatewayErrorHandlerTmplRaw = `
+// {{.TableName}}ErrorHTTPStatus return the http status of {{.TName}} typed errors, 0 if err is not a typed error
+func {{.TableName}}ErrorHTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, Err{{.TableName}}NotFound):
+		return http.StatusNotFound
+	case errors.Is(err, Err{{.TableName}}AlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, Err{{.TableName}}InvalidParams):
+		return http.StatusBadRequest
+	}
+	return 0
+}
+
+// {{.TableName}}GatewayErrorHandler grpc-gateway error handler which responds {{.TName}} typed errors with
+// the matching http status, other errors are handled by the default handler,
+// example: runtime.NewServeMux(runtime.WithErrorHandler({{.TableName}}GatewayErrorHandler))
+func {{.TableName}}GatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler,
+	w http.ResponseWriter, r *http.Request, err error) {
+	if httpStatus := {{.TableName}}ErrorHTTPStatus(err); httpStatus != 0 {
+		err = &runtime.HTTPStatusError{HTTPStatus: httpStatus, Err: err}
+	}
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+}
+`
+
+	daoRepositoryTmpl    *template.Template
+	daoRepositoryTmplRaw = `
+// {{.TableName}}Repository crud methods of {{.TName}}, services depend on it instead of the implementation
+type {{.TableName}}Repository interface {
+	Create(ctx context.Context, table *model.{{.TableName}}) error
+	Delete{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) error
+	Update{{.PKMethodSuffix}}(ctx context.Context, table *model.{{.TableName}}) error
+	Get{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) (*model.{{.TableName}}, error)
+	GetByColumns(ctx context.Context, params *query.Params) ([]*model.{{.TableName}}, int64, error)
+}
+
+var _ {{.TableName}}Repository = (*{{.TName}}Repo)(nil)
+
+// {{.TName}}Repo gorm implementation of {{.TableName}}Repository
+type {{.TName}}Repo struct {
+	db *gorm.DB
+}
+
+// New{{.TableName}}Repository create a gorm-backed {{.TName}} repository
+func New{{.TableName}}Repository(db *gorm.DB) {{.TableName}}Repository {
+	return &{{.TName}}Repo{db: db}
+}
+
+// Create a record, insert the record and the id value is written back to the table
+func (r *{{.TName}}Repo) Create(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+	return {{.WrapErr "r.db.WithContext(ctx).Create(table).Error"}}
+}
+
+// Delete{{.PKMethodSuffix}} delete a record by {{.CrudInfo.ColumnNameCamelFCL}}
+func (r *{{.TName}}Repo) Delete{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) error {
+{{- .QueryTimeoutCode}}
+	err := r.db.WithContext(ctx).Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).Delete(&model.{{.TableName}}{}).Error
+	return {{.WrapErr "err"}}
+}
+
+// Update{{.PKMethodSuffix}} update a record by {{.CrudInfo.ColumnNameCamelFCL}}, zero value fields are not updated
+func (r *{{.TName}}Repo) Update{{.PKMethodSuffix}}(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+	update := map[string]interface{}{}
+{{- .UpdateFieldsCode}}
+
+	return {{.WrapErr "r.db.WithContext(ctx).Model(table).Updates(update).Error"}}
+}
+
+// Get{{.PKMethodSuffix}} get a record by {{.CrudInfo.ColumnNameCamelFCL}}
+func (r *{{.TName}}Repo) Get{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) (*model.{{.TableName}}, error) {
+{{- .QueryTimeoutCode}}
+	record := &model.{{.TableName}}{}
+	err := r.db.WithContext(ctx).Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).First(record).Error
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return record, nil
+}
+
+// GetByColumns get paging records by column information
+func (r *{{.TName}}Repo) GetByColumns(ctx context.Context, params *query.Params) ([]*model.{{.TableName}}, int64, error) {
+{{- .QueryTimeoutCode}}
+{{- if .IsSortValidation}}
+	if err := validate{{.TableName}}Sort(params.Sort); err != nil {
+		return nil, 0, errors.New("query params error: " + err.Error())
+	}
+{{- end}}
+	queryStr, args, err := params.ConvertToGormConditions()
+	if err != nil {
+		return nil, 0, errors.New("query params error: " + err.Error())
+	}
+
+	db := r.db.WithContext(ctx).Model(&model.{{.TableName}}{})
+	if queryStr != "" {
+		db = db.Where(queryStr, args...)
+	}
+	var total int64
+	err = db.Count(&total).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	order, limit, offset := params.ConvertToPage()
+	records := []*model.{{.TableName}}{}
+	err = db.Order(order).Limit(limit).Offset(offset).Find(&records).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	return records, total, nil
+}
+`
+
+	// daoReadOnlyTmpl read-only dao of analytics models, such as clickhouse
+	daoReadOnlyTmpl    *template.Template
+	daoReadOnlyTmplRaw = `
+// {{.TName}}Dao read-only dao of {{.TName}}, the write methods are not generated for analytics models
+type {{.TName}}Dao struct {
+	db *gorm.DB
+}
+
+// New{{.TableName}}Dao creating the read-only dao
+func New{{.TableName}}Dao(db *gorm.DB) *{{.TName}}Dao {
+	return &{{.TName}}Dao{db: db}
+}
+
+// Get{{.PKMethodSuffix}} get a record by {{.CrudInfo.ColumnNameCamelFCL}}
+func (d *{{.TName}}Dao) Get{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) (*model.{{.TableName}}, error) {
+{{- .QueryTimeoutCode}}
+	record := &model.{{.TableName}}{}
+	err := d.db.WithContext(ctx).Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).First(record).Error
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return record, nil
+}
+
+// List get paging records by column information
+func (d *{{.TName}}Dao) List(ctx context.Context, params *query.Params) ([]*model.{{.TableName}}, int64, error) {
+{{- .QueryTimeoutCode}}
+{{- if .IsSortValidation}}
+	if err := validate{{.TableName}}Sort(params.Sort); err != nil {
+		return nil, 0, errors.New("query params error: " + err.Error())
+	}
+{{- end}}
+	total, err := d.Count(ctx, params)
+	if err != nil || total == 0 {
+		return nil, total, err
+	}
+
+	queryStr, args, err := params.ConvertToGormConditions()
+	if err != nil {
+		return nil, 0, errors.New("query params error: " + err.Error())
+	}
+	order, limit, offset := params.ConvertToPage()
+	records := []*model.{{.TableName}}{}
+	db := d.db.WithContext(ctx).Model(&model.{{.TableName}}{})
+	if queryStr != "" {
+		db = db.Where(queryStr, args...)
+	}
+	err = db.Order(order).Limit(limit).Offset(offset).Find(&records).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	return records, total, nil
+}
+
+// Count the number of records matching the column information
+func (d *{{.TName}}Dao) Count(ctx context.Context, params *query.Params) (int64, error) {
+{{- .QueryTimeoutCode}}
+	queryStr, args, err := params.ConvertToGormConditions()
+	if err != nil {
+		return 0, errors.New("query params error: " + err.Error())
+	}
+	db := d.db.WithContext(ctx).Model(&model.{{.TableName}}{})
+	if queryStr != "" {
+		db = db.Where(queryStr, args...)
+	}
+	var total int64
+	err = db.Count(&total).Error
+	if err != nil {
+		return 0, {{.WrapErr "err"}}
+	}
+	return total, nil
+}
+`
+
+	handlerFieldErrorsTmpl    *template.Template
+	handlerFieldErrorsTmplRaw = `
+// {{.TableName}}FieldError validation error of a request field
+type {{.TableName}}FieldError struct {
+	Field string ` + "`" + `json:"field"` + "`" + ` // field name in json
+	Rule  string ` + "`" + `json:"rule"` + "`" + `  // failed validation rule, example: required, gte
+	Param string ` + "`" + `json:"param,omitempty"` + "`" + ` // parameter of the rule, example: 0 of gte=0
+}
+
+// bind{{.TableName}}JSON bind the json body and collect every field validation error instead of the first one,
+// the field errors are empty if the request is valid, err is returned if the body can not be decoded
+func bind{{.TableName}}JSON(c *gin.Context, req interface{}) ([]{{.TableName}}FieldError, error) {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return nil, nil
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, err
+	}
+
+	fieldErrs := make([]{{.TableName}}FieldError, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		field := fieldErr.Field()
+		if sf, ok := reflect.TypeOf(req).Elem().FieldByName(fieldErr.StructField()); ok {
+			if name := strings.Split(sf.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+				field = name
+			}
+		}
+		fieldErrs = append(fieldErrs, {{.TableName}}FieldError{Field: field, Rule: fieldErr.Tag(), Param: fieldErr.Param()})
+	}
+	return fieldErrs, nil
+}
+
+// CreateWithFieldErrors create a {{.TName}}, all field validation errors are responded at once
+// @Summary Create a {{.TName}}
+// @Description Creates a new {{.TName}}, responds every invalid field if the request is invalid
+// @Tags {{.TName}}
+// @Accept json
+// @Produce json
+// @Param data body types.Create{{.TableName}}Request true "{{.TName}} information"
+// @Success 200 {object} types.Result{}
+// @Failure 400 {object} types.Result{data=[]{{.TableName}}FieldError}
+// @Router /api/v1/{{.TName}}/validated [post]
+// @Security BearerAuth
+func (h *{{.TName}}Handler) CreateWithFieldErrors(c *gin.Context) {
+{{- if .Opt.MaxBodyBytes}}
+	limit{{.TableName}}Body(c)
+{{- end}}
+	form := &types.Create{{.TableName}}Request{}
+	fieldErrs, err := bind{{.TableName}}JSON(c, form)
+	if err != nil {
+{{- if .Opt.MaxBodyBytes}}
+		if is{{.TableName}}BodyTooLarge(err) {
+			logger.Warn("request body too large", logger.Err(err), middleware.GCtxRequestIDField(c))
+			response.Output(c, http.StatusRequestEntityTooLarge)
+			return
+		}
+{{- end}}
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	if len(fieldErrs) > 0 {
+		logger.Warn("validate error: ", logger.Any("fieldErrors", fieldErrs), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams, fieldErrs)
+		return
+	}
+
+	record := &model.{{.TableName}}{}
+	err = copier.Copy(record, form)
+	if err != nil {
+		logger.Error("copier.Copy error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err = h.iDao.Create(ctx, record)
+	if err != nil {
+		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	response.Success(c, gin.H{"{{.CrudInfo.ColumnNameCamelFCL}}": record.{{.CrudInfo.ColumnNameCamel}}})
+}
+`
+
+	daoCountByGroupTmpl    *template.Template
+	daoCountByGroupTmplRaw = `
+// CountByGroup count the records grouped by the column, the key of map is the column value,
+// groupColumn must be in the column whitelist of model
+func (d *{{.TName}}Dao) CountByGroup(ctx context.Context, groupColumn string) (map[string]int64, error) {
+	if !model.{{.TableName}}ColumnNames[groupColumn] {
+		return nil, fmt.Errorf("invalid group column: %s", groupColumn)
+	}
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+	matchStage := bson.M{"$match": mgo.ExcludeDeleted(bson.M{})}
+	groupStage := bson.M{"$group": bson.M{"_id": "$" + groupColumn, "total": bson.M{"$sum": 1}}}
+	cursor, err := d.collection.Aggregate(ctx, []bson.M{matchStage, groupStage})
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	rows := []struct {
+		Value interface{} ` + "`" + `bson:"_id"` + "`" + `
+		Total int64       ` + "`" + `bson:"total"` + "`" + `
+	}{}
+	err = cursor.All(ctx, &rows)
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		key := ""
+		if row.Value != nil {
+			key = fmt.Sprint(row.Value)
+		}
+		counts[key] += row.Total
+	}
+	return counts, nil
+{{- else}}
+	rows := []struct {
+		Value *string ` + "`" + `gorm:"column:group_value"` + "`" + `
+		Total int64   ` + "`" + `gorm:"column:total"` + "`" + `
+	}{}
+	err := d.db.WithContext(ctx).Model(&model.{{.TableName}}{}).
+		Select(groupColumn + " AS group_value, COUNT(*) AS total").Group(groupColumn).Scan(&rows).Error
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		key := ""
+		if row.Value != nil {
+			key = *row.Value
+		}
+		counts[key] += row.Total
+	}
+	return counts, nil
+{{- end}}
+}
+`
+
+	protoCountByGroupRPCTmpl    *template.Template
+	protoCountByGroupRPCTmplRaw = `
+  // Count {{.TName}} grouped by a column
+  rpc CountByGroup(Count{{.TableName}}ByGroupRequest) returns (Count{{.TableName}}ByGroupReply) {
+{{- if .Opt.IsWebProto}}
+    option (google.api.http) = {
+      post: "/api/v1/{{.TName}}/count/group"
+      body: "*"
+    };
+  }
+{{- else}}}{{end}}
+`
+	protoCountByGroupMessageTmpl    *template.Template
+	protoCountByGroupMessageTmplRaw = `
+message Count{{.TableName}}ByGroupRequest {
+  string column = 1 [(validate.rules).string.min_len = 1]; // column name to group by
+}
+
+message Count{{.TableName}}ByGroupReply {
+  map<string, int64> counts = 1; // key is the column value, value is the number of records
+}
+`
+
+	handlerETagTmpl    *template.Template
+	handlerETagTmplRaw = `
+// {{.TName}}ETag return the etag of the record, which is the hash of {{.CrudInfo.ColumnNameCamelFCL}} and updated_at
+func {{.TName}}ETag(record *model.{{.TableName}}) string {
+	{{.UpdatedAtUnixNanoCode "record"}}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v-%d", record.{{.PKFieldName}}, updatedAt)))
+	return ` + "`" + `"` + "`" + ` + hex.EncodeToString(sum[:16]) + ` + "`" + `"` + "`" + `
+}
+
+// Get{{.PKMethodSuffix}}WithETag get a {{.TName}} detail by {{.CrudInfo.ColumnNameCamelFCL}}, it sets the ETag header and responds 304
+// if the ETag matches If-None-Match, register it in place of Get{{.PKMethodSuffix}} to let clients cache the detail
+// @Summary Get a {{.TName}} by {{.CrudInfo.ColumnNameCamelFCL}}
+// @Description Gets detailed information of a {{.TName}} specified by the given {{.CrudInfo.ColumnNameCamelFCL}}, supports If-None-Match
+// @Tags {{.TName}}
+// @Param {{.CrudInfo.ColumnNameCamelFCL}} path string true "{{.CrudInfo.ColumnNameCamelFCL}}"
+// @Param If-None-Match header string false "etag of the cached detail"
+// @Produce json
+// @Success 200 {object} types.Result{}
+// @Success 304 "not modified"
+// @Router /api/v1/{{.TName}}/{{"{"}}{{.CrudInfo.ColumnNameCamelFCL}}{{"}"}} [get]
+// @Security BearerAuth
+func (h *{{.TName}}Handler) Get{{.PKMethodSuffix}}WithETag(c *gin.Context) {
+{{.PKFromPathCode}}
+	ctx := middleware.WrapCtx(c)
+	record, err := h.iDao.Get{{.PKMethodSuffix}}(ctx, {{.PKParam}})
+	if err != nil {
+{{- if .IsMongo}}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+{{- else}}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+{{- end}}
+			logger.Warn("Get{{.PKMethodSuffix}} not found", logger.Err(err), logger.Any("{{.PKParam}}", {{.PKParam}}), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+			return
+		}
+		logger.Error("Get{{.PKMethodSuffix}} error", logger.Err(err), logger.Any("{{.PKParam}}", {{.PKParam}}), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	etag := {{.TName}}ETag(record)
+	c.Header("ETag", etag)
+	for _, match := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		if match = strings.TrimSpace(match); match == etag || match == "W/"+etag || match == "*" {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	response.Success(c, gin.H{"{{.TName}}": record})
+}
+`
+
+	daoMetricsTmpl    *template.Template
+	daoMetricsTmplRaw = `
+var (
+	// {{.TName}}DaoDuration duration of {{.TName}} dao calls
+	{{.TName}}DaoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dao_{{.MetricsName}}_duration_seconds",
+		Help:    "Duration of {{.TName}} dao calls in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+	// {{.TName}}DaoErrors number of failed {{.TName}} dao calls
+	{{.TName}}DaoErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dao_{{.MetricsName}}_errors_total",
+		Help: "Total number of failed {{.TName}} dao calls.",
+	}, []string{"operation"})
+)
+
+// observe{{.TableName}}DaoOp record the duration and error of a {{.TName}} dao call
+func observe{{.TableName}}DaoOp(operation string, start time.Time, err error) {
+	{{.TName}}DaoDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		{{.TName}}DaoErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+var _ {{.TableName}}Repository = (*{{.TName}}RepoMetrics)(nil)
+
+// {{.TName}}RepoMetrics {{.TableName}}Repository decorator which records prometheus metrics of each call
+type {{.TName}}RepoMetrics struct {
+	next {{.TableName}}Repository
+}
+
+// New{{.TableName}}RepositoryWithMetrics wrap the repository to record the duration and errors of each call
+func New{{.TableName}}RepositoryWithMetrics(next {{.TableName}}Repository) {{.TableName}}Repository {
+	return &{{.TName}}RepoMetrics{next: next}
+}
+
+// Create calls the repository and records the metrics
+func (m *{{.TName}}RepoMetrics) Create(ctx context.Context, table *model.{{.TableName}}) error {
+	start := time.Now()
+	err := m.next.Create(ctx, table)
+	observe{{.TableName}}DaoOp("Create", start, err)
+	return err
+}
+
+// Delete{{.PKMethodSuffix}} calls the repository and records the metrics
+func (m *{{.TName}}RepoMetrics) Delete{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) error {
+	start := time.Now()
+	err := m.next.Delete{{.PKMethodSuffix}}(ctx, {{.PKParam}})
+	observe{{.TableName}}DaoOp("Delete{{.PKMethodSuffix}}", start, err)
+	return err
+}
+
+// Update{{.PKMethodSuffix}} calls the repository and records the metrics
+func (m *{{.TName}}RepoMetrics) Update{{.PKMethodSuffix}}(ctx context.Context, table *model.{{.TableName}}) error {
+	start := time.Now()
+	err := m.next.Update{{.PKMethodSuffix}}(ctx, table)
+	observe{{.TableName}}DaoOp("Update{{.PKMethodSuffix}}", start, err)
+	return err
+}
+
+// Get{{.PKMethodSuffix}} calls the repository and records the metrics
+func (m *{{.TName}}RepoMetrics) Get{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) (*model.{{.TableName}}, error) {
+	start := time.Now()
+	record, err := m.next.Get{{.PKMethodSuffix}}(ctx, {{.PKParam}})
+	observe{{.TableName}}DaoOp("Get{{.PKMethodSuffix}}", start, err)
+	return record, err
+}
+
+// GetByColumns calls the repository and records the metrics
+func (m *{{.TName}}RepoMetrics) GetByColumns(ctx context.Context, params *query.Params) ([]*model.{{.TableName}}, int64, error) {
+	start := time.Now()
+	records, total, err := m.next.GetByColumns(ctx, params)
+	observe{{.TableName}}DaoOp("GetByColumns", start, err)
+	return records, total, err
+}
+`
+
+	// daoSeedTmpl seed function and sample fixtures
+	daoSeedTmpl    *template.Template
+	daoSeedTmplRaw = `
+// {{.TableName}}Fixtures sample fixtures of {{.TableName}}, inserted by Seed{{.TableName}} if no items are specified
+var {{.TableName}}Fixtures = []*model.{{.TableName}}{
+{{- range .FixtureItems}}
+	{
+{{- range .}}
+		{{.}}
+{{- end}}
+	},
+{{- end}}
+}
+
+// Seed{{.TableName}} bulk insert the fixture items for local development and tests, {{.TableName}}Fixtures is used if items is empty
+{{- if .IsMongo}}
+func Seed{{.TableName}}(collection *mongo.Collection, items []*model.{{.TableName}}) error {
+	if len(items) == 0 {
+		// copy the fixtures, the inserted items are filled with the generated primary keys
+		for _, fixture := range {{.TableName}}Fixtures {
+			item := *fixture
+			items = append(items, &item)
+		}
+	}
+	docs := make([]interface{}, 0, len(items))
+	for _, item := range items {
+{{- if .HasField "ID"}}
+		if item.ID.IsZero() {
+			item.ID = primitive.NewObjectID()
+		}
+{{- end}}
+		docs = append(docs, item)
+	}
+	_, err := collection.InsertMany(context.Background(), docs)
+	return err
+}
+{{- else}}
+func Seed{{.TableName}}(db *gorm.DB, items []*model.{{.TableName}}) error {
+	if len(items) == 0 {
+		// copy the fixtures, the inserted items are filled with the generated primary keys
+		for _, fixture := range {{.TableName}}Fixtures {
+			item := *fixture
+			items = append(items, &item)
+		}
+	}
+	return db.CreateInBatches(items, 100).Error
+}
+{{- end}}
+`
+
+	// daoForUpdateTmpl get by primary key with row locking
+	daoForUpdateTmpl    *template.Template
+	daoForUpdateTmplRaw = `
+// Get{{.PKMethodSuffix}}ForUpdate get a record by {{.CrudInfo.ColumnNameCamelFCL}} and lock the row with SELECT ... FOR UPDATE,
+// tx must be a transaction, the lock is released when the transaction is committed or rolled back
+func (d *{{.TName}}Dao) Get{{.PKMethodSuffix}}ForUpdate(ctx context.Context, tx *gorm.DB, {{.PKParam}} {{.PKGoType}}) (*model.{{.TableName}}, error) {
+{{- .QueryTimeoutCode}}
+	record := &model.{{.TableName}}{}
+	err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).First(record).Error
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return record, nil
+}
+`
+
+	// daoUpsertTmpl create or update by unique key
+	daoUpsertTmpl    *template.Template
+	daoUpsertTmplRaw = `
+// Upsert create a record, or update it if the unique key ({{range $i, $v := .UpsertConflictColumns}}{{if $i}}, {{end}}{{$v}}{{end}}) conflicts
+func (d *{{.TName}}Dao) Upsert(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+	err := d.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{ {{- range $i, $v := .UpsertConflictColumns}}{{if $i}}, {{end}}{Name: "{{$v}}"}{{end -}} },
+{{- if .UpsertUpdateColumns}}
+		DoUpdates: clause.AssignmentColumns([]string{ {{- range $i, $v := .UpsertUpdateColumns}}{{if $i}}, {{end}}"{{$v}}"{{end -}} }),
+{{- else}}
+		DoNothing: true,
+{{- end}}
+	}).Create(table).Error
+	if err != nil {
+		return {{.WrapErr "err"}}
+	}
+	return nil
+}
+`
+
+	// grpcClientRetryTmpl grpc client retrying the idempotent read methods
+	grpcClientRetryTmpl    *template.Template
+	grpcClientRetryTmplRaw = `
+const (
+	// default{{.TableName}}ClientRetryTimes retry times of the idempotent read methods
+	default{{.TableName}}ClientRetryTimes = {{.Opt.ClientRetries}}
+	// default{{.TableName}}ClientRetryBackoff interval between retries
+	default{{.TableName}}ClientRetryBackoff = {{.ClientRetryBackoffValue}}
+)
+
+// {{.TName}}RetryMethods the idempotent read methods which are retried, the key is method name
+var {{.TName}}RetryMethods = map[string]bool{
+	"Get{{.PKMethodSuffix}}": true,
+	"List":                  true,
+}
+
+// {{.TableName}}ClientRetryOption return the dial option which retries the idempotent read methods with backoff,
+// the other methods are not retried because they may not be idempotent
+func {{.TableName}}ClientRetryOption() grpc.DialOption {
+	retry := interceptor.UnaryClientRetry(
+		interceptor.WithRetryTimes(default{{.TableName}}ClientRetryTimes),
+		interceptor.WithRetryInterval(default{{.TableName}}ClientRetryBackoff),
+	)
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if {{.TName}}RetryMethods[method[strings.LastIndex(method, "/")+1:]] {
+			return retry(ctx, method, req, reply, cc, invoker, opts...)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
+
+// New{{.TableName}}Client create the {{.TName}} grpc client with the retry option, the context of each call
+// is propagated to the server, the caller should close the returned connection
+func New{{.TableName}}Client(target string, opts ...grpc.DialOption) (serverNameExampleV1.{{.TableName}}Client, *grpc.ClientConn, error) {
+	opts = append(opts, {{.TableName}}ClientRetryOption())
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return serverNameExampleV1.New{{.TableName}}Client(conn), conn, nil
+}
+`
+
+	handlerMaxBodyBytesTmpl    *template.Template
+	handlerMaxBodyBytesTmplRaw = `
+// {{.TName}}MaxBodyBytes max bytes of the request body of creating and updating {{.TName}}
+const {{.TName}}MaxBodyBytes = {{.Opt.MaxBodyBytes}}
+
+// limit{{.TableName}}Body wrap the request body with http.MaxBytesReader, reading beyond the limit returns error
+func limit{{.TableName}}Body(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, {{.TName}}MaxBodyBytes)
+}
+
+// is{{.TableName}}BodyTooLarge check if the error is caused by the request body exceeding the limit
+func is{{.TableName}}BodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// {{.TableName}}MaxBodyBytes return the middleware which limits the request body of Create and Update{{.PKMethodSuffix}},
+// the oversized request is responded with 413 before binding, example:
+//
+//	group.POST("/", {{.TableName}}MaxBodyBytes(), h.Create)
+//	group.PUT("/:{{.CrudInfo.ColumnNameCamelFCL}}", {{.TableName}}MaxBodyBytes(), h.Update{{.PKMethodSuffix}})
+func {{.TableName}}MaxBodyBytes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > {{.TName}}MaxBodyBytes {
+			logger.Warn("request body too large", logger.Int64("contentLength", c.Request.ContentLength), middleware.GCtxRequestIDField(c))
+			response.Output(c, http.StatusRequestEntityTooLarge)
+			c.Abort()
+			return
+		}
+		limit{{.TableName}}Body(c)
+		c.Next()
+	}
+}
+`
+
+	daoSearchTmpl    *template.Template
+	daoSearchTmplRaw = `
+// Search search the records whose text columns match the keyword and which match the conditions,
+// the empty keyword matches all records, order by {{.CrudInfo.ColumnName}} desc, page starts from 0
+func (d *{{.TName}}Dao) Search(ctx context.Context, keyword string, conditions *query.Conditions, page int, limit int) ([]*model.{{.TableName}}, int64, error) {
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+	filter := bson.M{}
+	if conditions != nil && len(conditions.Columns) > 0 {
+		var err error
+{{- if .Opt.NoColumnWhitelist}}
+		filter, err = conditions.ConvertToMongo()
+{{- else}}
+		filter, err = conditions.ConvertToMongo(query.WithWhitelistNames(model.{{.TableName}}ColumnNames))
+{{- end}}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if keyword != "" {
+		// requires a text index on {{range $i, $f := .SearchFields}}{{if $i}}, {{end}}{{$f.ColName}}{{end}}
+		filter["$text"] = bson.M{"$search": keyword}
+	}
+	filter = mgo.ExcludeDeleted(filter)
+
+	total, err := d.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	if total == 0 {
+		return []*model.{{.TableName}}{}, 0, nil
+	}
+
+	findOpts := options.Find().SetSort(bson.M{"_id": -1}).SetSkip(int64(page * limit)).SetLimit(int64(limit))
+	cursor, err := d.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	records := []*model.{{.TableName}}{}
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	return records, total, nil
+{{- else}}
+	db := d.db.WithContext(ctx).Model(&model.{{.TableName}}{})
+	if conditions != nil && len(conditions.Columns) > 0 {
+{{- if .Opt.NoColumnWhitelist}}
+		queryStr, args, err := conditions.ConvertToGorm()
+{{- else}}
+		queryStr, args, err := conditions.ConvertToGorm(query.WithWhitelistNames(model.{{.TableName}}ColumnNames))
+{{- end}}
+		if err != nil {
+			return nil, 0, err
+		}
+		db = db.Where(queryStr, args...)
+	}
+	if keyword != "" {
+		like := "%" + strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(keyword) + "%"
+		{{.SearchWhereCode}}
+	}
+
+	var total int64
+	err := db.Count(&total).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	if total == 0 {
+		return []*model.{{.TableName}}{}, 0, nil
+	}
+
+	records := []*model.{{.TableName}}{}
+	err = db.Order("{{.CrudInfo.ColumnName}} DESC").Offset(page * limit).Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	return records, total, nil
+{{- end}}
+}
+`
+
+	handlerSearchTmpl    *template.Template
+	handlerSearchTmplRaw = `
+// Search{{.TableName}}Request request of searching {{.TName}}
+type Search{{.TableName}}Request struct {
+	Keyword    string           ` + "`" + `json:"keyword"` + "`" + `    // matched against {{range $i, $f := .SearchFields}}{{if $i}}, {{end}}{{$f.ColName}}{{end}}
+	Conditions query.Conditions ` + "`" + `json:"conditions"` + "`" + ` // structured filters joined with the keyword
+	Page       int              ` + "`" + `json:"page" binding:"gte=0"` + "`" + ` // page number, starting from 0
+	Limit      int              ` + "`" + `json:"limit" binding:"gt=0"` + "`" + ` // limit size per page
+}
+
+// Search search {{.TName}} by keyword and conditions
+// @Summary Search {{.TName}}
+// @Description Searches {{.TName}} whose text columns match the keyword and which match the conditions
+// @Tags {{.TName}}
+// @Accept json
+// @Produce json
+// @Param data body Search{{.TableName}}Request true "keyword and conditions"
+// @Success 200 {object} types.Result{}
+// @Router /api/v1/{{.TName}}/search [post]
+// @Security BearerAuth
+func (h *{{.TName}}Handler) Search(c *gin.Context) {
+	form := &Search{{.TableName}}Request{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	records, total, err := h.iDao.Search(ctx, form.Keyword, &form.Conditions, form.Page, form.Limit)
+	if err != nil {
+		logger.Error("Search error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"{{.CrudInfo.TableNamePluralCamelFCL}}": records,
+		"total": total,
+	})
+}
+`
+
+	protoSearchRPCTmpl    *template.Template
+	protoSearchRPCTmplRaw = `
+  // Search {{.TName}} by keyword and conditions
+  rpc Search(Search{{.TableName}}Request) returns (Search{{.TableName}}Reply) {
+{{- if .Opt.IsWebProto}}
+    option (google.api.http) = {
+      post: "/api/v1/{{.TName}}/search"
+      body: "*"
+    };
+  }
+{{- else}}}{{end}}
+`
+	protoSearchMessageTmpl    *template.Template
+	protoSearchMessageTmplRaw = `
+message Search{{.TableName}}Request {
+  string keyword = 1; // matched against {{range $i, $f := .SearchFields}}{{if $i}}, {{end}}{{$f.ColName}}{{end}}
+  api.types.Conditions conditions = 2; // structured filters joined with the keyword
+  uint32 page = 3; // page number, starting from 0
+  uint32 limit = 4 [(validate.rules).uint32.gt = 0]; // limit size per page
+}
+
+message Search{{.TableName}}Reply {
+  int64 total = 1;
+  repeated {{.TableName}} {{.CrudInfo.TableNamePluralCamelFCL}} = 2;
+}
+`
+
+	handlerCSVExportTmpl    *template.Template
+	handlerCSVExportTmplRaw = `
+// default{{.TableName}}ExportPageSize page size of querying rows in Export{{.TableName}}CSV
+const default{{.TableName}}ExportPageSize = 500
+
+// {{.TName}}CSVHeader header row of the exported csv, the column names
+var {{.TName}}CSVHeader = []string{ {{- range $i, $f := .CSVFields}}{{if $i}}, {{end}}"{{$f.ColName}}"{{end -}} }
+
+// {{.TName}}CSVRow convert the record to a csv row in the order of {{.TName}}CSVHeader
+func {{.TName}}CSVRow(record *model.{{.TableName}}) []string {
+	return []string{
+{{- range .CSVFields}}
+		{{$.TName}}CSVValue(record.{{.Name}}),
+{{- end}}
+	}
+}
+
+// {{.TName}}CSVValue format the value of a csv cell, nil pointer is empty, time is in RFC3339 format
+func {{.TName}}CSVValue(v interface{}) string {
+	switch val := v.(type) {
+	case time.Time:
+		return val.Format(time.RFC3339)
+	case interface{ Hex() string }:
+		return val.Hex()
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ""
+		}
+		return {{.TName}}CSVValue(rv.Elem().Interface())
+	}
+	return fmt.Sprint(v)
+}
+
+// Export{{.TableName}}CSV export the {{.TName}} matching the conditions as csv
+// @Summary Export {{.TName}} as csv
+// @Description Streams the {{.TName}} matching the conditions as csv, the first row is the column names
+// @Tags {{.TName}}
+// @Accept json
+// @Produce text/csv
+// @Param data body query.Conditions true "query conditions, empty columns export all rows"
+// @Success 200 {string} string "csv file"
+// @Router /api/v1/{{.TName}}/export/csv [post]
+// @Security BearerAuth
+func (h *{{.TName}}Handler) Export{{.TableName}}CSV(c *gin.Context) {
+	form := &query.Conditions{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	if len(form.Columns) > 0 {
+		if err = form.CheckValid(); err != nil {
+			logger.Warn("Parameters error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.InvalidParams)
+			return
+		}
+	}
+
+	ctx := middleware.WrapCtx(c)
+	params := &query.Params{Page: 0, Limit: default{{.TableName}}ExportPageSize, Columns: form.Columns}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", ` + "`" + `attachment; filename="{{.RawTableName}}.csv"` + "`" + `)
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write({{.TName}}CSVHeader)
+	for {
+		records, _, err := h.iDao.GetByColumns(ctx, params)
+		if err != nil {
+			// the response header has been sent, the error can only be logged
+			logger.Error("GetByColumns error", logger.Err(err), logger.Any("params", params), middleware.GCtxRequestIDField(c))
+			return
+		}
+		for _, record := range records {
+			_ = writer.Write({{.TName}}CSVRow(record))
+		}
+		writer.Flush()
+		if err = writer.Error(); err != nil {
+			logger.Warn("write csv error", logger.Err(err), middleware.GCtxRequestIDField(c))
+			return
+		}
+		if len(records) < params.Limit {
+			return
+		}
+		params.Page++
+	}
+}
+`
+
+	daoAssociationTmpl    *template.Template
+	daoAssociationTmplRaw = `{{$a := index .JoinFields 0}}{{$b := index .JoinFields 1}}
+// AddAssociation add the association of {{$a.ColName}} and {{$b.ColName}}, adding an existing association is ignored
+func (d *{{.TName}}Dao) AddAssociation(ctx context.Context, {{$a.Param}} {{$a.GoType}}, {{$b.Param}} {{$b.GoType}}) error {
+{{- .QueryTimeoutCode}}
+	record := &model.{{.TableName}}{ {{- $a.Name}}: {{$a.Param}}, {{$b.Name}}: {{$b.Param -}} }
+	err := d.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error
+	if err != nil {
+		return {{.WrapErr "err"}}
+	}
+	return nil
+}
+
+// RemoveAssociation remove the association of {{$a.ColName}} and {{$b.ColName}}, the record is deleted permanently
+// so that it can be added again
+func (d *{{.TName}}Dao) RemoveAssociation(ctx context.Context, {{$a.Param}} {{$a.GoType}}, {{$b.Param}} {{$b.GoType}}) error {
+{{- .QueryTimeoutCode}}
+	err := d.db.WithContext(ctx).Unscoped().Where("{{$a.ColName}} = ? AND {{$b.ColName}} = ?", {{$a.Param}}, {{$b.Param}}).
+		Delete(&model.{{.TableName}}{}).Error
+	if err != nil {
+		return {{.WrapErr "err"}}
+	}
+	return nil
+}
+
+// ListAssociatedBy{{$a.Name}} list the {{$b.ColName}} values associated with the {{$a.ColName}}
+func (d *{{.TName}}Dao) ListAssociatedBy{{$a.Name}}(ctx context.Context, {{$a.Param}} {{$a.GoType}}) ([]{{$b.GoType}}, error) {
+{{- .QueryTimeoutCode}}
+	values := []{{$b.GoType}}{}
+	err := d.db.WithContext(ctx).Model(&model.{{.TableName}}{}).Where("{{$a.ColName}} = ?", {{$a.Param}}).
+		Order("{{$b.ColName}}").Pluck("{{$b.ColName}}", &values).Error
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return values, nil
+}
+
+// ListAssociatedBy{{$b.Name}} list the {{$a.ColName}} values associated with the {{$b.ColName}}
+func (d *{{.TName}}Dao) ListAssociatedBy{{$b.Name}}(ctx context.Context, {{$b.Param}} {{$b.GoType}}) ([]{{$a.GoType}}, error) {
+{{- .QueryTimeoutCode}}
+	values := []{{$a.GoType}}{}
+	err := d.db.WithContext(ctx).Model(&model.{{.TableName}}{}).Where("{{$b.ColName}} = ?", {{$b.Param}}).
+		Order("{{$a.ColName}}").Pluck("{{$a.ColName}}", &values).Error
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return values, nil
+}
+`
+
+	daoCursorListTmpl    *template.Template
+	daoCursorListTmplRaw = `
+// encode{{.TableName}}Cursor encode the {{.CrudInfo.ColumnNameCamelFCL}} of the last record to an opaque cursor
+func encode{{.TableName}}Cursor({{.PKParam}} {{.PKGoType}}) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprint({{.PKParam}})))
+}
+
+// decode{{.TableName}}Cursor decode the cursor to the {{.CrudInfo.ColumnNameCamelFCL}} of the last record
+func decode{{.TableName}}Cursor(cursor string) ({{.PKGoType}}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return {{.PKZeroValue}}, fmt.Errorf("invalid cursor: %w", err)
+	}
+{{.CursorParseCode}}
+}
+
+// List list the records after the cursor, order by {{.CrudInfo.ColumnName}} desc, the cursor of the first page is empty,
+// the returned nextCursor is empty if there are no more records
+func (d *{{.TName}}Dao) List(ctx context.Context, cursor string, limit int) ([]*model.{{.TableName}}, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid limit %d", limit)
+	}
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+	filter := bson.M{}
+	if cursor != "" {
+		id, err := decode{{.TableName}}Cursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor: %w", err)
+		}
+		filter["_id"] = bson.M{"$lt": oid}
+	}
+	filter = mgo.ExcludeDeleted(filter)
+
+	// query one more record to know whether there is a next page
+	findOpts := options.Find().SetSort(bson.M{"_id": -1}).SetLimit(int64(limit + 1))
+	cur, err := d.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, "", {{.WrapErr "err"}}
+	}
+	records := []*model.{{.TableName}}{}
+	err = cur.All(ctx, &records)
+	if err != nil {
+		return nil, "", {{.WrapErr "err"}}
+	}
+
+	nextCursor := ""
+	if len(records) > limit {
+		records = records[:limit]
+		nextCursor = encode{{.TableName}}Cursor(records[limit-1].ID.Hex())
+	}
+	return records, nextCursor, nil
+{{- else}}
+	db := d.db.WithContext(ctx)
+	if cursor != "" {
+		{{.PKParam}}, err := decode{{.TableName}}Cursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		db = db.Where("{{.CrudInfo.ColumnName}} < ?", {{.PKParam}})
+	}
+
+	// query one more record to know whether there is a next page
+	records := []*model.{{.TableName}}{}
+	err := db.Order("{{.CrudInfo.ColumnName}} DESC").Limit(limit + 1).Find(&records).Error
+	if err != nil {
+		return nil, "", {{.WrapErr "err"}}
+	}
+
+	nextCursor := ""
+	if len(records) > limit {
+		records = records[:limit]
+		nextCursor = encode{{.TableName}}Cursor(records[limit-1].{{.PKFieldName}})
+	}
+	return records, nextCursor, nil
+{{- end}}
+}
+`
+
+	// errorWrapTmpl add the operation to the errors returned by the dao methods as context
+	errorWrapTmpl    *template.Template
+	errorWrapTmplRaw = `
+// wrap{{.TableName}}Error add the operation to the error as context, example: dao.GetByID: record not found,
+// the wrapped error can be checked by errors.Is and errors.As, nil is returned if err is nil
+func wrap{{.TableName}}Error(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", operation, err)
+}
+`
+
+	daoUpdateByConditionTmpl    *template.Template
+	daoUpdateByConditionTmplRaw = `
+// UpdateByCondition update the specified columns of the records matching the conditions, including zero values,
+// return the number of updated records, the empty conditions are rejected to avoid updating all records
+func (d *{{.TName}}Dao) UpdateByCondition(ctx context.Context, conditions *query.Conditions, fields map[string]interface{}) (int64, error) {
+	if conditions == nil || len(conditions.Columns) == 0 {
+		return 0, errors.New("conditions cannot be empty")
+	}
+	if len(fields) == 0 {
+		return 0, nil
+	}
+{{- if not .Opt.NoColumnWhitelist}}
+	for column := range fields {
+		if !model.{{.TableName}}ColumnNames[column] {
+			return 0, fmt.Errorf("column %s is not allowed to update", column)
+		}
+	}
+{{- end}}
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+{{- if .Opt.NoColumnWhitelist}}
+	filter, err := conditions.ConvertToMongo()
+{{- else}}
+	filter, err := conditions.ConvertToMongo(query.WithWhitelistNames(model.{{.TableName}}ColumnNames))
+{{- end}}
+	if err != nil {
+		return 0, err
+	}
+	fields["updated_at"] = time.Now()
+	result, err := d.collection.UpdateMany(ctx, mgo.ExcludeDeleted(filter), bson.M{"$set": fields})
+	if err != nil {
+		return 0, {{.WrapErr "err"}}
+	}
+	return result.ModifiedCount, nil
+{{- else}}
+{{- if .Opt.NoColumnWhitelist}}
+	queryStr, args, err := conditions.ConvertToGorm()
+{{- else}}
+	queryStr, args, err := conditions.ConvertToGorm(query.WithWhitelistNames(model.{{.TableName}}ColumnNames))
+{{- end}}
+	if err != nil {
+		return 0, err
+	}
+	result := d.db.WithContext(ctx).Model(&model.{{.TableName}}{}).Where(queryStr, args...).Updates(fields)
+	if result.Error != nil {
+		return 0, {{.WrapErr "result.Error"}}
+	}
+	return result.RowsAffected, nil
+{{- end}}
+}
+`
+
+	handlerAuthorizeTmpl    *template.Template
+	handlerAuthorizeTmplRaw = `
+// authorize{{.TableName}} check whether the identity of claims is allowed to mutate the {{.TName}} of {{.PKParam}},
+// it is called before updating or deleting, action is "update" or "delete", claims is nil if the request is
+// not authenticated by jwt, a non-nil error responds forbidden
+func (h *{{.TName}}Handler) authorize{{.TableName}}(c *gin.Context, action string, {{.PKParam}} {{.PKGoType}}, claims *jwt.Claims) error {
+	// TODO: implement the resource level authorization, example: only the owner can mutate the record
+	//
+	//	record, err := h.iDao.Get{{.PKMethodSuffix}}(middleware.WrapCtx(c), {{.PKParam}})
+	//	if err != nil {
+	//		return err
+	//	}
+	//	if claims == nil || record.Owner != claims.UID {
+	//		return errors.New("permission denied")
+	//	}
+	return nil
+}
+
+// Update{{.PKMethodSuffix}}WithAuthorization update a {{.TName}} by {{.CrudInfo.ColumnNameCamelFCL}} after authorize{{.TableName}} allows it,
+// register it in place of Update{{.PKMethodSuffix}}
+// @Summary Update a {{.TName}} by {{.CrudInfo.ColumnNameCamelFCL}}
+// @Description Updates the specified {{.TName}} by given {{.CrudInfo.ColumnNameCamelFCL}}, responds forbidden if it is not authorized
+// @Tags {{.TName}}
+// @Accept json
+// @Produce json
+// @Param {{.CrudInfo.ColumnNameCamelFCL}} path string true "{{.CrudInfo.ColumnNameCamelFCL}}"
+// @Param data body types.Update{{.TableName}}{{.PKMethodSuffix}}Request true "{{.TName}} information"
+// @Success 200 {object} types.Result{}
+// @Router /api/v1/{{.TName}}/{{"{"}}{{.CrudInfo.ColumnNameCamelFCL}}{{"}"}} [put]
+// @Security BearerAuth
+func (h *{{.TName}}Handler) Update{{.PKMethodSuffix}}WithAuthorization(c *gin.Context) {
+{{.PKFromPathCode}}
+{{- if .Opt.MaxBodyBytes}}
+	limit{{.TableName}}Body(c)
+{{- end}}
+	form := &types.Update{{.TableName}}{{.PKMethodSuffix}}Request{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	form.{{.PKFieldName}} = {{.PKParam}}
+
+	claims, _ := middleware.GetClaims(c)
+	if err = h.authorize{{.TableName}}(c, "update", {{.PKParam}}, claims); err != nil {
+		logger.Warn("authorize{{.TableName}} error", logger.Err(err), logger.Any("{{.PKParam}}", {{.PKParam}}), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Forbidden)
+		return
+	}
+
+	record := &model.{{.TableName}}{}
+	err = copier.Copy(record, form)
+	if err != nil {
+		logger.Error("copier.Copy error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err = h.iDao.Update{{.PKMethodSuffix}}(ctx, record)
+	if err != nil {
+		logger.Error("Update{{.PKMethodSuffix}} error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	response.Success(c)
+}
+
+// Delete{{.PKMethodSuffix}}WithAuthorization delete a {{.TName}} by {{.CrudInfo.ColumnNameCamelFCL}} after authorize{{.TableName}} allows it,
+// register it in place of Delete{{.PKMethodSuffix}}
+// @Summary Delete a {{.TName}} by {{.CrudInfo.ColumnNameCamelFCL}}
+// @Description Deletes a {{.TName}} by {{.CrudInfo.ColumnNameCamelFCL}}, responds forbidden if it is not authorized
+// @Tags {{.TName}}
+// @Param {{.CrudInfo.ColumnNameCamelFCL}} path string true "{{.CrudInfo.ColumnNameCamelFCL}}"
+// @Produce json
+// @Success 200 {object} types.Result{}
+// @Router /api/v1/{{.TName}}/{{"{"}}{{.CrudInfo.ColumnNameCamelFCL}}{{"}"}} [delete]
+// @Security BearerAuth
+func (h *{{.TName}}Handler) Delete{{.PKMethodSuffix}}WithAuthorization(c *gin.Context) {
+{{.PKFromPathCode}}
+	claims, _ := middleware.GetClaims(c)
+	if err := h.authorize{{.TableName}}(c, "delete", {{.PKParam}}, claims); err != nil {
+		logger.Warn("authorize{{.TableName}} error", logger.Err(err), logger.Any("{{.PKParam}}", {{.PKParam}}), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.Forbidden)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	err := h.iDao.Delete{{.PKMethodSuffix}}(ctx, {{.PKParam}})
+	if err != nil {
+		logger.Error("Delete{{.PKMethodSuffix}} error", logger.Err(err), logger.Any("{{.PKParam}}", {{.PKParam}}), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	response.Success(c)
+}
+`
+
+	modelCacheKeyTmpl    *template.Template
+	modelCacheKeyTmplRaw = `
+// CacheKey return the cache key of the record, it is stable for the same primary key, example: {{.RawTableName}}:1
+func (m *{{.TableName}}) CacheKey() string {
+{{- if .IsMongo}}
+	return "{{.RawTableName}}:" + m.ID.Hex()
+{{- else}}
+	return fmt.Sprintf("{{.RawTableName}}:%v", m.{{.PKFieldName}})
+{{- end}}
+}
+`
+
+	modelEnumConstTmpl    *template.Template
+	modelEnumConstTmplRaw = `{{range .EnumFields}}
+// values of column {{.ColName}}
+const (
+{{- range .EnumConsts}}
+	{{.Name}} = {{printf "%q" .Value}}
+{{- end}}
+)
+{{end}}`
+
+	daoSortValidationTmpl    *template.Template
+	daoSortValidationTmplRaw = `
+// validate{{.TableName}}Sort check the sort columns are in the column whitelist of model to prevent order by injection,
+// multiple columns are separated by comma, the column prefixed with - means descending order
+func validate{{.TableName}}Sort(sort string) error {
+	for _, column := range strings.Split(sort, ",") {
+		column = strings.TrimPrefix(strings.TrimSpace(column), "-")
+		if column == "" {
+			continue
+		}
+		if !model.{{.TableName}}ColumnNames[column] {
+			return fmt.Errorf("invalid sort column: %s", column)
+		}
+	}
+	return nil
+}
+`
+
+	// daoCRUDTmpl the standard crud methods of dao, they replace the methods of the same name in the dao template
+	// when the options change them
+	daoCRUDTmpl    *template.Template
+	daoCRUDTmplRaw = `
+// Create a record, insert the record and the id value is written back to the table
+func (d *{{.TName}}Dao) Create(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+{{- if .HasField "ID"}}
+	if table.ID.IsZero() {
+		table.ID = primitive.NewObjectID()
+	}
+{{- end}}
+	_, err := d.collection.InsertOne(ctx, table)
+	return {{.WrapErr "err"}}
+{{- else}}
+	return {{.WrapErr "d.db.WithContext(ctx).Create(table).Error"}}
+{{- end}}
+}
+
+// Delete{{.PKMethodSuffix}} delete a record by {{.CrudInfo.ColumnNameCamelFCL}}
+func (d *{{.TName}}Dao) Delete{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) error {
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+	oid, err := primitive.ObjectIDFromHex({{.PKParam}})
+	if err != nil {
+		return {{.WrapErr "err"}}
+	}
+{{- if or .Opt.IsEmbed (.HasColumn "deleted_at")}}
+	_, err = d.collection.UpdateOne(ctx, mgo.ExcludeDeleted(bson.M{"_id": oid}), bson.M{"$set": bson.M{"deleted_at": time.Now()}})
+{{- else}}
+	_, err = d.collection.DeleteOne(ctx, bson.M{"_id": oid})
+{{- end}}
+	return {{.WrapErr "err"}}
+{{- else}}
+	err := d.db.WithContext(ctx).Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).Delete(&model.{{.TableName}}{}).Error
+	return {{.WrapErr "err"}}
+{{- end}}
+}
+
+// Update{{.PKMethodSuffix}} update a record by {{.CrudInfo.ColumnNameCamelFCL}}, zero value fields are not updated
+func (d *{{.TName}}Dao) Update{{.PKMethodSuffix}}(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+{{- if .IsMongo}}
+	update := bson.M{}
+{{- .UpdateFieldsCode}}
+{{- if or .Opt.IsEmbed (.HasColumn "updated_at")}}
+	update["updated_at"] = time.Now()
+{{- end}}
+
+	_, err := d.collection.UpdateOne(ctx, mgo.ExcludeDeleted(bson.M{"_id": table.ID}), bson.M{"$set": update})
+	return {{.WrapErr "err"}}
+{{- else}}
+	update := map[string]interface{}{}
+{{- .UpdateFieldsCode}}
+
+	return {{.WrapErr "d.db.WithContext(ctx).Model(table).Updates(update).Error"}}
+{{- end}}
+}
+
+// Get{{.PKMethodSuffix}} get a record by {{.CrudInfo.ColumnNameCamelFCL}}
+func (d *{{.TName}}Dao) Get{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) (*model.{{.TableName}}, error) {
+{{- .QueryTimeoutCode}}
+	record := &model.{{.TableName}}{}
+{{- if .IsMongo}}
+	oid, err := primitive.ObjectIDFromHex({{.PKParam}})
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	err = d.collection.FindOne(ctx, mgo.ExcludeDeleted(bson.M{"_id": oid})).Decode(record)
+{{- else}}
+	err := d.db.WithContext(ctx).Where("{{.CrudInfo.ColumnName}} = ?", {{.PKParam}}).First(record).Error
+{{- end}}
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return record, nil
+}
+
+// GetByColumns get paging records by column information
+func (d *{{.TName}}Dao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.{{.TableName}}, int64, error) {
+{{- .QueryTimeoutCode}}
+{{- if .IsSortValidation}}
+	if err := validate{{.TableName}}Sort(params.Sort); err != nil {
+		return nil, 0, errors.New("query params error: " + err.Error())
+	}
+{{- end}}
+{{- if .IsMongo}}
+	filter, err := params.ConvertToMongoFilter()
+	if err != nil {
+		return nil, 0, errors.New("query params error: " + err.Error())
+	}
+	filter = mgo.ExcludeDeleted(filter)
+	total, err := d.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	sort, limit, skip := params.ConvertToPage()
+	findOpts := options.Find().SetSort(sort).SetLimit(int64(limit)).SetSkip(int64(skip))
+	cursor, err := d.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	records := []*model.{{.TableName}}{}
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	return records, total, nil
+{{- else}}
+	queryStr, args, err := params.ConvertToGormConditions()
+	if err != nil {
+		return nil, 0, errors.New("query params error: " + err.Error())
+	}
+
+	db := d.db.WithContext(ctx).Model(&model.{{.TableName}}{})
+	if queryStr != "" {
+		db = db.Where(queryStr, args...)
+	}
+	var total int64
+	err = db.Count(&total).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	order, limit, offset := params.ConvertToPage()
+	records := []*model.{{.TableName}}{}
+	err = db.Order(order).Limit(limit).Offset(offset).Find(&records).Error
+	if err != nil {
+		return nil, 0, {{.WrapErr "err"}}
+	}
+	return records, total, nil
+{{- end}}
+}
+`
+
+	// daoSQLTmpl dao of models without orm, the queries are hand-written
+	daoSQLTmpl    *template.Template
+	daoSQLTmplRaw = `{{$q := .SQLQueries}}{{$db := "sql"}}{{if .IsSqlx}}{{$db = "sqlx"}}{{end}}
+// {{.TName}}Dao dao of {{.TName}} using {{if .IsSqlx}}sqlx{{else}}database/sql{{end}} with hand-written queries
+type {{.TName}}Dao struct {
+	db *{{$db}}.DB
+}
+
+// New{{.TableName}}Dao creating the dao
+func New{{.TableName}}Dao(db *{{$db}}.DB) *{{.TName}}Dao {
+	return &{{.TName}}Dao{db: db}
+}
+
+// Create a record{{if $q.AutoIncrementField}}, the auto increment {{$q.AutoIncrementField}} is written back to the table{{end}}
+func (d *{{.TName}}Dao) Create(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+{{- if $q.IsInsertNow}}
+	now := time.Now()
+{{- end}}
+{{- if $q.IsReturning}}
+	err := d.db.QueryRowContext(ctx, "{{$q.Insert}}", {{$q.InsertArgs}}).Scan(&table.{{$q.AutoIncrementField}})
+	return {{.WrapErr "err"}}
+{{- else if $q.AutoIncrementField}}
+	result, err := d.db.ExecContext(ctx, "{{$q.Insert}}", {{$q.InsertArgs}})
+	if err != nil {
+		return {{.WrapErr "err"}}
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return {{.WrapErr "err"}}
+	}
+	table.{{$q.AutoIncrementField}} = {{$q.AutoIncrementType}}(id)
+	return nil
+{{- else}}
+	_, err := d.db.ExecContext(ctx, "{{$q.Insert}}", {{$q.InsertArgs}})
+	return {{.WrapErr "err"}}
+{{- end}}
+}
+
+// Delete{{.PKMethodSuffix}} {{if .HasColumn "deleted_at"}}soft {{end}}delete a record by {{.CrudInfo.ColumnNameCamelFCL}}
+func (d *{{.TName}}Dao) Delete{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) error {
+{{- .QueryTimeoutCode}}
+	_, err := d.db.ExecContext(ctx, "{{$q.Delete}}", {{$q.DeleteArgs}})
+	return {{.WrapErr "err"}}
+}
+
+// Update{{.PKMethodSuffix}} update the columns of a record by {{.CrudInfo.ColumnNameCamelFCL}}, the zero value fields are updated too,
+// created_at is not updated
+func (d *{{.TName}}Dao) Update{{.PKMethodSuffix}}(ctx context.Context, table *model.{{.TableName}}) error {
+{{- .QueryTimeoutCode}}
+{{- if $q.IsUpdateNow}}
+	now := time.Now()
+{{- end}}
+	_, err := d.db.ExecContext(ctx, "{{$q.Update}}", {{$q.UpdateArgs}})
+	return {{.WrapErr "err"}}
+}
+
+// Get{{.PKMethodSuffix}} get a record by {{.CrudInfo.ColumnNameCamelFCL}}
+func (d *{{.TName}}Dao) Get{{.PKMethodSuffix}}(ctx context.Context, {{.PKParam}} {{.PKGoType}}) (*model.{{.TableName}}, error) {
+{{- .QueryTimeoutCode}}
+	record := &model.{{.TableName}}{}
+{{- if .IsSqlx}}
+	err := d.db.GetContext(ctx, record, "{{$q.Get}}", {{.PKParam}})
+{{- else}}
+	err := d.db.QueryRowContext(ctx, "{{$q.Get}}", {{.PKParam}}).Scan({{$q.ScanArgs}})
+{{- end}}
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return record, nil
+}
+
+// List get paging records ordered by {{.CrudInfo.ColumnNameCamelFCL}} descending, page starts from 0
+func (d *{{.TName}}Dao) List(ctx context.Context, page int, limit int) ([]*model.{{.TableName}}, error) {
+{{- .QueryTimeoutCode}}
+	records := []*model.{{.TableName}}{}
+{{- if .IsSqlx}}
+	err := d.db.SelectContext(ctx, &records, "{{$q.List}}", limit, page*limit)
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return records, nil
+{{- else}}
+	rows, err := d.db.QueryContext(ctx, "{{$q.List}}", limit, page*limit)
+	if err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record := &model.{{.TableName}}{}
+		err = rows.Scan({{$q.ScanArgs}})
+		if err != nil {
+			return nil, {{.WrapErr "err"}}
+		}
+		records = append(records, record)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, {{.WrapErr "err"}}
+	}
+	return records, nil
+{{- end}}
+}
+`
+
+	extendTmplParseOnce sync.Once
+)
+
+func initExtendTemplate() {
+	extendTmplParseOnce.Do(func() {
+		var err, errSum error
+
+		grpcRegisterTmpl, err = template.New("grpcRegister").Parse(grpcRegisterTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "grpcRegisterTmplRaw:"+err.Error())
+		}
+		daoCreateBatchTmpl, err = template.New("daoCreateBatch").Parse(daoCreateBatchTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoCreateBatchTmplRaw:"+err.Error())
+		}
+		errorTmpl, err = template.New("error").Parse(errorTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "errorTmplRaw:"+err.Error())
+		}
+		daoRestoreTmpl, err = template.New("daoRestore").Parse(daoRestoreTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoRestoreTmplRaw:"+err.Error())
+		}
+		handlerRestoreTmpl, err = template.New("handlerRestore").Parse(handlerRestoreTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "handlerRestoreTmplRaw:"+err.Error())
+		}
+		protoRestoreRPCTmpl, err = template.New("protoRestoreRPC").Parse(protoRestoreRPCTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "protoRestoreRPCTmplRaw:"+err.Error())
+		}
+		protoRestoreMessageTmpl, err = template.New("protoRestoreMessage").Parse(protoRestoreMessageTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "protoRestoreMessageTmplRaw:"+err.Error())
+		}
+		modelUUIDHookTmpl, err = template.New("modelUUIDHook").Parse(modelUUIDHookTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "modelUUIDHookTmplRaw:"+err.Error())
+		}
+		daoDistinctTmpl, err = template.New("daoDistinct").Parse(daoDistinctTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoDistinctTmplRaw:"+err.Error())
+		}
+		handlerListNDJSONTmpl, err = template.New("handlerListNDJSON").Parse(handlerListNDJSONTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "handlerListNDJSONTmplRaw:"+err.Error())
+		}
+		convertTmpl, err = template.New("convert").Parse(convertTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "convertTmplRaw:"+err.Error())
+		}
+		daoRWSplitTmpl, err = template.New("daoRWSplit").Parse(daoRWSplitTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoRWSplitTmplRaw:"+err.Error())
+		}
+		modelMaskTmpl, err = template.New("modelMask").Parse(modelMaskTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "modelMaskTmplRaw:"+err.Error())
+		}
+		daoQueryTimeoutTmpl, err = template.New("daoQueryTimeout").Parse(daoQueryTimeoutTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoQueryTimeoutTmplRaw:"+err.Error())
+		}
+		daoFilterTmpl, err = template.New("daoFilter").Parse(daoFilterTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoFilterTmplRaw:"+err.Error())
+		}
+		daoListByTimeRangeTmpl, err = template.New("daoListByTimeRange").Parse(daoListByTimeRangeTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoListByTimeRangeTmplRaw:"+err.Error())
+		}
+		protoListByTimeRangeRPCTmpl, err = template.New("protoListByTimeRangeRPC").Parse(protoListByTimeRangeRPCTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "protoListByTimeRangeRPCTmplRaw:"+err.Error())
+		}
+		protoListByTimeRangeMessageTmpl, err = template.New("protoListByTimeRangeMessage").Parse(protoListByTimeRangeMessageTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "protoListByTimeRangeMessageTmplRaw:"+err.Error())
+		}
+		daoUpdateColumnsTmpl, err = template.New("daoUpdateColumns").Parse(daoUpdateColumnsTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoUpdateColumnsTmplRaw:"+err.Error())
+		}
+		serviceUpdateMaskTmpl, err = template.New("serviceUpdateMask").Parse(serviceUpdateMaskTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "serviceUpdateMaskTmplRaw:"+err.Error())
+		}
+
+		gatewayErrorHandlerTmpl, err = template.New("gatewayErrorHandler").Parse(gatewayErrorHandlerTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "gatewayErrorHandlerTmplRaw:"+err.Error())
+		}
+		daoRepositoryTmpl, err = template.New("daoRepository").Parse(daoRepositoryTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoRepositoryTmplRaw:"+err.Error())
+		}
+		daoReadOnlyTmpl, err = template.New("daoReadOnly").Parse(daoReadOnlyTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoReadOnlyTmplRaw:"+err.Error())
+		}
+		handlerFieldErrorsTmpl, err = template.New("handlerFieldErrors").Parse(handlerFieldErrorsTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "handlerFieldErrorsTmplRaw:"+err.Error())
+		}
+		daoCountByGroupTmpl, err = template.New("daoCountByGroup").Parse(daoCountByGroupTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoCountByGroupTmplRaw:"+err.Error())
+		}
+		protoCountByGroupRPCTmpl, err = template.New("protoCountByGroupRPC").Parse(protoCountByGroupRPCTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "protoCountByGroupRPCTmplRaw:"+err.Error())
+		}
+		protoCountByGroupMessageTmpl, err = template.New("protoCountByGroupMessage").Parse(protoCountByGroupMessageTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "protoCountByGroupMessageTmplRaw:"+err.Error())
+		}
+		handlerETagTmpl, err = template.New("handlerETag").Parse(handlerETagTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "handlerETagTmplRaw:"+err.Error())
+		}
+		daoMetricsTmpl, err = template.New("daoMetrics").Parse(daoMetricsTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoMetricsTmplRaw:"+err.Error())
+		}
+		daoSeedTmpl, err = template.New("daoSeed").Parse(daoSeedTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoSeedTmplRaw:"+err.Error())
+		}
+		daoForUpdateTmpl, err = template.New("daoForUpdate").Parse(daoForUpdateTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoForUpdateTmplRaw:"+err.Error())
+		}
+		daoUpsertTmpl, err = template.New("daoUpsert").Parse(daoUpsertTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoUpsertTmplRaw:"+err.Error())
+		}
+		grpcClientRetryTmpl, err = template.New("grpcClientRetry").Parse(grpcClientRetryTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "grpcClientRetryTmplRaw:"+err.Error())
+		}
+		handlerMaxBodyBytesTmpl, err = template.New("handlerMaxBodyBytes").Parse(handlerMaxBodyBytesTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "handlerMaxBodyBytesTmplRaw:"+err.Error())
+		}
+		daoSearchTmpl, err = template.New("daoSearch").Parse(daoSearchTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoSearchTmplRaw:"+err.Error())
+		}
+		handlerSearchTmpl, err = template.New("handlerSearch").Parse(handlerSearchTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "handlerSearchTmplRaw:"+err.Error())
+		}
+		protoSearchRPCTmpl, err = template.New("protoSearchRPC").Parse(protoSearchRPCTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "protoSearchRPCTmplRaw:"+err.Error())
+		}
+		protoSearchMessageTmpl, err = template.New("protoSearchMessage").Parse(protoSearchMessageTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "protoSearchMessageTmplRaw:"+err.Error())
+		}
+		handlerCSVExportTmpl, err = template.New("handlerCSVExport").Parse(handlerCSVExportTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "handlerCSVExportTmplRaw:"+err.Error())
+		}
+		daoAssociationTmpl, err = template.New("daoAssociation").Parse(daoAssociationTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoAssociationTmplRaw:"+err.Error())
+		}
+		daoCursorListTmpl, err = template.New("daoCursorList").Parse(daoCursorListTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoCursorListTmplRaw:"+err.Error())
+		}
+		errorWrapTmpl, err = template.New("errorWrap").Parse(errorWrapTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "errorWrapTmplRaw:"+err.Error())
+		}
+		daoUpdateByConditionTmpl, err = template.New("daoUpdateByCondition").Parse(daoUpdateByConditionTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoUpdateByConditionTmplRaw:"+err.Error())
+		}
+		handlerAuthorizeTmpl, err = template.New("handlerAuthorize").Parse(handlerAuthorizeTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "handlerAuthorizeTmplRaw:"+err.Error())
+		}
+		modelCacheKeyTmpl, err = template.New("modelCacheKey").Parse(modelCacheKeyTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "modelCacheKeyTmplRaw:"+err.Error())
+		}
+		modelEnumConstTmpl, err = template.New("modelEnumConst").Parse(modelEnumConstTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "modelEnumConstTmplRaw:"+err.Error())
+		}
+
+		daoSortValidationTmpl, err = template.New("daoSortValidation").Parse(daoSortValidationTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoSortValidationTmplRaw:"+err.Error())
+		}
+		daoCRUDTmpl, err = template.New("daoCRUD").Parse(daoCRUDTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoCRUDTmplRaw:"+err.Error())
+		}
+
+		daoSQLTmpl, err = template.New("daoSQL").Parse(daoSQLTmplRaw)
+		if err != nil {
+			errSum = errors.Wrap(errSum, "daoSQLTmplRaw:"+err.Error())
+		}
+
+		if errSum != nil {
+			panic(errSum)
+		}
+	})
+}
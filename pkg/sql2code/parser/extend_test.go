@@ -0,0 +1,1075 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var extendTestSQL = `create table user_order (
+    id         bigint unsigned auto_increment,
+    created_at datetime        null,
+    updated_at datetime        null,
+    deleted_at datetime        null,
+    order_no   varchar(36)     not null comment 'order no',
+    user_id    bigint unsigned not null comment 'user id',
+    status     varchar(20)     not null default 'active' comment 'status',
+    amount     int             not null comment 'amount',
+    primary key (id)
+);`
+
+func TestParseSQL_GRPCRegister(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithGRPCRegister(true))
+	assert.NoError(t, err)
+	code := codes[CodeTypeGRPCRegister]
+	assert.Contains(t, code, "func RegisterUserOrderServer(server *grpc.Server, svc serverNameExampleV1.UserOrderServer)")
+	assert.Contains(t, code, "serverNameExampleV1.RegisterUserOrderServer(server, svc)")
+	assert.Contains(t, code, "reflection.Register(server)")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithGRPCRegister(false))
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeGRPCRegister], "serverNameExampleV1.RegisterUserOrderServer(server, svc)")
+	assert.NotContains(t, codes[CodeTypeGRPCRegister], "reflection.Register")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	_, ok := codes[CodeTypeGRPCRegister]
+	assert.False(t, ok)
+}
+
+func Test_initExtendTemplate(t *testing.T) {
+	initExtendTemplate()
+
+	defer func() { recover() }()
+	grpcRegisterTmplRaw = "{{if .foo}}"
+	daoCreateBatchTmplRaw = "{{if .foo}}"
+	errorTmplRaw = "{{if .foo}}"
+	daoRestoreTmplRaw = "{{if .foo}}"
+	handlerRestoreTmplRaw = "{{if .foo}}"
+	protoRestoreRPCTmplRaw = "{{if .foo}}"
+	protoRestoreMessageTmplRaw = "{{if .foo}}"
+	modelUUIDHookTmplRaw = "{{if .foo}}"
+	daoDistinctTmplRaw = "{{if .foo}}"
+	handlerListNDJSONTmplRaw = "{{if .foo}}"
+	convertTmplRaw = "{{if .foo}}"
+	daoRWSplitTmplRaw = "{{if .foo}}"
+	modelMaskTmplRaw = "{{if .foo}}"
+	daoQueryTimeoutTmplRaw = "{{if .foo}}"
+	daoFilterTmplRaw = "{{if .foo}}"
+	daoListByTimeRangeTmplRaw = "{{if .foo}}"
+	protoListByTimeRangeRPCTmplRaw = "{{if .foo}}"
+	protoListByTimeRangeMessageTmplRaw = "{{if .foo}}"
+	daoUpdateColumnsTmplRaw = "{{if .foo}}"
+	serviceUpdateMaskTmplRaw = "{{if .foo}}"
+	gatewayErrorHandlerTmplRaw = "{{if .foo}}"
+	daoRepositoryTmplRaw = "{{if .foo}}"
+	daoReadOnlyTmplRaw = "{{if .foo}}"
+	handlerFieldErrorsTmplRaw = "{{if .foo}}"
+	daoCountByGroupTmplRaw = "{{if .foo}}"
+	protoCountByGroupRPCTmplRaw = "{{if .foo}}"
+	protoCountByGroupMessageTmplRaw = "{{if .foo}}"
+	handlerETagTmplRaw = "{{if .foo}}"
+	daoMetricsTmplRaw = "{{if .foo}}"
+	daoSeedTmplRaw = "{{if .foo}}"
+	daoForUpdateTmplRaw = "{{if .foo}}"
+	daoUpsertTmplRaw = "{{if .foo}}"
+	grpcClientRetryTmplRaw = "{{if .foo}}"
+	handlerMaxBodyBytesTmplRaw = "{{if .foo}}"
+	daoSearchTmplRaw = "{{if .foo}}"
+	handlerSearchTmplRaw = "{{if .foo}}"
+	protoSearchRPCTmplRaw = "{{if .foo}}"
+	protoSearchMessageTmplRaw = "{{if .foo}}"
+	handlerCSVExportTmplRaw = "{{if .foo}}"
+	daoAssociationTmplRaw = "{{if .foo}}"
+	daoCursorListTmplRaw = "{{if .foo}}"
+	errorWrapTmplRaw = "{{if .foo}}"
+	daoUpdateByConditionTmplRaw = "{{if .foo}}"
+	handlerAuthorizeTmplRaw = "{{if .foo}}"
+	modelCacheKeyTmplRaw = "{{if .foo}}"
+	modelEnumConstTmplRaw = "{{if .foo}}"
+	daoSortValidationTmplRaw = "{{if .foo}}"
+	daoCRUDTmplRaw = "{{if .foo}}"
+	daoSQLTmplRaw = "{{if .foo}}"
+	initExtendTemplate()
+}
+
+var extendTestMgoFields = []*MgoField{
+	{Name: "_id", Type: "primitive.ObjectID"},
+	{Name: "name", Type: "string"},
+	{Name: "status", Type: "string"},
+	{Name: "age", Type: "int"},
+	{Name: "created_at", Type: "time.Time"},
+	{Name: "updated_at", Type: "time.Time"},
+	{Name: "deleted_at", Type: "*time.Time"},
+}
+
+func parseMgoExtendTestSQL(t *testing.T, opts ...Option) map[string]string {
+	sql, fieldsMap := ConvertToSQLByMgoFields("user_order", extendTestMgoFields)
+	opts = append([]Option{WithDBDriver(DBDriverMongodb), WithFieldTypes(fieldsMap), WithJSONTag(1)}, opts...)
+	codes, err := ParseSQL(sql, opts...)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return codes
+}
+
+func TestParseSQL_CreateBatch(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithCreateBatch())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) CreateBatch(ctx context.Context, items []*model.UserOrder, chunkSize int) error {")
+	assert.Contains(t, code, "chunkSize = defaultUserOrderBatchSize")
+	assert.Contains(t, code, "d.db.WithContext(ctx).CreateInBatches(items, chunkSize).Error")
+
+	codes = parseMgoExtendTestSQL(t, WithCreateBatch())
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) CreateBatch(ctx context.Context, items []*model.UserOrder, chunkSize int) error {")
+	assert.Contains(t, code, "item.ID = primitive.NewObjectID()")
+	assert.Contains(t, code, "d.collection.InsertMany(ctx, docs)")
+	assert.NotContains(t, code, "CreateInBatches")
+}
+
+func TestParseSQL_TypedErrors(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithTypedErrors(), WithCreateBatch())
+	assert.NoError(t, err)
+	code := codes[CodeTypeError]
+	assert.Contains(t, code, `ErrUserOrderNotFound = errors.New("userOrder not found")`)
+	assert.Contains(t, code, `ErrUserOrderAlreadyExists = errors.New("userOrder already exists")`)
+	assert.Contains(t, code, "errors.Is(err, gorm.ErrRecordNotFound)")
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "return convertUserOrderError(d.db.WithContext(ctx).CreateInBatches(items, chunkSize).Error)")
+	// the standard crud methods of dao return the typed errors too
+	assert.Contains(t, code, "return convertUserOrderError(d.db.WithContext(ctx).Create(table).Error)")
+	assert.Contains(t, code, "First(record).Error\n\tif err != nil {\n\t\treturn nil, convertUserOrderError(err)")
+	assert.Contains(t, code, "return convertUserOrderError(d.db.WithContext(ctx).Model(table).Updates(update).Error)")
+	assert.Contains(t, code, "Delete(&model.UserOrder{}).Error\n\treturn convertUserOrderError(err)")
+	assert.Contains(t, code, "Find(&records).Error\n\tif err != nil {\n\t\treturn nil, 0, convertUserOrderError(err)")
+
+	codes = parseMgoExtendTestSQL(t, WithTypedErrors(), WithCreateBatch())
+	assert.Contains(t, codes[CodeTypeError], "errors.Is(err, mongo.ErrNoDocuments)")
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "return convertUserOrderError(err)")
+	assert.Contains(t, code, "err = d.collection.FindOne(ctx, mgo.ExcludeDeleted(bson.M{\"_id\": oid})).Decode(record)\n\tif err != nil {\n\t\treturn nil, convertUserOrderError(err)")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithCreateBatch())
+	assert.NoError(t, err)
+	assert.Empty(t, codes[CodeTypeError])
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "convertUserOrderError")
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "func (d *userOrderDao) GetByID(")
+}
+
+func TestParseSQL_GatewayErrorHandler(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithGatewayErrorHandler())
+	assert.NoError(t, err)
+	code := codes[CodeTypeError]
+	assert.Contains(t, code, `ErrUserOrderNotFound = errors.New("userOrder not found")`)
+	assert.Contains(t, code, "case errors.Is(err, ErrUserOrderNotFound):\n\t\treturn http.StatusNotFound")
+	assert.Contains(t, code, "case errors.Is(err, ErrUserOrderInvalidParams):\n\t\treturn http.StatusBadRequest")
+	assert.Contains(t, code, "func UserOrderGatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler,")
+	assert.Contains(t, code, "err = &runtime.HTTPStatusError{HTTPStatus: httpStatus, Err: err}")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithTypedErrors())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeError], "GatewayErrorHandler")
+}
+
+func TestParseSQL_Restore(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithRestore(), WithWebProto())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeDAOExtend], "func (d *userOrderDao) RestoreByID(ctx context.Context, id uint64) error {")
+	assert.Contains(t, codes[CodeTypeDAOExtend], `Update("deleted_at", nil)`)
+	assert.Contains(t, codes[CodeTypeHandlerExtend], "func (h *userOrderHandler) RestoreByID(c *gin.Context) {")
+	assert.Contains(t, codes[CodeTypeHandlerExtend], "h.iDao.RestoreByID(ctx, id)")
+	assert.Contains(t, codes[CodeTypeProto], "rpc RestoreByID(RestoreUserOrderByIDRequest) returns (RestoreUserOrderByIDReply) {")
+	assert.Contains(t, codes[CodeTypeProto], `post: "/api/v1/userOrder/{id}/restore"`)
+	assert.Contains(t, codes[CodeTypeProto], "message RestoreUserOrderByIDRequest {")
+
+	codes = parseMgoExtendTestSQL(t, WithRestore())
+	assert.Contains(t, codes[CodeTypeDAOExtend], "func (d *userOrderDao) RestoreByID(ctx context.Context, id string) error {")
+	assert.Contains(t, codes[CodeTypeProto], "rpc RestoreByID(RestoreUserOrderByIDRequest) returns (RestoreUserOrderByIDReply) {}")
+
+	// table without deleted_at column
+	sql := `create table user_str (
+    user_id    varchar(36)  not null comment 'user id',
+    username   varchar(50)  not null comment 'username',
+    primary key (user_id)
+);`
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithRestore())
+	assert.NoError(t, err)
+	assert.Empty(t, codes[CodeTypeDAOExtend])
+	assert.Empty(t, codes[CodeTypeHandlerExtend])
+	assert.NotContains(t, codes[CodeTypeProto], "Restore")
+}
+
+func TestParseSQL_UUIDHook(t *testing.T) {
+	sql := `create table user_order (
+    id         char(36)        not null comment 'order id',
+    user_id    bigint unsigned not null comment 'user id',
+    primary key (id)
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithUUIDHook())
+	assert.NoError(t, err)
+	code := codes[CodeTypeModel]
+	assert.Contains(t, code, "func (m *UserOrder) BeforeCreate(tx *gorm.DB) error {")
+	assert.Contains(t, code, "m.ID = uuid.NewString()")
+	assert.Contains(t, code, `"github.com/google/uuid"`)
+	assert.Contains(t, code, `"gorm.io/gorm"`)
+
+	// primary key is not uuid
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithUUIDHook())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeModel], "BeforeCreate")
+
+	// option is disabled
+	codes, err = ParseSQL(sql, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeModel], "BeforeCreate")
+}
+
+func TestParseSQL_Distinct(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithDistinctColumns("status", "not_exist"))
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) DistinctStatus(ctx context.Context) ([]string, error) {")
+	assert.Contains(t, code, `Distinct("status").Pluck("status", &values)`)
+	assert.NotContains(t, code, "DistinctNotExist")
+
+	codes = parseMgoExtendTestSQL(t, WithDistinctColumns("status"))
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) DistinctStatus(ctx context.Context) ([]string, error) {")
+	assert.Contains(t, code, `d.collection.Distinct(ctx, "status", mgo.ExcludeDeleted(bson.M{}))`)
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "Distinct")
+}
+
+func TestParseSQL_SwaggerTags(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithWebProto(), WithSwaggerTags(), WithRestore())
+	assert.NoError(t, err)
+	code := codes[CodeTypeProto]
+	assert.Contains(t, code, `rpc Create(CreateUserOrderRequest) returns (CreateUserOrderReply) {
+    option (google.api.http) = {
+      post: "/api/v1/userOrder"
+      body: "*"
+    };
+    option (grpc.gateway.protoc_gen_openapiv2.options.openapiv2_operation) = {
+      tags: "userOrder"
+    };
+  }`)
+	assert.Equal(t, strings.Count(code, "option (google.api.http)"), strings.Count(code, `tags: "userOrder"`))
+
+	// only for web proto
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithSwaggerTags())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeProto], `tags: "userOrder"`)
+}
+
+func TestParseSQL_ListNDJSON(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithListNDJSON())
+	assert.NoError(t, err)
+	code := codes[CodeTypeHandlerExtend]
+	assert.Contains(t, code, "func (h *userOrderHandler) ListNDJSON(c *gin.Context) {")
+	assert.Contains(t, code, `c.Header("Content-Type", "application/x-ndjson")`)
+	assert.Contains(t, code, "encoder.Encode(record)")
+	assert.Contains(t, code, "c.Writer.Flush()")
+	assert.Less(t, strings.Index(code, "encoder.Encode(record)"), strings.Index(code, "c.Writer.Flush()"))
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeHandlerExtend], "ListNDJSON")
+}
+
+func TestParseSQL_ConvertPB(t *testing.T) {
+	sql := `create table user_order (
+    id         bigint unsigned auto_increment,
+    created_at datetime        null,
+    order_no   varchar(36)     not null comment 'order no',
+    amount     int             not null comment 'amount',
+    price      decimal(10, 2)  null comment 'price',
+    primary key (id)
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithNullStyle(NullDisable), WithConvertPB())
+	assert.NoError(t, err)
+	code := codes[CodeTypeConvert]
+	assert.Contains(t, code, "func ConvertUserOrderToPB(record *model.UserOrder) *serverNameExampleV1.UserOrder {")
+	assert.Contains(t, code, "func ConvertPBToUserOrder(pb *serverNameExampleV1.UserOrder) (*model.UserOrder, error) {")
+	assert.Contains(t, code, "pb.Id = record.ID")
+	assert.Contains(t, code, "pb.CreatedAt = record.CreatedAt.Format(time.RFC3339)")
+	assert.Contains(t, code, "createdAtValue, err := time.Parse(time.RFC3339, pb.CreatedAt)")
+	assert.Contains(t, code, "record.CreatedAt = &createdAtValue")
+	assert.Contains(t, code, "pb.Amount = int32(record.Amount)")
+	assert.Contains(t, code, "record.Amount = int(pb.Amount)")
+	assert.Contains(t, code, "priceValue, err := decimal.NewFromString(pb.Price)")
+	assert.NotContains(t, code, "todo")
+
+	// embedded gorm.Model and camel case json name
+	codes, err = ParseSQL(sql, WithJSONTag(1), WithEmbed(), WithNullStyle(NullDisable), WithConvertPB())
+	assert.NoError(t, err)
+	code = codes[CodeTypeConvert]
+	assert.Contains(t, code, "if !record.CreatedAt.IsZero() {")
+	assert.Contains(t, code, "record.CreatedAt = createdAtValue")
+	assert.Contains(t, code, "pb.OrderNo = record.OrderNo")
+
+	codes = parseMgoExtendTestSQL(t, WithConvertPB())
+	code = codes[CodeTypeConvert]
+	assert.Contains(t, code, "pb.Id = record.ID.Hex()")
+	assert.Contains(t, code, "idValue, err := primitive.ObjectIDFromHex(pb.Id)")
+	assert.Contains(t, code, "record.Age = int(pb.Age)")
+
+	codes, err = ParseSQL(sql, WithJSONTag(0))
+	assert.NoError(t, err)
+	_, ok := codes[CodeTypeConvert]
+	assert.False(t, ok)
+}
+
+func Test_protoGoFieldName(t *testing.T) {
+	assert.Equal(t, "Id", protoGoFieldName("id"))
+	assert.Equal(t, "UserId", protoGoFieldName("user_id"))
+	assert.Equal(t, "UserID", protoGoFieldName("userID"))
+	assert.Equal(t, "Field_1", protoGoFieldName("field_1"))
+	assert.Equal(t, "XFoo", protoGoFieldName("_foo"))
+}
+
+func TestParseSQL_ReadWriteSplit(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithReadWriteSplit())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func NewUserOrderRWDao(db *gorm.DB, readDB *gorm.DB) *userOrderRWDao {")
+	// writes use the primary db
+	assert.Contains(t, code, "return d.db.WithContext(ctx).Create(table).Error")
+	assert.Contains(t, code, `err := d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.UserOrder{}).Error`)
+	assert.Contains(t, code, "err := d.db.WithContext(ctx).Model(&model.UserOrder{}).")
+	// reads use the replica db
+	assert.Contains(t, code, `err := d.readDB.WithContext(ctx).Where("id = ?", id).First(record).Error`)
+	assert.Contains(t, code, `err := d.readDB.WithContext(ctx).Where("id IN (?)", ids).Find(&records).Error`)
+	assert.NotContains(t, code, "d.readDB.WithContext(ctx).Create")
+
+	codes = parseMgoExtendTestSQL(t, WithReadWriteSplit())
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "RWDao")
+}
+
+func TestParseSQL_MaskedColumns(t *testing.T) {
+	sql := `create table user_order (
+    id         bigint unsigned auto_increment,
+    email      varchar(50)     not null comment 'email',
+    phone      varchar(20)     null comment 'phone',
+    amount     int             not null comment 'amount',
+    primary key (id)
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithNullStyle(NullInPointer), WithMaskedColumns([]string{"email", "phone", "amount"}))
+	assert.NoError(t, err)
+	code := codes[CodeTypeModel]
+	assert.Contains(t, code, "func (m UserOrder) MarshalJSON() ([]byte, error) {")
+	assert.Contains(t, code, "v.Email = maskUserOrderValue(v.Email)")
+	assert.Contains(t, code, "masked := maskUserOrderValue(*v.Phone)")
+	assert.NotContains(t, code, "maskUserOrderValue(v.Amount)")
+	assert.Contains(t, code, `"encoding/json"`)
+	assert.Contains(t, code, `"strings"`)
+
+	codes, err = ParseSQL(sql, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeModel], "MarshalJSON")
+}
+
+func TestParseSQL_QueryTimeout(t *testing.T) {
+	sql := `create table user_order (
+    id         bigint unsigned auto_increment,
+    name       varchar(50)     not null comment 'name',
+    deleted_at datetime        null,
+    primary key (id)
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithQueryTimeout(3*time.Second), WithCreateBatch(), WithRestore())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "var defaultUserOrderQueryTimeout = 3 * time.Second")
+	assert.Contains(t, code, "func WithUserOrderQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {")
+	assert.Contains(t, code, "return context.WithTimeout(ctx, timeout)")
+	assert.Equal(t, 7, strings.Count(code, "ctx, cancel := withUserOrderQueryTimeout(ctx)"), "CreateBatch, RestoreByID and the standard crud methods")
+	// the standard crud methods of dao wrap the context with the timeout before executing
+	for _, signature := range []string{
+		"Create(ctx context.Context, table *model.UserOrder) error {",
+		"GetByID(ctx context.Context, id uint64) (*model.UserOrder, error) {",
+		"UpdateByID(ctx context.Context, table *model.UserOrder) error {",
+		"DeleteByID(ctx context.Context, id uint64) error {",
+		"GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserOrder, int64, error) {",
+	} {
+		assert.Contains(t, code, "func (d *userOrderDao) "+signature+"\n\tctx, cancel := withUserOrderQueryTimeout(ctx)\n\tdefer cancel()")
+	}
+
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithQueryTimeout(1500*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeDAOExtend], "var defaultUserOrderQueryTimeout = 1500 * time.Millisecond")
+
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithCreateBatch())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "QueryTimeout")
+}
+
+func TestParseSQL_TypedFilter(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithNullStyle(NullDisable), WithTypedFilter())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "type UserOrderFilter struct {")
+	assert.Contains(t, code, "ID        *uint64    `json:\"id,omitempty\"`")
+	assert.Contains(t, code, "OrderNo   *string")
+	assert.Contains(t, code, "Amount    *int ")
+	assert.Contains(t, code, "CreatedAt *time.Time")
+	assert.NotContains(t, code, "DeletedAt")
+	assert.Contains(t, code, "func (f *UserOrderFilter) ToConditions() *query.Conditions {")
+	assert.Contains(t, code, `columns = append(columns, query.Column{Name: "user_id", Value: *f.UserID})`)
+
+	codes = parseMgoExtendTestSQL(t, WithTypedFilter())
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "ID        *string")
+	assert.Contains(t, code, "Age       *int ")
+}
+
+func TestParseSQL_OpenAPIExamples(t *testing.T) {
+	sql := `create table user (
+    id     bigint unsigned auto_increment,
+    status varchar(20) not null default 'active' comment 'status',
+    age    int         not null default 0 comment 'age, example: 18',
+    email  varchar(50) not null comment 'email, e.g. foo@bar.com',
+    primary key (id)
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithWebProto(), WithOpenAPIExamples())
+	assert.NoError(t, err)
+	proto := codes[CodeTypeProto]
+	assert.Contains(t, proto, `string status = 1 [(grpc.gateway.protoc_gen_openapiv2.options.openapiv2_field) = {example: "\"active\""}];`)
+	assert.Contains(t, proto, `int32 age = 2 [(grpc.gateway.protoc_gen_openapiv2.options.openapiv2_field) = {example: "18"}];`)
+	assert.Contains(t, proto, `string email = 3 [(grpc.gateway.protoc_gen_openapiv2.options.openapiv2_field) = {example: "\"foo@bar.com\""}];`)
+	handler := codes[CodeTypeHandler]
+	assert.Contains(t, handler, `json:"status" binding:"" example:"active"`)
+	assert.Contains(t, handler, `json:"age" binding:"" example:"18"`)
+
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithOpenAPIExamples())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeProto], "openapiv2_field")
+
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithWebProto())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeProto], "openapiv2_field")
+	assert.Contains(t, codes[CodeTypeHandler], `json:"status" binding:""`+"`")
+}
+
+func TestParseSQL_ListByTimeRange(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithListByTimeRange(), WithWebProto())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeDAOExtend], "func (d *userOrderDao) ListByCreatedAtRange(ctx context.Context, from time.Time, to time.Time, page int, limit int) ([]*model.UserOrder, int64, error) {")
+	assert.Contains(t, codes[CodeTypeDAOExtend], `Where("created_at >= ? AND created_at < ?", from, to)`)
+	proto := codes[CodeTypeProto]
+	assert.Contains(t, proto, "rpc ListByCreatedAtRange(ListUserOrderByCreatedAtRangeRequest) returns (ListUserOrderByCreatedAtRangeReply) {")
+	assert.Contains(t, proto, `post: "/api/v1/userOrder/list/createdAtRange"`)
+	assert.Contains(t, proto, "message ListUserOrderByCreatedAtRangeRequest {")
+	assert.Contains(t, proto, "repeated UserOrder userOrders = 2;")
+
+	codes = parseMgoExtendTestSQL(t, WithListByTimeRange())
+	assert.Contains(t, codes[CodeTypeDAOExtend], `filter := mgo.ExcludeDeleted(bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})`)
+	assert.Contains(t, codes[CodeTypeProto], "rpc ListByCreatedAtRange(ListUserOrderByCreatedAtRangeRequest) returns (ListUserOrderByCreatedAtRangeReply) {}")
+
+	// table without created_at column
+	sql := `create table user_str (
+    user_id    varchar(36)  not null comment 'user id',
+    username   varchar(50)  not null comment 'username',
+    primary key (user_id)
+);`
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithListByTimeRange())
+	assert.NoError(t, err)
+	assert.Empty(t, codes[CodeTypeDAOExtend])
+	assert.NotContains(t, codes[CodeTypeProto], "ListByCreatedAtRange")
+}
+
+func TestParseSQL_FieldMask(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithNullStyle(NullDisable), WithFieldMask())
+	assert.NoError(t, err)
+	proto := codes[CodeTypeProto]
+	assert.Contains(t, proto, `import "google/protobuf/field_mask.proto";`)
+	assert.Contains(t, proto, "google.protobuf.FieldMask update_mask = 6;")
+
+	code := codes[CodeTypeServiceExtend]
+	assert.Contains(t, code, "func userOrderUpdateMaskColumns(pb *serverNameExampleV1.UpdateUserOrderByIDRequest) (map[string]interface{}, error) {")
+	assert.Contains(t, code, "paths := pb.GetUpdateMask().GetPaths()")
+	assert.Contains(t, code, `case "order_no":`)
+	assert.Contains(t, code, `columns["order_no"] = record.OrderNo`)
+	assert.Contains(t, code, `return nil, fmt.Errorf("invalid update_mask path: %s", path)`)
+	assert.Contains(t, codes[CodeTypeDAOExtend], "func (d *userOrderDao) UpdateColumnsByID(ctx context.Context, id uint64, columns map[string]interface{}) error {")
+
+	codes = parseMgoExtendTestSQL(t, WithFieldMask())
+	assert.Contains(t, codes[CodeTypeProto], "google.protobuf.FieldMask update_mask =")
+	assert.Contains(t, codes[CodeTypeDAOExtend], `bson.M{"$set": columns}`)
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeProto], "update_mask")
+}
+
+func TestParseSQL_Repository(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithRepository())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "type UserOrderRepository interface {")
+	methods := []string{
+		"Create(ctx context.Context, table *model.UserOrder) error",
+		"DeleteByID(ctx context.Context, id uint64) error",
+		"UpdateByID(ctx context.Context, table *model.UserOrder) error",
+		"GetByID(ctx context.Context, id uint64) (*model.UserOrder, error)",
+		"GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserOrder, int64, error)",
+	}
+	for _, method := range methods {
+		assert.Contains(t, code, "\t"+method+"\n")
+		assert.Contains(t, code, "func (r *userOrderRepo) "+method+" {")
+	}
+	assert.Contains(t, code, "var _ UserOrderRepository = (*userOrderRepo)(nil)")
+	assert.Contains(t, code, "func NewUserOrderRepository(db *gorm.DB) UserOrderRepository {")
+	assert.Contains(t, code, `update["order_no"] = table.OrderNo`)
+
+	codes = parseMgoExtendTestSQL(t, WithRepository())
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "Repository")
+}
+
+func TestParseSQL_FieldErrors(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithFieldErrors())
+	assert.NoError(t, err)
+	code := codes[CodeTypeHandlerExtend]
+	assert.Contains(t, code, "func bindUserOrderJSON(c *gin.Context, req interface{}) ([]UserOrderFieldError, error) {")
+	assert.Contains(t, code, "for _, fieldErr := range validationErrs {")
+	assert.Contains(t, code, "fieldErrs = append(fieldErrs, UserOrderFieldError{Field: field, Rule: fieldErr.Tag(), Param: fieldErr.Param()})")
+	assert.Contains(t, code, "func (h *userOrderHandler) CreateWithFieldErrors(c *gin.Context) {")
+	assert.Contains(t, code, "fieldErrs, err := bindUserOrderJSON(c, form)")
+	assert.Contains(t, code, "response.Error(c, ecode.InvalidParams, fieldErrs)")
+	assert.Contains(t, code, `response.Success(c, gin.H{"id": record.ID})`)
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeHandlerExtend], "CreateWithFieldErrors")
+}
+
+func TestParseSQL_CountByGroup(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithCountByGroup(), WithWebProto())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) CountByGroup(ctx context.Context, groupColumn string) (map[string]int64, error) {")
+	assert.Contains(t, code, "if !model.UserOrderColumnNames[groupColumn] {")
+	assert.Contains(t, code, `Select(groupColumn + " AS group_value, COUNT(*) AS total").Group(groupColumn).Scan(&rows).Error`)
+	proto := codes[CodeTypeProto]
+	assert.Contains(t, proto, "rpc CountByGroup(CountUserOrderByGroupRequest) returns (CountUserOrderByGroupReply) {")
+	assert.Contains(t, proto, `post: "/api/v1/userOrder/count/group"`)
+	assert.Contains(t, proto, "map<string, int64> counts = 1;")
+
+	codes = parseMgoExtendTestSQL(t, WithCountByGroup())
+	assert.Contains(t, codes[CodeTypeDAOExtend], `groupStage := bson.M{"$group": bson.M{"_id": "$" + groupColumn, "total": bson.M{"$sum": 1}}}`)
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithCountByGroup(), WithoutColumnWhitelist())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "CountByGroup")
+	assert.NotContains(t, codes[CodeTypeProto], "CountByGroup")
+}
+
+func TestParseSQL_ETag(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithETag())
+	assert.NoError(t, err)
+	code := codes[CodeTypeHandlerExtend]
+	assert.Contains(t, code, "func userOrderETag(record *model.UserOrder) string {")
+	assert.Contains(t, code, "updatedAt := record.UpdatedAt.Time.UnixNano()")
+	assert.Contains(t, code, `sum := sha256.Sum256([]byte(fmt.Sprintf("%v-%d", record.ID, updatedAt)))`)
+	assert.Contains(t, code, "func (h *userOrderHandler) GetByIDWithETag(c *gin.Context) {")
+	assert.Contains(t, code, `c.Header("ETag", etag)`)
+	assert.Contains(t, code, `for _, match := range strings.Split(c.GetHeader("If-None-Match"), ",") {`)
+	assert.Contains(t, code, "c.Status(http.StatusNotModified)")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithNullStyle(NullInPointer), WithETag())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeHandlerExtend], "updatedAt = record.UpdatedAt.UnixNano()")
+
+	codes = parseMgoExtendTestSQL(t, WithETag())
+	assert.Contains(t, codes[CodeTypeHandlerExtend], "errors.Is(err, mongo.ErrNoDocuments)")
+
+	codes, err = ParseSQL(`create table tag (id bigint unsigned auto_increment, name varchar(50), primary key (id));`, WithETag())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeHandlerExtend], "WithETag")
+}
+
+func TestParseSQL_DaoMetrics(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithDaoMetrics())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "type UserOrderRepository interface {")
+	assert.Contains(t, code, `Name:    "dao_user_order_duration_seconds",`)
+	assert.Contains(t, code, `Name: "dao_user_order_errors_total",`)
+	assert.Contains(t, code, "func NewUserOrderRepositoryWithMetrics(next UserOrderRepository) UserOrderRepository {")
+	assert.Contains(t, code, `func (m *userOrderRepoMetrics) Create(ctx context.Context, table *model.UserOrder) error {
+	start := time.Now()
+	err := m.next.Create(ctx, table)
+	observeUserOrderDaoOp("Create", start, err)
+	return err
+}`)
+	assert.Contains(t, code, "records, total, err := m.next.GetByColumns(ctx, params)")
+
+	codes = parseMgoExtendTestSQL(t, WithDaoMetrics())
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "prometheus")
+}
+
+func TestParseSQL_Seed(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithSeed())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "var UserOrderFixtures = []*model.UserOrder{")
+	assert.Contains(t, code, `		OrderNo: "order_no_1",
+		UserID:  1,
+		Status:  "status_1",
+		Amount:  1,`)
+	assert.Contains(t, code, `OrderNo: "order_no_2",`)
+	assert.NotContains(t, code, "CreatedAt:")
+	assert.Contains(t, code, "func SeedUserOrder(db *gorm.DB, items []*model.UserOrder) error {")
+	assert.Contains(t, code, "return db.CreateInBatches(items, 100).Error")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithOpenAPIExamples(), WithSeed())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeDAOExtend], `Status:  "active",`)
+
+	codes = parseMgoExtendTestSQL(t, WithSeed())
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func SeedUserOrder(collection *mongo.Collection, items []*model.UserOrder) error {")
+	assert.Contains(t, code, "_, err := collection.InsertMany(context.Background(), docs)")
+}
+
+func TestParseSQL_ForUpdate(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithForUpdate())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) GetByIDForUpdate(ctx context.Context, tx *gorm.DB, id uint64) (*model.UserOrder, error) {")
+	assert.Contains(t, code, `err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).`)
+	assert.Contains(t, code, `Where("id = ?", id).First(record).Error`)
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithDBDriver(DBDriverSqlite), WithForUpdate())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "ForUpdate")
+
+	codes = parseMgoExtendTestSQL(t, WithForUpdate())
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "ForUpdate")
+}
+
+func TestParseSQL_Upsert(t *testing.T) {
+	sql := strings.Replace(extendTestSQL, "primary key (id)", "primary key (id),\n    unique key uk_user_order (user_id, order_no)", 1)
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithUpsert())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) Upsert(ctx context.Context, table *model.UserOrder) error {")
+	assert.Contains(t, code, `Columns:   []clause.Column{{Name: "user_id"}, {Name: "order_no"}},`)
+	assert.Contains(t, code, `DoUpdates: clause.AssignmentColumns([]string{"updated_at", "status", "amount"}),`)
+
+	// single column unique key
+	sql = strings.Replace(extendTestSQL, "varchar(36)     not null", "varchar(36)     not null unique", 1)
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithUpsert())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeDAOExtend], `Columns:   []clause.Column{{Name: "order_no"}},`)
+
+	// no unique key
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithUpsert())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "Upsert")
+}
+
+func TestParseSQL_ClientRetries(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithClientRetries(3, 200*time.Millisecond))
+	assert.NoError(t, err)
+	code := codes[CodeTypeGRPCClient]
+	assert.Contains(t, code, "interceptor.UnaryClientRetry(")
+	assert.Contains(t, code, "interceptor.WithRetryTimes(defaultUserOrderClientRetryTimes)")
+	assert.Contains(t, code, "defaultUserOrderClientRetryBackoff = 200 * time.Millisecond")
+	assert.Contains(t, code, `"GetByID": true`)
+	assert.Contains(t, code, `"List":    true`)
+	assert.Contains(t, code, "opts = append(opts, UserOrderClientRetryOption())")
+
+	// not generated by default
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.Empty(t, codes[CodeTypeGRPCClient])
+}
+
+func TestParseSQL_MaxBodyBytes(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithFieldErrors(), WithMaxBodyBytes(1<<20))
+	assert.NoError(t, err)
+	code := codes[CodeTypeHandlerExtend]
+	assert.Contains(t, code, "const userOrderMaxBodyBytes = 1048576")
+	assert.Contains(t, code, "c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, userOrderMaxBodyBytes)")
+	assert.Contains(t, code, "func (h *userOrderHandler) CreateWithFieldErrors(c *gin.Context) {\n\tlimitUserOrderBody(c)")
+	assert.Contains(t, code, "response.Output(c, http.StatusRequestEntityTooLarge)")
+	assert.Contains(t, code, "func UserOrderMaxBodyBytes() gin.HandlerFunc {")
+
+	// no limit by default
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithFieldErrors())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeHandlerExtend], "MaxBytesReader")
+}
+
+func TestParseSQL_Search(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithWebProto(), WithSearch("order_no", "status", "user_id"))
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) Search(ctx context.Context, keyword string, conditions *query.Conditions, page int, limit int) ([]*model.UserOrder, int64, error) {")
+	assert.Contains(t, code, "conditions.ConvertToGorm(query.WithWhitelistNames(model.UserOrderColumnNames))")
+	assert.Contains(t, code, `db = db.Where("(order_no LIKE ? OR status LIKE ?)", like, like)`) // user_id is not a text column
+	code = codes[CodeTypeHandlerExtend]
+	assert.Contains(t, code, "func (h *userOrderHandler) Search(c *gin.Context) {")
+	assert.Contains(t, code, "h.iDao.Search(ctx, form.Keyword, &form.Conditions, form.Page, form.Limit)")
+	assert.Contains(t, codes[CodeTypeProto], "rpc Search(SearchUserOrderRequest) returns (SearchUserOrderReply) {")
+	assert.Contains(t, codes[CodeTypeProto], "api.types.Conditions conditions = 2;")
+
+	codes = parseMgoExtendTestSQL(t, WithSearch("name"))
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, `filter["$text"] = bson.M{"$search": keyword}`)
+	assert.Contains(t, code, "conditions.ConvertToMongo(query.WithWhitelistNames(model.UserOrderColumnNames))")
+
+	// no search column
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithSearch("user_id"))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "Search")
+	assert.NotContains(t, codes[CodeTypeProto], "Search")
+}
+
+func TestParseSQL_CSVExport(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithCSVExport())
+	assert.NoError(t, err)
+	code := codes[CodeTypeHandlerExtend]
+	assert.Contains(t, code, "func (h *userOrderHandler) ExportUserOrderCSV(c *gin.Context) {")
+	assert.Contains(t, code, `c.Header("Content-Type", "text/csv; charset=utf-8")`)
+	assert.Contains(t, code, `var userOrderCSVHeader = []string{"id", "created_at", "updated_at", "order_no", "user_id", "status", "amount"}`)
+	assert.Contains(t, code, "_ = writer.Write(userOrderCSVHeader)")
+	assert.Contains(t, code, "Columns: form.Columns}")
+
+	codes = parseMgoExtendTestSQL(t, WithCSVExport())
+	assert.Contains(t, codes[CodeTypeHandlerExtend], `var userOrderCSVHeader = []string{"id", "name", "status", "age", "created_at", "updated_at"}`)
+}
+
+func TestParseSQL_JoinTable(t *testing.T) {
+	sql := `create table user_role (
+    user_id    bigint unsigned not null comment 'user id',
+    role_id    bigint unsigned not null comment 'role id',
+    created_at datetime        null,
+    primary key (user_id, role_id),
+    foreign key (user_id) references user (id),
+    constraint fk_user_role_role foreign key (role_id) references role (id)
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0))
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userRoleDao) AddAssociation(ctx context.Context, userID uint64, roleID uint64) error {")
+	assert.Contains(t, code, "clause.OnConflict{DoNothing: true}")
+	assert.Contains(t, code, "func (d *userRoleDao) RemoveAssociation(ctx context.Context, userID uint64, roleID uint64) error {")
+	assert.Contains(t, code, "func (d *userRoleDao) ListAssociatedByUserID(ctx context.Context, userID uint64) ([]uint64, error) {")
+	assert.Contains(t, code, "func (d *userRoleDao) ListAssociatedByRoleID(ctx context.Context, roleID uint64) ([]uint64, error) {")
+	assert.Contains(t, codes[CodeTypeModel], `gorm:"column:role_id;primary_key"`)
+	// no crud code
+	assert.Empty(t, codes[CodeTypeDAO])
+	assert.Empty(t, codes[CodeTypeHandler])
+	assert.Empty(t, codes[CodeTypeProto])
+
+	// not a pure join table
+	codes, err = ParseSQL(strings.Replace(sql, "created_at datetime", "expired_at datetime", 1), WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "AddAssociation")
+	assert.NotEmpty(t, codes[CodeTypeHandler])
+
+	// the keys declared by column references are foreign keys too
+	codes, err = ParseSQL(`create table user_role (
+    user_id bigint unsigned not null references user (id),
+    role_id bigint unsigned not null references role (id),
+    primary key (user_id, role_id)
+);`, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeDAOExtend], "AddAssociation")
+
+	// the table with composite primary key but without foreign keys still gets crud code
+	for _, fk := range []string{"", "    foreign key (user_id) references user (id),\n"} {
+		codes, err = ParseSQL(`create table user_role (
+    user_id    bigint unsigned not null comment 'user id',
+    role_id    bigint unsigned not null comment 'role id',
+    created_at datetime        null,
+`+fk+`    primary key (user_id, role_id)
+);`, WithJSONTag(0))
+		assert.NoError(t, err)
+		assert.NotContains(t, codes[CodeTypeDAOExtend], "AddAssociation")
+		assert.NotEmpty(t, codes[CodeTypeDAO])
+		assert.NotEmpty(t, codes[CodeTypeHandler])
+		assert.NotEmpty(t, codes[CodeTypeProto])
+	}
+}
+
+func TestParseSQL_CursorList(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithCursorList())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) List(ctx context.Context, cursor string, limit int) ([]*model.UserOrder, string, error) {")
+	assert.Contains(t, code, "id, err := decodeUserOrderCursor(cursor)")
+	assert.Contains(t, code, `db = db.Where("id < ?", id)`)
+	assert.Contains(t, code, "v, err := strconv.ParseUint(string(raw), 10, 64)")
+	assert.Contains(t, code, "nextCursor = encodeUserOrderCursor(records[limit-1].ID)")
+	assert.Contains(t, code, "return records, nextCursor, nil")
+
+	codes = parseMgoExtendTestSQL(t, WithCursorList())
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "oid, err := primitive.ObjectIDFromHex(id)")
+	assert.Contains(t, code, `filter["_id"] = bson.M{"$lt": oid}`)
+	assert.Contains(t, code, "nextCursor = encodeUserOrderCursor(records[limit-1].ID.Hex())")
+	assert.Contains(t, code, "return string(raw), nil")
+}
+
+func TestParseSQL_LayerPackage(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithFieldMask(), WithRestore(), WithPackage("entity"),
+		WithLayerPackage(CodeTypeService, "grpcsvc"), WithLayerPackage(CodeTypeDAO, "repo"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(codes[CodeTypeServiceExtend], "package grpcsvc\n\n"))
+	assert.Contains(t, codes[CodeTypeServiceExtend], "record := &entity.UserOrder{}")
+	assert.True(t, strings.HasPrefix(codes[CodeTypeDAOExtend], "package repo\n\n"))
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "model.")
+	assert.True(t, strings.HasPrefix(codes[CodeTypeModel], "package entity"))
+	// the layer without package override is not changed
+	assert.False(t, strings.HasPrefix(codes[CodeTypeHandlerExtend], "package "))
+	assert.Contains(t, codes[CodeTypeHandlerExtend], "func (h *userOrderHandler) Restore")
+}
+
+func TestParseSQL_DaoErrorWrapping(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithRestore(), WithForUpdate(), WithDaoErrorWrapping())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, `return wrapUserOrderError("dao.RestoreByID", err)`)
+	assert.Contains(t, code, `return nil, wrapUserOrderError("dao.GetByIDForUpdate", err)`)
+	// the standard crud methods of dao wrap the errors too
+	assert.Contains(t, code, "First(record).Error\n\tif err != nil {\n\t\treturn nil, wrapUserOrderError(\"dao.GetByID\", err)")
+	assert.Contains(t, code, `return wrapUserOrderError("dao.Create", d.db.WithContext(ctx).Create(table).Error)`)
+	assert.Contains(t, code, `return wrapUserOrderError("dao.UpdateByID", d.db.WithContext(ctx).Model(table).Updates(update).Error)`)
+	assert.Contains(t, code, `return wrapUserOrderError("dao.DeleteByID", err)`)
+	assert.Contains(t, code, `return nil, 0, wrapUserOrderError("dao.GetByColumns", err)`)
+	assert.NotContains(t, code, errorOperationMark)
+	assert.Contains(t, codes[CodeTypeError], "func wrapUserOrderError(operation string, err error) error {")
+
+	// the typed errors are wrapped
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithRestore(), WithTypedErrors(), WithDaoErrorWrapping())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeDAOExtend], `return wrapUserOrderError("dao.RestoreByID", convertUserOrderError(err))`)
+	assert.Contains(t, codes[CodeTypeDAOExtend], `return nil, wrapUserOrderError("dao.GetByID", convertUserOrderError(err))`)
+	assert.Contains(t, codes[CodeTypeError], "func convertUserOrderError(err error) error {")
+
+	codes = parseMgoExtendTestSQL(t, WithRestore(), WithDaoErrorWrapping())
+	assert.Contains(t, codes[CodeTypeDAOExtend], `return wrapUserOrderError("dao.RestoreByID", err)`)
+	assert.Contains(t, codes[CodeTypeDAOExtend], `return nil, wrapUserOrderError("dao.GetByID", err)`)
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithRestore())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "wrapUserOrderError")
+	assert.Empty(t, codes[CodeTypeError])
+}
+
+func TestParseSQL_UpdateByCondition(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithUpdateByCondition())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) UpdateByCondition(ctx context.Context, conditions *query.Conditions, fields map[string]interface{}) (int64, error) {")
+	assert.Contains(t, code, "queryStr, args, err := conditions.ConvertToGorm(query.WithWhitelistNames(model.UserOrderColumnNames))")
+	assert.Contains(t, code, "if !model.UserOrderColumnNames[column] {")
+	assert.Contains(t, code, "return result.RowsAffected, nil")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithUpdateByCondition(), WithoutColumnWhitelist())
+	assert.NoError(t, err)
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "queryStr, args, err := conditions.ConvertToGorm()")
+	assert.NotContains(t, code, "UserOrderColumnNames")
+
+	codes = parseMgoExtendTestSQL(t, WithUpdateByCondition())
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "filter, err := conditions.ConvertToMongo(query.WithWhitelistNames(model.UserOrderColumnNames))")
+	assert.Contains(t, code, "return result.ModifiedCount, nil")
+}
+
+func TestParseSQL_AuditFields(t *testing.T) {
+	sql := `create table user_order (
+    id         bigint unsigned auto_increment,
+    created_by varchar(64)     null,
+    updated_by varchar(64)     null,
+    created_at datetime        null,
+    updated_at datetime        null,
+    order_no   varchar(36)     not null comment 'order no',
+    primary key (id)
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithAuditFields())
+	assert.NoError(t, err)
+	code := codes[CodeTypeModel]
+	assert.Contains(t, code, "sgorm.Audit2 `gorm:\"embedded\"`")
+	assert.NotContains(t, code, "CreatedBy")
+	assert.NotContains(t, code, "*time.Time")
+	assert.Contains(t, code, `"created_by": true,`)
+	assert.Contains(t, code, `"updated_at": true,`)
+	// the dao update does not copy the audit columns, updated_by is set by the hook of sgorm.Audit2
+	assert.NotContains(t, codes[CodeTypeDAO], "created_by")
+	assert.NotContains(t, codes[CodeTypeDAO], "updated_by")
+
+	codes, err = ParseSQL(sql, WithJSONTag(1), WithAuditFields(), WithEmbed())
+	assert.NoError(t, err)
+	code = codes[CodeTypeModel]
+	assert.Contains(t, code, "sgorm.Model `gorm:\"embedded\"`")
+	assert.Contains(t, code, "sgorm.AuditUser `gorm:\"embedded\"`")
+
+	codes, err = ParseSQL(strings.ReplaceAll(sql, "bigint unsigned auto_increment", "char(36)"), WithJSONTag(0), WithAuditFields(), WithUUIDHook())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeModel], "return m.Audit2.BeforeCreate(tx)")
+}
+
+func TestParseSQL_AuthorizationHook(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithAuthorizationHook())
+	assert.NoError(t, err)
+	code := codes[CodeTypeHandlerExtend]
+	assert.Contains(t, code, "func (h *userOrderHandler) authorizeUserOrder(c *gin.Context, action string, id uint64, claims *jwt.Claims) error {")
+	assert.Contains(t, code, "// TODO: implement the resource level authorization")
+	assert.Contains(t, code, "func (h *userOrderHandler) UpdateByIDWithAuthorization(c *gin.Context) {")
+	assert.Contains(t, code, `if err = h.authorizeUserOrder(c, "update", id, claims); err != nil {`)
+	assert.Contains(t, code, "err = h.iDao.UpdateByID(ctx, record)")
+	assert.Contains(t, code, "func (h *userOrderHandler) DeleteByIDWithAuthorization(c *gin.Context) {")
+	assert.Contains(t, code, `if err := h.authorizeUserOrder(c, "delete", id, claims); err != nil {`)
+	assert.Contains(t, code, "response.Error(c, ecode.Forbidden)")
+	// the hook is called before mutating
+	assert.Less(t, strings.Index(code, `h.authorizeUserOrder(c, "update"`), strings.Index(code, "h.iDao.UpdateByID("))
+
+	codes = parseMgoExtendTestSQL(t, WithAuthorizationHook())
+	assert.Contains(t, codes[CodeTypeHandlerExtend], "func (h *userOrderHandler) authorizeUserOrder(c *gin.Context, action string, id string, claims *jwt.Claims) error {")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeHandlerExtend], "WithAuthorization")
+}
+
+func TestParseSQL_CacheKey(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithCacheKey())
+	assert.NoError(t, err)
+	code := codes[CodeTypeModel]
+	assert.Contains(t, code, "func (m *UserOrder) CacheKey() string {")
+	assert.Contains(t, code, `return fmt.Sprintf("user_order:%v", m.ID)`)
+	assert.Contains(t, code, `"fmt"`)
+
+	codes, err = ParseSQL(`create table tag (name varchar(50), title varchar(50), primary key (name));`, WithCacheKey())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeModel], `return fmt.Sprintf("tag:%v", m.Name)`)
+
+	codes = parseMgoExtendTestSQL(t, WithCacheKey())
+	assert.Contains(t, codes[CodeTypeModel], `return "user_order:" + m.ID.Hex()`)
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeModel], "CacheKey")
+}
+
+func TestParseSQL_EnumConst(t *testing.T) {
+	sql := `create table user_order (
+    id bigint unsigned auto_increment primary key,
+    status enum('pending','in-progress','paid') not null default 'pending',
+    name varchar(50) not null
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithEnumConst())
+	assert.NoError(t, err)
+	code := codes[CodeTypeProto]
+	assert.Contains(t, code, "enum UserOrderStatus {")
+	assert.Contains(t, code, "USER_ORDER_STATUS_UNSPECIFIED = 0;")
+	assert.Contains(t, code, "USER_ORDER_STATUS_IN_PROGRESS = 2; // in-progress")
+	assert.Contains(t, code, "UserOrderStatus status = ")
+	assert.Contains(t, code, "string name = ")
+	code = codes[CodeTypeModel]
+	assert.Contains(t, code, `UserOrderStatusPending    = "pending"`)
+	assert.Contains(t, code, `UserOrderStatusInProgress = "in-progress"`)
+	// the enum is converted by the constants, the unknown value is UNSPECIFIED
+	code = codes[CodeTypeConvert]
+	assert.Contains(t, code, "switch record.Status {")
+	assert.Contains(t, code, "case model.UserOrderStatusInProgress:\n\t\tpb.Status = serverNameExampleV1.UserOrderStatus_USER_ORDER_STATUS_IN_PROGRESS")
+	assert.Contains(t, code, "default:\n\t\tpb.Status = serverNameExampleV1.UserOrderStatus_USER_ORDER_STATUS_UNSPECIFIED")
+	assert.Contains(t, code, "case serverNameExampleV1.UserOrderStatus_USER_ORDER_STATUS_PAID:\n\t\trecord.Status = model.UserOrderStatusPaid")
+	assert.NotContains(t, code, "todo: convert record.Status")
+
+	codes, err = ParseSQL(sql, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeProto], "enum UserOrderStatus")
+	assert.Contains(t, codes[CodeTypeProto], "string status = ")
+	assert.NotContains(t, codes[CodeTypeModel], "UserOrderStatusPending")
+}
+
+func TestParseSQL_SortValidation(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithSortValidation(), WithRepository())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func validateUserOrderSort(sort string) error {")
+	// the unknown sort column is rejected before querying
+	assert.Contains(t, code, "if !model.UserOrderColumnNames[column] {")
+	assert.Contains(t, code, `return fmt.Errorf("invalid sort column: %s", column)`)
+	// the standard GetByColumns of dao rejects the unknown sort column before querying
+	daoList := code[strings.Index(code, "func (d *userOrderDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserOrder, int64, error) {"):]
+	assert.Less(t, strings.Index(daoList, "if err := validateUserOrderSort(params.Sort); err != nil {"), strings.Index(daoList, "params.ConvertToPage()"))
+	assert.Contains(t, code, "func (d *userOrderDao) GetByID(ctx context.Context, id uint64) (*model.UserOrder, error) {")
+	assert.Equal(t, 2, strings.Count(code, "validateUserOrderSort(params.Sort)"), "GetByColumns of dao and repository")
+
+	sql := extendTestSQL + `
+CREATE VIEW user_order_view AS SELECT id, order_no FROM user_order;`
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithSortValidation())
+	assert.NoError(t, err)
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func validateUserOrderViewSort(sort string) error {")
+	assert.Contains(t, code, "if err := validateUserOrderViewSort(params.Sort); err != nil {")
+	assert.NotContains(t, code, "func (d *userOrderViewDao) GetByColumns(")
+
+	codes = parseMgoExtendTestSQL(t, WithSortValidation())
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func (d *userOrderDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserOrder, int64, error) {")
+	assert.Less(t, strings.Index(code, "if err := validateUserOrderSort(params.Sort); err != nil {"), strings.Index(code, "params.ConvertToMongoFilter()"))
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithSortValidation(), WithoutColumnWhitelist())
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "validateUserOrderSort")
+}
+
+func TestParseSQL_ORM(t *testing.T) {
+	codes, err := ParseSQL(extendTestSQL, WithJSONTag(0), WithORM(ORMSqlx))
+	assert.NoError(t, err)
+	code := codes[CodeTypeModel]
+	assert.Contains(t, code, "`db:\"order_no\" json:\"order_no\"`")
+	assert.NotContains(t, code, "gorm:")
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func NewUserOrderDao(db *sqlx.DB) *userOrderDao {")
+	assert.Contains(t, code, `err := d.db.GetContext(ctx, record, "SELECT id, created_at, updated_at, deleted_at, order_no, user_id, status, amount FROM user_order WHERE id = ? AND deleted_at IS NULL", id)`)
+	assert.Contains(t, code, `err := d.db.SelectContext(ctx, &records, "SELECT id, created_at, updated_at, deleted_at, order_no, user_id, status, amount FROM user_order WHERE deleted_at IS NULL ORDER BY id DESC LIMIT ? OFFSET ?", limit, page*limit)`)
+	// the auto increment id and deleted_at are not inserted, created_at and updated_at are the current time
+	assert.Contains(t, code, `"INSERT INTO user_order (created_at, updated_at, order_no, user_id, status, amount) VALUES (?, ?, ?, ?, ?, ?)", now, now, table.OrderNo,`)
+	assert.Contains(t, code, "table.ID = uint64(id)")
+	assert.Contains(t, code, `"UPDATE user_order SET updated_at = ?, order_no = ?, user_id = ?, status = ?, amount = ? WHERE id = ? AND deleted_at IS NULL", now, table.OrderNo,`)
+	// soft delete
+	assert.Contains(t, code, `"UPDATE user_order SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now(), id)`)
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0), WithORM(ORMStdlib), WithDBDriver(DBDriverPostgresql))
+	assert.NoError(t, err)
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, "func NewUserOrderDao(db *sql.DB) *userOrderDao {")
+	assert.Contains(t, code, "VALUES ($1, $2, $3, $4, $5, $6) RETURNING id\"")
+	assert.Contains(t, code, ".Scan(&table.ID)")
+	assert.Contains(t, code, "Scan(&record.ID, &record.CreatedAt, &record.UpdatedAt, &record.DeletedAt, &record.OrderNo, &record.UserID, &record.Status, &record.Amount)")
+	assert.Contains(t, code, `"UPDATE user_order SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL"`)
+
+	// the table without deleted_at is hard deleted
+	codes, err = ParseSQL(`create table tag (id bigint unsigned auto_increment, name varchar(50) not null, primary key (id));`,
+		WithJSONTag(0), WithORM(ORMSqlx))
+	assert.NoError(t, err)
+	code = codes[CodeTypeDAOExtend]
+	assert.Contains(t, code, `"DELETE FROM tag WHERE id = ?", id)`)
+	assert.Contains(t, code, `"SELECT id, name FROM tag ORDER BY id DESC LIMIT ? OFFSET ?"`)
+	assert.NotContains(t, code, "now := time.Now()")
+
+	codes, err = ParseSQL(extendTestSQL, WithJSONTag(0))
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeModel], "gorm:\"column:order_no;not null\"")
+	assert.NotContains(t, codes[CodeTypeDAOExtend], "sqlx")
+
+	_, err = ParseSQL(extendTestSQL, WithORM("sqlX"))
+	assert.ErrorContains(t, err, `unsupported orm "sqlX"`)
+	_, err = ParseSQL(extendTestSQL, WithORM(ORMSqlx), WithCreateBatch(), WithAuditFields())
+	assert.ErrorContains(t, err, "options WithAuditFields, WithCreateBatch depend on gorm")
+	_, err = ParseSQL(extendTestSQL, WithORM(ORMStdlib), WithDBDriver(DBDriverMongodb))
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"github.com/zhufuyi/sqlparser/ast"
+)
+
+// getJoinKeys return the two columns of the composite primary key if the table is a pure join table,
+// which has no columns other than the two keys and the time columns, and both keys reference other tables
+// by foreign keys, return nil if it is not a join table
+func getJoinKeys(stmt *ast.CreateTableStmt) []string {
+	var keys []string
+	for _, con := range stmt.Constraints {
+		if con.Tp != ast.ConstraintPrimaryKey {
+			continue
+		}
+		if len(con.Keys) != 2 || con.Keys[0].Column == nil || con.Keys[1].Column == nil {
+			return nil
+		}
+		keys = []string{con.Keys[0].Column.Name.String(), con.Keys[1].Column.Name.String()}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	for _, col := range stmt.Cols {
+		switch colName := col.Name.Name.String(); colName {
+		case keys[0], keys[1], columnCreatedAt, columnUpdatedAt, columnDeletedAt:
+		default:
+			return nil
+		}
+	}
+
+	// a table with a composite primary key but no foreign keys is an ordinary table, crud code is generated for it
+	foreignKeys := getForeignKeyColumns(stmt)
+	if !foreignKeys[keys[0]] || !foreignKeys[keys[1]] {
+		return nil
+	}
+	return keys
+}
+
+// getForeignKeyColumns return the columns referencing other tables, declared by the FOREIGN KEY constraints
+// or the REFERENCES option of columns
+func getForeignKeyColumns(stmt *ast.CreateTableStmt) map[string]bool {
+	columns := make(map[string]bool)
+	for _, con := range stmt.Constraints {
+		if con.Tp != ast.ConstraintForeignKey {
+			continue
+		}
+		for _, key := range con.Keys {
+			if key.Column != nil {
+				columns[key.Column.Name.String()] = true
+			}
+		}
+	}
+	for _, col := range stmt.Cols {
+		for _, opt := range col.Options {
+			if opt.Tp == ast.ColumnOptionReference {
+				columns[col.Name.Name.String()] = true
+			}
+		}
+	}
+	return columns
+}
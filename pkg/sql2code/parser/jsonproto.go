@@ -0,0 +1,27 @@
+package parser
+
+import (
+	"regexp"
+)
+
+var protoMessageRegexp = regexp.MustCompile(`(?m)^\s*message\s+(\w+)\s*\{`)
+
+// getProtoMessageNames get the names of the messages defined in the proto code
+func getProtoMessageNames(protoCode string) map[string]bool {
+	names := make(map[string]bool)
+	for _, match := range protoMessageRegexp.FindAllStringSubmatch(protoCode, -1) {
+		names[match[1]] = true
+	}
+	return names
+}
+
+// getJSONProtoMessage get the nested message referenced by the json column, the message is named by the
+// field type of the column or the column name in camel case, return empty if the message is not defined.
+func getJSONProtoMessage(colName string, fieldType string, protoMessages map[string]bool) string {
+	for _, name := range []string{fieldType, toCamel(colName)} {
+		if name != "" && protoMessages[name] {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhufuyi/sqlparser/ast"
+)
+
+// tableIndex index defined in the table
+type tableIndex struct {
+	Name     string
+	Columns  []string
+	IsUnique bool
+}
+
+func newTableIndex(con *ast.Constraint, isUnique bool) tableIndex {
+	index := tableIndex{Name: con.Name, IsUnique: isUnique}
+	for _, key := range con.Keys {
+		if key.Column != nil {
+			index.Columns = append(index.Columns, key.Column.Name.String())
+		}
+	}
+	return index
+}
+
+// getName return the index name, the unnamed index is named by table and columns, example: idx_user_order_user_id
+func (index tableIndex) getName(tableName string) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	prefix := "idx_"
+	if index.IsUnique {
+		prefix = "uk_"
+	}
+	return prefix + tableName + "_" + strings.Join(index.Columns, "_")
+}
+
+// getIndexMigrationCode generate the up and down sql migration of the table indexes in sql-migrate format,
+// return empty string if the table has no index
+func getIndexMigrationCode(data tmplData) string {
+	var ups, downs []string
+	for _, index := range data.Indexes {
+		if len(index.Columns) == 0 {
+			continue
+		}
+		name := index.getName(data.RawTableName)
+		unique := ""
+		if index.IsUnique {
+			unique = "UNIQUE "
+		}
+		ups = append(ups, fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", unique, name, data.RawTableName, strings.Join(index.Columns, ", ")))
+
+		switch data.DBDriver {
+		case DBDriverPostgresql, DBDriverSqlite: // the index name is unique in schema
+			downs = append(downs, fmt.Sprintf("DROP INDEX %s;", name))
+		default:
+			downs = append(downs, fmt.Sprintf("DROP INDEX %s ON %s;", name, data.RawTableName))
+		}
+	}
+	if len(ups) == 0 {
+		return ""
+	}
+
+	// drop in reverse order of creation
+	for i, j := 0, len(downs)-1; i < j; i, j = i+1, j-1 {
+		downs[i], downs[j] = downs[j], downs[i]
+	}
+	return fmt.Sprintf("-- indexes of table %s\n\n-- +migrate Up\n%s\n\n-- +migrate Down\n%s\n",
+		data.RawTableName, strings.Join(ups, "\n"), strings.Join(downs, "\n"))
+}
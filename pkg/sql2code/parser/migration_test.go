@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQL_IndexMigration(t *testing.T) {
+	sql := `create table user_order (
+    id         bigint unsigned auto_increment,
+    order_no   varchar(36)     not null unique comment 'order no',
+    user_id    bigint unsigned not null comment 'user id',
+    status     varchar(20)     not null comment 'status',
+    primary key (id),
+    key idx_user_id (user_id),
+    unique key (user_id, status)
+);`
+	codes, err := ParseSQL(sql, WithIndexMigration())
+	assert.NoError(t, err)
+	assert.Equal(t, `-- indexes of table user_order
+
+-- +migrate Up
+CREATE INDEX idx_user_id ON user_order (user_id);
+CREATE UNIQUE INDEX uk_user_order_user_id_status ON user_order (user_id, status);
+CREATE UNIQUE INDEX order_no ON user_order (order_no);
+
+-- +migrate Down
+DROP INDEX order_no ON user_order;
+DROP INDEX uk_user_order_user_id_status ON user_order;
+DROP INDEX idx_user_id ON user_order;
+`, codes[CodeTypeIndexMigration])
+
+	codes, err = ParseSQL(sql, WithDBDriver(DBDriverPostgresql), WithIndexMigration())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeIndexMigration], "DROP INDEX idx_user_id;")
+
+	codes, err = ParseSQL(sql)
+	assert.NoError(t, err)
+	assert.NotContains(t, codes, CodeTypeIndexMigration)
+}
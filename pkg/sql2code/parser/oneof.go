@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// protoFieldLineRegexp match the field definition line of proto message, the submatch is field name,
+// repeated and map fields are not matched because they can not be in oneof
+var protoFieldLineRegexp = regexp.MustCompile(`^\s*(?:optional\s+)?[\w.]+\s+(\w+)\s*=\s*\d+`)
+
+// addProtoOneof group the fields of mutually exclusive columns into oneof in all messages of the proto code,
+// the group is skipped in a message which has less than two fields of the group
+func addProtoOneof(data tmplData, groups [][]string, code string) string {
+	if len(groups) == 0 {
+		return code
+	}
+
+	jsonNames := make(map[string]string, len(data.Fields)) // column name:json name
+	for _, field := range data.Fields {
+		jsonNames[field.ColName] = field.JSONName
+	}
+
+	lines := strings.Split(code, "\n")
+	for _, group := range groups {
+		var names []string
+		for _, col := range group {
+			if name, ok := jsonNames[col]; ok {
+				names = append(names, name)
+			}
+		}
+		if len(names) < 2 {
+			continue
+		}
+		lines = addMessageOneof(lines, customToSnake(strings.Join(group, "_or_")), names)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// addMessageOneof move the field lines of each message into a oneof block at the position of the first field
+func addMessageOneof(lines []string, oneofName string, fieldNames []string) []string {
+	isOneofField := make(map[string]bool, len(fieldNames))
+	for _, name := range fieldNames {
+		isOneofField[name] = true
+	}
+
+	newLines := make([]string, 0, len(lines)+2)
+	start := -1 // the index of the first line of current message
+	var fieldLines []int
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "message ") && strings.HasSuffix(strings.TrimSpace(line), "{"):
+			start, fieldLines = i, nil
+		case start >= 0 && strings.TrimSpace(line) == "}" && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, " "):
+			newLines = append(newLines, groupOneofLines(lines[start:i+1], fieldLines, start, oneofName)...)
+			start = -1
+			continue
+		case start >= 0:
+			if m := protoFieldLineRegexp.FindStringSubmatch(line); m != nil && isOneofField[m[1]] {
+				fieldLines = append(fieldLines, i)
+			}
+		}
+		if start < 0 {
+			newLines = append(newLines, line)
+		}
+	}
+	if start >= 0 { // unclosed message
+		newLines = append(newLines, lines[start:]...)
+	}
+	return newLines
+}
+
+func groupOneofLines(message []string, fieldLines []int, offset int, oneofName string) []string {
+	if len(fieldLines) < 2 {
+		return message
+	}
+
+	isFieldLine := make(map[int]bool, len(fieldLines))
+	for _, i := range fieldLines {
+		isFieldLine[i-offset] = true
+	}
+	lines := make([]string, 0, len(message)+2)
+	for i, line := range message {
+		if !isFieldLine[i] {
+			lines = append(lines, line)
+			continue
+		}
+		if i != fieldLines[0]-offset {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		lines = append(lines, indent+"oneof "+oneofName+" {")
+		for _, j := range fieldLines {
+			lines = append(lines, indent+message[j-offset])
+		}
+		lines = append(lines, indent+"}")
+	}
+	return lines
+}
@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQL_Oneof(t *testing.T) {
+	sql := `create table user_contact (
+    id         bigint unsigned auto_increment,
+    name       varchar(50) not null comment 'name',
+    email      varchar(100) null comment 'email',
+    phone      varchar(20) null comment 'phone',
+    remark     varchar(255) null comment 'remark',
+    primary key (id)
+);`
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithNullStyle(NullDisable), WithOneof([][]string{{"email", "phone"}, {"remark", "not_exist"}}))
+	assert.NoError(t, err)
+	code := codes[CodeTypeProto]
+	assert.Contains(t, code, `message CreateUserContactRequest {
+	string name = 1;  // name
+	oneof email_or_phone {
+		string email = 2;  // email
+		string phone = 3;  // phone
+	}
+	string remark = 4;  // remark
+}`)
+	assert.Contains(t, code, "\toneof email_or_phone {\n\t\tstring email = 3;  // email\n\t\tstring phone = 4;  // phone\n\t}\n\tstring remark = 5;")
+	assert.NotContains(t, code, "oneof remark")
+
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithNullStyle(NullDisable))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeProto], "oneof")
+}
@@ -1,5 +1,7 @@
 package parser
 
+import "time"
+
 // NullStyle null type
 type NullStyle int
 
@@ -32,6 +34,58 @@ type options struct {
 	IsExtendedAPI  bool              // true: extended api (9 api), false: basic api (5 api)
 
 	IsCustomTemplate bool // true: custom extend template, false: use milady template
+
+	IsGRPCRegister   bool // generate grpc server registration code
+	IsGRPCReflection bool // register grpc reflection service in registration code
+	IsCreateBatch    bool // generate batch create dao method
+	IsTypedErrors    bool // generate typed error sentinels, extended dao methods return them
+	IsRestore        bool // generate restore api for soft deleted records
+	IsUUIDHook       bool // generate BeforeCreate hook which sets uuid primary key
+
+	DistinctColumns       []string      // columns which generate Distinct<Column> dao methods
+	IsSwaggerTags         bool          // add swagger tags to the rpc of web proto, group endpoints by entity
+	IsListNDJSON          bool          // generate handler which streams list rows as newline-delimited JSON
+	IsConvertPB           bool          // generate conversion functions between model and proto message
+	IsRWSplit             bool          // generate read write split dao, reads use replica db, writes use primary db
+	MaskedColumns         []string      // string columns whose values are masked in model json output
+	QueryTimeout          time.Duration // default timeout of each query in generated dao methods
+	IsTypedFilter         bool          // generate typed filter struct which converts to query conditions
+	InfoSeparator         string        // separator of multiple tables crud info and table info
+	IsOpenAPIExamples     bool          // add openapi examples of request fields from column default values and comments
+	IsListByTimeRange     bool          // generate list by created_at time range dao method and rpc
+	IsFieldMask           bool          // add update_mask to update request, generate the code updating the masked fields only
+	IsGatewayErrorHandler bool          // generate grpc-gateway error handler which maps typed errors to http status
+	IsRepository          bool          // generate repository interface and its gorm implementation
+	NoColumnWhitelist     bool          // do not generate the <Table>ColumnNames whitelist map of model
+	IsFieldErrors         bool          // generate create handler which responds all field validation errors at once
+	IsCountByGroup        bool          // generate count by group column dao method and rpc
+	IsETag                bool          // generate get handler supporting ETag and If-None-Match
+	IsDaoMetrics          bool          // generate repository decorator recording prometheus metrics of each call
+	IsSeed                bool          // generate seed function and sample fixtures
+	IsForUpdate           bool          // generate get by primary key dao method with FOR UPDATE row locking
+	OneofGroups           [][]string    // groups of mutually exclusive columns, each group is a oneof in proto messages
+	IsUpsert              bool          // generate upsert dao method which conflicts on the unique key
+	IsIndexMigration      bool          // generate sql migration which creates the table indexes
+	IsExplicitNullUpdates bool          // update pointer fields when they are not nil, even if they point to zero value
+	ClientRetries         uint          // retry times of the idempotent read methods in generated grpc client
+	ClientRetryBackoff    time.Duration // interval between retries of generated grpc client
+	MaxBodyBytes          int64         // max bytes of request body read by generated create and update handlers
+	SearchColumns         []string      // text columns matched by the keyword of generated Search method
+	IsCSVExport           bool          // generate handler which exports the rows matching conditions as csv
+	IsCursorList          bool          // generate List method paginated by opaque cursor tokens
+	IsDaoErrorWrapping    bool          // wrap the errors returned by dao methods with the method name as context
+	IsUpdateByCondition   bool          // generate dao method which updates the records matching conditions
+	IsAuditFields         bool          // embed sgorm audit struct in model, the hooks set the users from context
+	IsAuthorizationHook   bool          // generate update and delete handlers calling a resource level authorization hook
+	IsCacheKey            bool          // generate model method returning the cache key built from the primary key
+	IsEnumConst           bool          // generate go constants and proto enums of the enum columns
+	IsSortValidation      bool          // validate the sort columns of list params against the column whitelist
+	ORM                   string        // orm of models and dao, gorm (default), sqlx or stdlib
+
+	LayerPackages map[string]string // package names of the dao, handler and service layers, key is code type
+
+	checkRules map[string]map[string][]checkRule // rules parsed from CHECK constraints, table -> column -> rules
+	isView     bool                              // the statement is a view, the model is read-only
 }
 
 var defaultOptions = options{
@@ -39,6 +93,8 @@ var defaultOptions = options{
 	FieldTypes: map[string]string{},
 	NullStyle:  NullInSql,
 	Package:    "model",
+
+	InfoSeparator: DefaultInfoSeparator,
 }
 
 // WithDBDriver set db driver
@@ -158,6 +214,379 @@ func WithCustomTemplate() Option {
 	}
 }
 
+// WithGRPCRegister generate grpc server registration code, enableReflection is true to register reflection service too
+func WithGRPCRegister(enableReflection bool) Option {
+	return func(o *options) {
+		o.IsGRPCRegister = true
+		o.IsGRPCReflection = enableReflection
+	}
+}
+
+// WithCreateBatch generate CreateBatch dao method which inserts records in chunks
+func WithCreateBatch() Option {
+	return func(o *options) {
+		o.IsCreateBatch = true
+	}
+}
+
+// WithTypedErrors generate typed error sentinels for each table, such as ErrFooBarNotFound,
+// the standard crud methods of dao are generated and they convert driver errors to them like the extended
+// dao methods, so callers can use errors.Is
+func WithTypedErrors() Option {
+	return func(o *options) {
+		o.IsTypedErrors = true
+	}
+}
+
+// WithRestore generate restore rpc, handler and dao method which undo the soft delete,
+// it only takes effect for the table with deleted_at column
+func WithRestore() Option {
+	return func(o *options) {
+		o.IsRestore = true
+	}
+}
+
+// WithUUIDHook generate BeforeCreate hook in model which sets a new uuid if the primary key is empty,
+// it only takes effect when the primary key is uuid or char(36) column
+func WithUUIDHook() Option {
+	return func(o *options) {
+		o.IsUUIDHook = true
+	}
+}
+
+// WithDistinctColumns generate Distinct<Column> dao methods which return the distinct values of
+// the columns, suitable for low-cardinality columns such as status, columns not in table are ignored
+func WithDistinctColumns(columns ...string) Option {
+	return func(o *options) {
+		o.DistinctColumns = append(o.DistinctColumns, columns...)
+	}
+}
+
+// WithSwaggerTags add openapiv2_operation tags to each rpc of web proto, the tag is the table name,
+// so that swagger-ui groups the endpoints by entity, it only takes effect with WithWebProto
+func WithSwaggerTags() Option {
+	return func(o *options) {
+		o.IsSwaggerTags = true
+	}
+}
+
+// WithListNDJSON generate ListNDJSON handler which queries the list page by page and streams
+// the rows as newline-delimited JSON, so that large results are not buffered in memory
+func WithListNDJSON() Option {
+	return func(o *options) {
+		o.IsListNDJSON = true
+	}
+}
+
+// WithConvertPB generate ConvertFooBarToPB and ConvertPBToFooBar functions, which convert between
+// model struct and proto detail message, handling the differences of time, decimal, json fields
+func WithConvertPB() Option {
+	return func(o *options) {
+		o.IsConvertPB = true
+	}
+}
+
+// WithReadWriteSplit generate a read write split dao whose constructor takes the primary db and
+// the replica db, the read methods use the replica db and the write methods use the primary db,
+// it is invalid for mongodb
+func WithReadWriteSplit() Option {
+	return func(o *options) {
+		o.IsRWSplit = true
+	}
+}
+
+// WithMaskedColumns generate MarshalJSON method of model which masks the values of the columns,
+// such as email, phone, only string columns take effect
+func WithMaskedColumns(columns []string) Option {
+	return func(o *options) {
+		o.MaskedColumns = append(o.MaskedColumns, columns...)
+	}
+}
+
+// WithQueryTimeout the generated dao methods wrap the context with timeout before executing, the standard crud
+// methods of dao are generated to do so too, the timeout of a call can be overridden by the generated
+// With{Table}QueryTimeout function
+func WithQueryTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.QueryTimeout = d
+	}
+}
+
+// WithTypedFilter generate typed filter struct of table, the filter converts to query conditions by ToConditions
+func WithTypedFilter() Option {
+	return func(o *options) {
+		o.IsTypedFilter = true
+	}
+}
+
+// WithInfoSeparator set the separator of multiple tables crud info and table info, default is DefaultInfoSeparator,
+// ParseSQL returns an error if the info of a table contains the separator
+func WithInfoSeparator(sep string) Option {
+	return func(o *options) {
+		if sep != "" {
+			o.InfoSeparator = sep
+		}
+	}
+}
+
+// WithOpenAPIExamples add examples to the request fields of web proto and handler structs, the example value
+// comes from the comment hint (e.g. 'status, example: active') or the column default value
+func WithOpenAPIExamples() Option {
+	return func(o *options) {
+		o.IsOpenAPIExamples = true
+	}
+}
+
+// WithListByTimeRange generate ListByCreatedAtRange dao method and rpc, only for the table with created_at column
+func WithListByTimeRange() Option {
+	return func(o *options) {
+		o.IsListByTimeRange = true
+	}
+}
+
+// WithFieldMask add google.protobuf.FieldMask update_mask to the update request message, and generate
+// the service code converting the masked fields to columns and the dao method updating the columns
+func WithFieldMask() Option {
+	return func(o *options) {
+		o.IsFieldMask = true
+	}
+}
+
+// WithGatewayErrorHandler generate grpc-gateway error handler which maps the typed errors to http status,
+// not found -> 404, already exists -> 409, invalid params -> 400, the typed errors are generated too
+func WithGatewayErrorHandler() Option {
+	return func(o *options) {
+		o.IsGatewayErrorHandler = true
+		o.IsTypedErrors = true
+	}
+}
+
+// WithRepository generate FooBarRepository interface with the crud methods and the gorm implementation
+// fooBarRepo, services depend on the interface so that it can be replaced in tests, not supported for mongodb
+func WithRepository() Option {
+	return func(o *options) {
+		o.IsRepository = true
+	}
+}
+
+// WithoutColumnWhitelist do not generate the <Table>ColumnNames whitelist map of model, it is only used by
+// custom condition queries, the map is noise for wide tables if the project never uses them
+func WithoutColumnWhitelist() Option {
+	return func(o *options) {
+		o.NoColumnWhitelist = true
+	}
+}
+
+// WithFieldErrors generate the handler helper which binds the request and collects every field validation error
+// into a structured response, and the CreateWithFieldErrors handler which uses it, suitable for forms
+func WithFieldErrors() Option {
+	return func(o *options) {
+		o.IsFieldErrors = true
+	}
+}
+
+// WithCountByGroup generate CountByGroup dao method and rpc which count the records grouped by a column,
+// the group column is validated against the column whitelist of model, ignored by WithoutColumnWhitelist
+func WithCountByGroup() Option {
+	return func(o *options) {
+		o.IsCountByGroup = true
+	}
+}
+
+// WithETag generate Get<PK>WithETag handler which sets the ETag computed from the id and updated_at of record,
+// and responds 304 if it matches If-None-Match, it only takes effect for the table with updated_at column
+func WithETag() Option {
+	return func(o *options) {
+		o.IsETag = true
+	}
+}
+
+// WithDaoMetrics generate the repository decorator which records the duration histogram and error counter
+// of each dao call with prometheus, the repository is generated too, not supported for mongodb
+func WithDaoMetrics() Option {
+	return func(o *options) {
+		o.IsDaoMetrics = true
+		o.IsRepository = true
+	}
+}
+
+// WithSeed generate the Seed function which bulk inserts fixture items and a sample fixtures slice,
+// used to prepare data for local development and tests
+func WithSeed() Option {
+	return func(o *options) {
+		o.IsSeed = true
+	}
+}
+
+// WithForUpdate generate the GetByIDForUpdate dao method which locks the row with SELECT ... FOR UPDATE
+// in the transaction, not supported for mongodb and sqlite
+func WithForUpdate() Option {
+	return func(o *options) {
+		o.IsForUpdate = true
+	}
+}
+
+// WithOneof group the mutually exclusive columns into a proto oneof, each group is a list of column names,
+// the oneof name is the column names joined with _or_, example: [][]string{{"email", "phone"}} --> oneof email_or_phone.
+// Note: the fields of oneof are wrapped types in the generated go code, the code which sets the fields needs to be adapted
+func WithOneof(groups [][]string) Option {
+	return func(o *options) {
+		o.OneofGroups = groups
+	}
+}
+
+// WithUpsert generate the Upsert dao method which creates a record or updates it if the unique key conflicts,
+// the first unique index of the table is used as the conflict key, composite unique index is supported,
+// it is not generated if the table has no unique index, not supported for mongodb
+func WithUpsert() Option {
+	return func(o *options) {
+		o.IsUpsert = true
+	}
+}
+
+// WithIndexMigration generate the up and down sql migration of the indexes defined in each table,
+// the code type is CodeTypeIndexMigration, not supported for mongodb
+func WithIndexMigration() Option {
+	return func(o *options) {
+		o.IsIndexMigration = true
+	}
+}
+
+// WithExplicitNullUpdates update the pointer fields in the generated update code when the pointer is not nil,
+// even if it points to zero value, so a field can be set back to empty, example: NullInPointer style fields
+func WithExplicitNullUpdates() Option {
+	return func(o *options) {
+		o.IsExplicitNullUpdates = true
+	}
+}
+
+// WithClientRetries generate the grpc client which retries the idempotent read methods Get and List,
+// n is the retry times, backoff is the interval between retries, the code type is CodeTypeGRPCClient
+func WithClientRetries(n uint, backoff time.Duration) Option {
+	return func(o *options) {
+		o.ClientRetries = n
+		o.ClientRetryBackoff = backoff
+	}
+}
+
+// WithMaxBodyBytes limit the request body of generated create and update handlers to n bytes by http.MaxBytesReader,
+// the oversized request is responded with 413, the code type is CodeTypeHandlerExtend
+func WithMaxBodyBytes(n int64) Option {
+	return func(o *options) {
+		o.MaxBodyBytes = n
+	}
+}
+
+// WithSearch generate Search dao method, handler and rpc which match the keyword against the text columns
+// and filter the records by conditions, the keyword is matched by LIKE in sql databases and by $text in mongodb,
+// the collection of mongodb requires a text index on the columns
+func WithSearch(columns ...string) Option {
+	return func(o *options) {
+		o.SearchColumns = append(o.SearchColumns, columns...)
+	}
+}
+
+// WithCSVExport generate the Export<Table>CSV handler which streams the rows matching the conditions as csv,
+// the first row is the column names
+func WithCSVExport() Option {
+	return func(o *options) {
+		o.IsCSVExport = true
+	}
+}
+
+// WithCursorList generate the List method which pages by an opaque cursor instead of the last id,
+// the cursor encodes the primary key of the last record of the previous page
+func WithCursorList() Option {
+	return func(o *options) {
+		o.IsCursorList = true
+	}
+}
+
+// WithDaoErrorWrapping wrap the errors returned by the dao methods with the method name, the standard crud
+// methods of dao are generated to wrap their errors like the extended dao methods, example: dao.GetByID: record
+// not found, the wrapped errors can still be checked by errors.Is, the handlers and services are not changed
+func WithDaoErrorWrapping() Option {
+	return func(o *options) {
+		o.IsDaoErrorWrapping = true
+	}
+}
+
+// WithUpdateByCondition generate the UpdateByCondition dao method which updates the specified columns of
+// all records matching the conditions and returns the affected count, the empty conditions are rejected
+func WithUpdateByCondition() Option {
+	return func(o *options) {
+		o.IsUpdateByCondition = true
+	}
+}
+
+// WithAuditFields embed the sgorm.Audit struct (created_by, updated_by, created_at, updated_at) in the
+// generated model instead of the columns of the table, the create and update of dao set created_by and
+// updated_by to the user of the context set by sgorm.WithAuditUser, it is ignored by mongodb.
+// If WithEmbed is set, sgorm.AuditUser is embedded because sgorm.Model already has the time fields.
+func WithAuditFields() Option {
+	return func(o *options) {
+		o.IsAuditFields = true
+	}
+}
+
+// WithAuthorizationHook generate Update<PK>WithAuthorization and Delete<PK>WithAuthorization handlers,
+// they call the authorize<Table> hook stub with the target primary key and the jwt claims before mutating,
+// the hook is a TODO to implement the resource level authorization, e.g. only the owner can update
+func WithAuthorizationHook() Option {
+	return func(o *options) {
+		o.IsAuthorizationHook = true
+	}
+}
+
+// WithCacheKey generate the CacheKey method of model, the key is the table name and the primary key
+// joined by colon, e.g. foo_bar:123, it is not generated for the join tables
+func WithCacheKey() Option {
+	return func(o *options) {
+		o.IsCacheKey = true
+	}
+}
+
+// WithEnumConst generate the go constants of the values of enum columns in model, and the proto3 enums
+// referenced by the fields of messages, the zero value of proto enum is <TABLE>_<COLUMN>_UNSPECIFIED.
+// copier can not copy between string and proto enum, the conversion functions of WithConvertPB are generated too.
+func WithEnumConst() Option {
+	return func(o *options) {
+		o.IsEnumConst = true
+		o.IsConvertPB = true
+	}
+}
+
+// WithSortValidation generate the validate<Table>Sort function which rejects the sort columns of query params
+// not in the column whitelist of model to prevent order by injection, the standard crud methods of dao are
+// generated and their GetByColumns validates the sort before querying, so do the List of read-only dao and
+// GetByColumns of repository, ignored by WithoutColumnWhitelist
+func WithSortValidation() Option {
+	return func(o *options) {
+		o.IsSortValidation = true
+	}
+}
+
+// WithORM set the orm of models and dao, value is ORMGorm (default), ORMSqlx or ORMStdlib, the models of sqlx
+// and stdlib have db tags instead of gorm tags, the dao uses sqlx or database/sql with hand-written queries,
+// ParseSQL returns error if the orm is unknown or the options depending on gorm are used with sqlx or stdlib
+func WithORM(orm string) Option {
+	return func(o *options) {
+		o.ORM = orm
+	}
+}
+
+// WithLayerPackage set the package name of a layer, layer is CodeTypeDAO, CodeTypeHandler or CodeTypeService,
+// the codes of the layer that are complete go files start with the package clause, and the model types are
+// referenced by the package name set by WithPackage
+func WithLayerPackage(layer string, pkg string) Option {
+	return func(o *options) {
+		if o.LayerPackages == nil {
+			o.LayerPackages = make(map[string]string)
+		}
+		o.LayerPackages[layer] = pkg
+	}
+}
+
 // parseOption apply options to override default options
 func parseOption(options []Option) options {
 	o := defaultOptions
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"go/format"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
@@ -38,6 +39,25 @@ const (
 	CodeTypeCrudInfo = "crud_info"
 	// CodeTypeTableInfo table info json data
 	CodeTypeTableInfo = "table_info"
+	// CodeTypeGRPCRegister grpc server registration code
+	CodeTypeGRPCRegister = "grpc_register"
+	// CodeTypeDAOExtend extended dao methods code enabled by options
+	CodeTypeDAOExtend = "dao_extend"
+	// CodeTypeError typed error sentinels code
+	CodeTypeError = "error"
+	// CodeTypeHandlerExtend extended gin handler methods code enabled by options
+	CodeTypeHandlerExtend = "handler_extend"
+	// CodeTypeConvert conversion functions between model and proto message
+	CodeTypeConvert = "convert"
+	// CodeTypeServiceExtend extended grpc service code enabled by options
+	CodeTypeServiceExtend = "service_extend"
+	// CodeTypeIndexMigration up and down sql migration of table indexes
+	CodeTypeIndexMigration = "index_migration"
+	// CodeTypeGRPCClient grpc client code enabled by options
+	CodeTypeGRPCClient = "grpc_client"
+
+	// DefaultInfoSeparator default separator of multiple tables crud info and table info
+	DefaultInfoSeparator = " |||| "
 
 	// DBDriverMysql mysql driver
 	DBDriverMysql = "mysql"
@@ -49,6 +69,15 @@ const (
 	DBDriverSqlite = "sqlite"
 	// DBDriverMongodb mongodb driver
 	DBDriverMongodb = "mongodb"
+	// DBDriverClickHouse clickhouse driver, read-only analytics models
+	DBDriverClickHouse = "clickhouse"
+
+	// ORMGorm models and dao use gorm, it is the default
+	ORMGorm = "gorm"
+	// ORMSqlx models have db tags, dao uses sqlx with hand-written queries
+	ORMSqlx = "sqlx"
+	// ORMStdlib models have db tags, dao uses database/sql with hand-written queries
+	ORMStdlib = "stdlib"
 
 	jsonTypeName     = "datatypes.JSON"
 	jsonPkgPath      = "gorm.io/datatypes"
@@ -92,9 +121,14 @@ type modelCodes struct {
 func ParseSQL(sql string, options ...Option) (map[string]string, error) {
 	initTemplate()
 	initCommonTemplate()
+	initExtendTemplate()
 	// 解析选项
 	opt := parseOption(options)
+	if err := opt.checkORM(); err != nil {
+		return nil, err
+	}
 
+	sql, opt.checkRules = parseCheckConstraints(sql)
 	stmts, err := parser.New().Parse(sql, opt.Charset, opt.Collation)
 	if err != nil {
 		return nil, err
@@ -109,14 +143,32 @@ func ParseSQL(sql string, options ...Option) (map[string]string, error) {
 	tableNames := make([]string, 0, len(stmts))
 	primaryKeysCodes := make([]string, 0, len(stmts))
 	tableInfoCodes := make([]string, 0, len(stmts))
+	extendCodes := make(map[string][]string)
+	tables := make(map[string]*ast.CreateTableStmt)
 	for _, stmt := range stmts {
 		if ct, ok := stmt.(*ast.CreateTableStmt); ok {
-			code, err2 := makeCode(ct, opt)
+			tables[ct.Table.Name.L] = ct
+		}
+	}
+	for _, stmt := range stmts {
+		ct, ok := stmt.(*ast.CreateTableStmt)
+		codeOpt := opt
+		if cv, isView := stmt.(*ast.CreateViewStmt); isView {
+			ct, err = viewToCreateTable(cv, tables)
+			if err != nil {
+				return nil, err
+			}
+			ok, codeOpt.isView = true, true
+		}
+		if ok {
+			code, err2 := makeCode(ct, codeOpt)
 			if err2 != nil {
 				return nil, err2
 			}
 			modelStructCodes = append(modelStructCodes, code.modelStruct)
-			updateFieldsCodes = append(updateFieldsCodes, code.updateFields)
+			if code.updateFields != "" {
+				updateFieldsCodes = append(updateFieldsCodes, code.updateFields)
+			}
 			handlerStructCodes = append(handlerStructCodes, code.handlerStruct)
 			protoFileCodes = append(protoFileCodes, code.protoFile)
 			serviceStructCodes = append(serviceStructCodes, code.serviceStruct)
@@ -127,6 +179,9 @@ func ParseSQL(sql string, options ...Option) (map[string]string, error) {
 			for _, s := range code.importPaths {
 				importPath[s] = struct{}{}
 			}
+			for k, v := range code.extendCodes {
+				extendCodes[k] = append(extendCodes[k], v)
+			}
 		}
 	}
 
@@ -146,6 +201,15 @@ func ParseSQL(sql string, options ...Option) (map[string]string, error) {
 		return nil, err
 	}
 
+	if len(tableNames) > 1 {
+		for i, tableName := range tableNames {
+			if strings.Contains(primaryKeysCodes[i], opt.InfoSeparator) || strings.Contains(tableInfoCodes[i], opt.InfoSeparator) {
+				return nil, fmt.Errorf("the info of table %s contains the separator %q, use WithInfoSeparator to set another separator",
+					tableName, opt.InfoSeparator)
+			}
+		}
+	}
+
 	var codesMap = map[string]string{
 		CodeTypeModel:     modelCode,
 		CodeTypeJSON:      strings.Join(modelJSONCodes, "\n\n"),
@@ -154,9 +218,13 @@ func ParseSQL(sql string, options ...Option) (map[string]string, error) {
 		CodeTypeProto:     strings.Join(protoFileCodes, "\n\n"),
 		CodeTypeService:   strings.Join(serviceStructCodes, "\n\n"),
 		TableName:         strings.Join(tableNames, ", "),
-		CodeTypeCrudInfo:  strings.Join(primaryKeysCodes, " |||| "),
-		CodeTypeTableInfo: strings.Join(tableInfoCodes, " |||| "),
+		CodeTypeCrudInfo:  strings.Join(primaryKeysCodes, opt.InfoSeparator),
+		CodeTypeTableInfo: strings.Join(tableInfoCodes, opt.InfoSeparator),
+	}
+	for k, v := range extendCodes {
+		codesMap[k] = strings.Join(v, "\n\n")
 	}
+	addLayerPackageClauses(codesMap, opt.LayerPackages)
 
 	return codesMap, nil
 }
@@ -173,6 +241,8 @@ type tmplData struct {
 	SubStructs      string      // sub structs for model
 	ProtoSubStructs string      // sub structs for protobuf
 	DBDriver        string
+	Indexes         []tableIndex // indexes defined in the table
+	JoinKeys        []string     // the two columns of composite primary key if the table is a pure join table
 
 	CrudInfo *CrudInfo
 }
@@ -186,8 +256,16 @@ type tmplField struct {
 	Comment      string
 	JSONName     string // json tag
 	DBDriver     string
-
-	rewriterField *rewriterField
+	IsUUID       bool // column type is uuid or char(36)
+
+	rewriterField   *rewriterField
+	checkRules      []checkRule // simple range rules parsed from CHECK constraints
+	example         string      // example value of openapi, from default value or comment hint
+	isExplicitNull  bool        // update the pointer field if it is not nil, even if it points to zero value
+	protoMessage    string      // nested proto message of the json column, defined in ProtoSubStructs
+	protoEnum       string      // proto enum of the enum column, defined in ProtoSubStructs
+	isAutoIncrement bool        // the column is auto increment
+	enumValues      []string    // values of the enum column
 }
 
 type rewriterField struct {
@@ -208,6 +286,9 @@ func (d tmplData) isCommonStyle(isEmbed bool) bool {
 
 // ConditionZero type of condition 0, used in dao template code
 func (t tmplField) ConditionZero() string {
+	if t.isExplicitNull && strings.HasPrefix(t.GoType, "*") {
+		return ` != nil` //nolint
+	}
 	if t.DBDriver == DBDriverMysql || t.DBDriver == DBDriverPostgresql || t.DBDriver == DBDriverTidb {
 		if t.rewriterField != nil {
 			switch t.rewriterField.goType {
@@ -362,7 +443,7 @@ func (t tmplField) AddOneWithTag(i int) string {
 		}
 		return fmt.Sprintf(`%d [(validate.rules).%s.gt = 0, (tagger.tags) = "uri:\"id\""]`, i+1, t.GoType)
 	}
-	return fmt.Sprintf("%d", i+1)
+	return t.AddOneWithCheck(i)
 }
 
 func (t tmplField) AddOneWithTag2(i int) string {
@@ -372,7 +453,7 @@ func (t tmplField) AddOneWithTag2(i int) string {
 		}
 		return fmt.Sprintf(`%d [(validate.rules).%s.gt = 0, (tagger.tags) = "uri:\"%s\""]`, i+1, t.GoType, t.JSONName)
 	}
-	return fmt.Sprintf("%d", i+1)
+	return t.AddOneWithCheck(i)
 }
 
 func getProtoFieldName(fields []tmplField) string {
@@ -387,6 +468,7 @@ func getProtoFieldName(fields []tmplField) string {
 const (
 	__mysqlModel__ = "__mysqlModel__" //nolint
 	__type__       = "__type__"       //nolint
+	__auditModel__ = "__auditModel__" //nolint
 )
 
 // replaceFields 替换字段类型为 sgorm.Model
@@ -402,8 +484,30 @@ const (
 	columnUpdatedAt  = "updated_at"
 	columnDeletedAt  = "deleted_at"
 	columnMysqlModel = __mysqlModel__
+	columnCreatedBy  = "created_by"
+	columnUpdatedBy  = "updated_by"
 )
 
+// auditColumns the columns of the audit struct embedded by WithAuditFields
+var auditColumns = []string{columnCreatedBy, columnUpdatedBy, columnCreatedAt, columnUpdatedAt}
+
+// isAuditColumn return true if the column is replaced by the embedded audit struct
+func isAuditColumn(colName string) bool {
+	return slices.Contains(auditColumns, colName)
+}
+
+// getAuditEmbed return the audit struct embedded in model, the time fields are in sgorm.Model if it is embedded
+func getAuditEmbed(isEmbed bool, jsonNamedType int) string {
+	embed := "Audit"
+	if isEmbed {
+		embed = "AuditUser"
+	}
+	if jsonNamedType == 0 { // snake case
+		embed += "2"
+	}
+	return embed
+}
+
 var ignoreColumns = map[string]struct{}{
 	columnID:         {},
 	columnCreatedAt:  {},
@@ -472,6 +576,7 @@ type codeText struct {
 	serviceStruct string
 	crudInfo      string
 	tableInfo     []byte
+	extendCodes   map[string]string // optional codes enabled by options, key is code type
 }
 
 // nolint
@@ -527,16 +632,30 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 		}
 	}
 
+	data.JoinKeys = getJoinKeys(stmt)
+
 	isPrimaryKey := make(map[string]bool)
 	for _, con := range stmt.Constraints {
 		if con.Tp == ast.ConstraintPrimaryKey {
 			isPrimaryKey[con.Keys[0].Column.String()] = true
+			if len(data.JoinKeys) > 0 { // both keys of join table are primary key
+				isPrimaryKey[con.Keys[1].Column.String()] = true
+			}
 		}
 		if con.Tp == ast.ConstraintForeignKey {
 			// TODO: foreign key support
 		}
+		switch con.Tp {
+		case ast.ConstraintIndex, ast.ConstraintKey:
+			data.Indexes = append(data.Indexes, newTableIndex(con, false))
+		case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+			data.Indexes = append(data.Indexes, newTableIndex(con, true))
+		}
 	}
 
+	// the json columns reference the nested messages defined in proto sub structs
+	protoMessages := getProtoMessageNames(opt.FieldTypes[ProtoSubStructKey])
+
 	// handle sql column
 	columnPrefix := opt.ColumnPrefix
 	for _, col := range stmt.Cols {
@@ -557,6 +676,9 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 			Name:     goFieldNameData,
 			ColName:  colName,
 			JSONName: jsonName,
+			IsUUID:   isUUIDColumn(col.Tp, opt.FieldTypes[colName]),
+
+			checkRules: opt.checkRules[strings.ToLower(data.RawTableName)][strings.ToLower(colName)],
 		}
 
 		tags := make([]string, 0, 4)
@@ -569,7 +691,7 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 			switch opt.DBDriver {
 			case DBDriverMysql, DBDriverTidb, DBDriverSqlite:
 				gormTag.WriteString(col.Tp.InfoSchemaStr())
-			case DBDriverPostgresql:
+			case DBDriverPostgresql, DBDriverClickHouse:
 				gormTag.WriteString(opt.FieldTypes[colName])
 			}
 		}
@@ -590,13 +712,18 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 				isNotNull = true
 			case ast.ColumnOptionAutoIncrement:
 				gormTag.WriteString(";AUTO_INCREMENT")
+				field.isAutoIncrement = true
 			case ast.ColumnOptionDefaultValue:
+				if opt.IsOpenAPIExamples && o.Expr.GetDatum().Kind() != types.KindNull && field.example == "" {
+					field.example = fmt.Sprintf("%v", o.Expr.GetDatum().GetValue())
+				}
 				if value := getDefaultValue(o.Expr); value != "" {
 					gormTag.WriteString(";default:")
 					gormTag.WriteString(value)
 				}
 			case ast.ColumnOptionUniqKey:
 				gormTag.WriteString(";unique")
+				data.Indexes = append(data.Indexes, tableIndex{Name: colName, Columns: []string{colName}, IsUnique: true})
 			case ast.ColumnOptionNull:
 				//gormTag.WriteString(";NULL")
 				canNull = true
@@ -604,6 +731,11 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 			case ast.ColumnOptionFulltext:
 			case ast.ColumnOptionComment:
 				field.Comment = replaceCommentNewline(o.Expr.GetDatum().GetString())
+				if opt.IsOpenAPIExamples {
+					if example := getCommentExample(field.Comment); example != "" {
+						field.example = example
+					}
+				}
 			default:
 				//return "", nil, errors.Errorf(" unsupport option %d\n", o.Tp)
 			}
@@ -630,7 +762,11 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 			if !isPrimaryKey[colName] && isNotNull {
 				gormTag.WriteString(";not null")
 			}
-			tags = append(tags, "gorm", gormTag.String())
+			if opt.isSQLORM() {
+				tags = append(tags, "db", colName)
+			} else {
+				tags = append(tags, "gorm", gormTag.String())
+			}
 
 			if opt.JSONTag {
 				tags = append(tags, "json", jsonName)
@@ -648,11 +784,27 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 			}
 			field.GoType = goType
 			field.rewriterField = rrField
+			if col.Tp.Tp == mysql.TypeJSON {
+				field.protoMessage = getJSONProtoMessage(colName, opt.FieldTypes[colName], protoMessages)
+			}
+			if opt.IsEnumConst && col.Tp.Tp == mysql.TypeEnum && len(col.Tp.Elems) > 0 {
+				field.protoEnum = data.TableName + field.Name
+				field.enumValues = col.Tp.Elems
+			}
 			if opt.DBDriver == DBDriverPostgresql {
 				if opt.FieldTypes[colName] == "bool" {
 					field.GoType = "bool" // rewritten type
 				}
 			}
+			if opt.DBDriver == DBDriverClickHouse {
+				if chGoType := clickHouseGoType(opt.FieldTypes[colName]); chGoType != "" {
+					field.GoType = chGoType // rewritten type
+					field.rewriterField = nil
+					if strings.HasSuffix(chGoType, "time.Time") {
+						importPath = append(importPath, "time")
+					}
+				}
+			}
 		}
 
 		data.Fields = append(data.Fields, field)
@@ -666,6 +818,10 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 	if v, ok := opt.FieldTypes[ProtoSubStructKey]; ok {
 		data.ProtoSubStructs = v
 	}
+	// the enum columns reference the proto enums
+	if enumCode := getProtoEnumCode(data.Fields); enumCode != "" {
+		data.ProtoSubStructs = strings.TrimSpace(data.ProtoSubStructs + "\n\n" + enumCode)
+	}
 
 	if len(data.Fields) == 0 {
 		return nil, errors.New("no columns found in table " + data.TableName)
@@ -680,15 +836,27 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 	}
 
 	// 生成 model 结构体代码
-	modelStructCode, importPaths, err := getModelStructCode(data, importPath, opt.IsEmbed, opt.JSONNamedType)
+	isAudit := opt.IsAuditFields && data.DBDriver != DBDriverMongodb
+	modelStructCode, importPaths, err := getModelStructCode(data, importPath, opt.IsEmbed, isAudit, opt.JSONNamedType, !opt.NoColumnWhitelist)
 	if err != nil {
 		return nil, err
 	}
-
-	updateFieldsCode, err := getUpdateFieldsCode(data, opt.IsEmbed)
+	modelHookCode, hookImportPaths, err := getModelHookCode(data, opt)
 	if err != nil {
 		return nil, err
 	}
+	modelStructCode += modelHookCode
+	importPaths = append(importPaths, hookImportPaths...)
+
+	// views and clickhouse models are read-only, the update fields code is not generated,
+	// join tables are managed by the association dao methods instead of crud
+	updateFieldsCode := ""
+	if !opt.isView && opt.DBDriver != DBDriverClickHouse && len(data.JoinKeys) == 0 {
+		updateFieldsCode, err = getUpdateFieldsCode(data, opt.IsEmbed, isAudit, opt.IsExplicitNullUpdates)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	modelJSONCode, err := getModelJSONCode(data)
 	if err != nil {
@@ -698,7 +866,10 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 	handlerStructCode := ""
 	serviceStructCode := ""
 	protoFileCode := ""
-	if data.isCommonStyle(opt.IsEmbed) {
+	switch {
+	case len(data.JoinKeys) > 0:
+		// join table has no crud api, it is managed by the association dao methods
+	case data.isCommonStyle(opt.IsEmbed):
 		handlerStructCode, err = getCommonHandlerStructCodes(data, opt.JSONNamedType)
 		if err != nil {
 			return nil, err
@@ -711,7 +882,7 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 		if err != nil {
 			return nil, err
 		}
-	} else {
+	default:
 		handlerStructCode, err = getHandlerStructCodes(data, opt.JSONNamedType)
 		if err != nil {
 			return nil, err
@@ -726,6 +897,22 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 		}
 	}
 
+	protoFileCode = addProtoOneof(data, opt.OneofGroups, protoFileCode)
+	protoFileCode, err = addExtendProtoCode(data, opt, protoFileCode)
+	if err != nil {
+		return nil, err
+	}
+
+	extendCodes, err := getExtendCodes(data, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.IsIndexMigration && opt.DBDriver != DBDriverMongodb {
+		if code := getIndexMigrationCode(data); code != "" {
+			extendCodes[CodeTypeIndexMigration] = code
+		}
+	}
+
 	return &codeText{
 		importPaths:   importPaths,
 		modelStruct:   modelStructCode,
@@ -735,11 +922,23 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 		protoFile:     protoFileCode,
 		serviceStruct: serviceStructCode,
 		crudInfo:      data.CrudInfo.getCode(),
+		extendCodes:   extendCodes,
 	}, nil
 }
 
 // getModelStructCode 生成 model 结构体代码
-func getModelStructCode(data tmplData, importPaths []string, isEmbed bool, jsonNamedType int) (string, []string, error) {
+func getModelStructCode(data tmplData, importPaths []string, isEmbed bool, isAudit bool, jsonNamedType int, isColumnWhitelist bool) (string, []string, error) {
+	// the audit columns are replaced by the embedded audit struct
+	if isAudit {
+		var fields []tmplField
+		for _, field := range data.Fields {
+			if !isAuditColumn(field.ColName) {
+				fields = append(fields, field)
+			}
+		}
+		data.Fields = fields
+	}
+
 	// filter to ignore field fields
 	var newFields = []tmplField{}
 	var newImportPaths = []string{}
@@ -827,6 +1026,35 @@ func getModelStructCode(data tmplData, importPaths []string, isEmbed bool, jsonN
 		newImportPaths = importPaths
 	}
 
+	if isAudit {
+		isHaveTimeType := false
+		for _, field := range data.Fields {
+			if strings.Contains(field.GoType, "time.Time") {
+				isHaveTimeType = true
+			}
+		}
+		var paths []string
+		for _, path := range newImportPaths {
+			if path == "time" && !isHaveTimeType {
+				continue
+			}
+			paths = append(paths, path)
+		}
+		newImportPaths = append(paths, "github.com/moweilong/milady/pkg/sgorm")
+		// the audit struct is embedded at the beginning, after the embedded gorm model if present
+		index := 0
+		if isEmbed {
+			index = 1
+		}
+		data.Fields = slices.Insert(data.Fields, index, tmplField{
+			Name:    __auditModel__,
+			ColName: __auditModel__,
+			GoType:  __type__,
+			Tag:     `gorm:"embedded"`,
+			Comment: "embed audit users and time\n",
+		})
+	}
+
 	builder := strings.Builder{}
 	err := modelStructTmpl.Execute(&builder, data)
 	if err != nil {
@@ -848,6 +1076,10 @@ func getModelStructCode(data tmplData, importPaths []string, isEmbed bool, jsonN
 		structCode = strings.ReplaceAll(structCode, __mysqlModel__, gormEmbed)
 		structCode = strings.ReplaceAll(structCode, __type__, replaceFields[__type__])
 	}
+	if isAudit {
+		structCode = strings.ReplaceAll(structCode, __auditModel__, "sgorm."+getAuditEmbed(isEmbed, jsonNamedType))
+		structCode = strings.ReplaceAll(structCode, __type__, replaceFields[__type__])
+	}
 
 	if data.SubStructs != "" {
 		structCode += data.SubStructs
@@ -860,17 +1092,19 @@ func getModelStructCode(data tmplData, importPaths []string, isEmbed bool, jsonN
 	}
 
 	// 生成表字段名白名单代码
-	tableColumnsCode, err := getTableColumnsCode(data, isEmbed)
-	if err != nil {
-		return "", nil, err
+	if isColumnWhitelist {
+		tableColumnsCode, err := getTableColumnsCode(data, isEmbed, isAudit)
+		if err != nil {
+			return "", nil, err
+		}
+		structCode += string(tableColumnsCode)
 	}
 
-	structCode += string(tableColumnsCode)
 	return structCode, newImportPaths, nil
 }
 
 // getTableColumnsCode 生成表字段名白名单代码, generated map[string]bool
-func getTableColumnsCode(data tmplData, isEmbed bool) ([]byte, error) {
+func getTableColumnsCode(data tmplData, isEmbed bool, isAudit bool) ([]byte, error) {
 	if data.DBDriver == DBDriverMongodb {
 		for _, field := range data.Fields {
 			if field.Name == "ID" {
@@ -903,6 +1137,21 @@ func getTableColumnsCode(data tmplData, isEmbed bool) ([]byte, error) {
 		}
 		data.Fields = fields
 	}
+	if isAudit {
+		var fields []tmplField
+		for _, field := range data.Fields {
+			if field.Name != __auditModel__ {
+				fields = append(fields, field)
+			}
+		}
+		for _, colName := range auditColumns {
+			if isEmbed && isIgnoreFields(colName) { // already added with the embedded gorm model
+				continue
+			}
+			fields = append(fields, tmplField{ColName: colName})
+		}
+		data.Fields = fields
+	}
 	builder := strings.Builder{}
 	err := tableColumnsTmpl.Execute(&builder, data)
 	if err != nil {
@@ -930,7 +1179,7 @@ func getModelCode(data modelCodes) (string, error) {
 	return string(code), nil
 }
 
-func getUpdateFieldsCode(data tmplData, isEmbed bool) (string, error) {
+func getUpdateFieldsCode(data tmplData, isEmbed bool, isAudit bool, isExplicitNull bool) (string, error) {
 	_ = isEmbed
 
 	// filter fields
@@ -940,6 +1189,10 @@ func getUpdateFieldsCode(data tmplData, isEmbed bool) (string, error) {
 		if isIgnoreFields(field.ColName, falseColumns...) || field.ColName == columnID || field.ColName == _columnID {
 			continue
 		}
+		// updated_by is set by the hook of the audit struct
+		if isAudit && isAuditColumn(field.ColName) {
+			continue
+		}
 		switch field.DBDriver {
 		case DBDriverMysql, DBDriverTidb, DBDriverPostgresql:
 			if field.rewriterField != nil {
@@ -948,6 +1201,7 @@ func getUpdateFieldsCode(data tmplData, isEmbed bool) (string, error) {
 				}
 			}
 		}
+		field.isExplicitNull = isExplicitNull
 		newFields = append(newFields, field)
 	}
 	data.Fields = newFields
@@ -1058,6 +1312,11 @@ func getModelJSONCode(data tmplData) (string, error) {
 
 func getProtoFileCode(data tmplData, jsonNamedType int, isWebProto bool, isExtendedAPI bool) (string, error) {
 	data.Fields = goTypeToProto(data.Fields, jsonNamedType, false)
+	if !isWebProto { // openapiv2 options are only imported by web proto
+		for i := range data.Fields {
+			data.Fields[i].example = ""
+		}
+	}
 
 	var err error
 	builder := strings.Builder{}
@@ -1178,9 +1437,21 @@ func adaptedDbType(data tmplData, isWebProto bool, code string) string {
 	return code
 }
 
+// replaceProtoMessageFieldCode replace the marks with the field codes, the marks are replaced in sorted order,
+// longer marks first, so that the output is deterministic even if a mark is the prefix of another one
 func replaceProtoMessageFieldCode(code string, messageFields map[string]string) string {
-	for k, v := range messageFields {
-		code = strings.ReplaceAll(code, k, v)
+	marks := make([]string, 0, len(messageFields))
+	for k := range messageFields {
+		marks = append(marks, k)
+	}
+	sort.Slice(marks, func(i, j int) bool {
+		if len(marks[i]) != len(marks[j]) {
+			return len(marks[i]) > len(marks[j])
+		}
+		return marks[i] < marks[j]
+	})
+	for _, k := range marks {
+		code = strings.ReplaceAll(code, k, messageFields[k])
 	}
 	return code
 }
@@ -1408,6 +1679,12 @@ func goTypeToProto(fields []tmplField, jsonNameType int, isCommonStyle bool) []t
 				field.GoType = "bool"
 			}
 		}
+		if field.protoMessage != "" {
+			field.GoType = field.protoMessage
+		}
+		if field.protoEnum != "" {
+			field.GoType = field.protoEnum
+		}
 
 		newFields = append(newFields, field)
 	}
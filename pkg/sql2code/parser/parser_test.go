@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/jinzhu/inflection"
@@ -264,6 +265,28 @@ func TestParseSQLs(t *testing.T) {
 	}
 }
 
+func TestParseSQL_InfoSeparator(t *testing.T) {
+	sql := `create table user (id bigint unsigned auto_increment, name varchar(50) comment 'user name', primary key (id));
+create table user_order (id bigint unsigned auto_increment, user_id bigint unsigned comment 'a ## b', primary key (id));`
+
+	codes, err := ParseSQL(sql)
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(codes[CodeTypeCrudInfo], DefaultInfoSeparator), 2)
+
+	codes, err = ParseSQL(sql, WithCustomTemplate(), WithInfoSeparator("\n---\n"))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeTableInfo], DefaultInfoSeparator)
+	infos := strings.Split(codes[CodeTypeTableInfo], "\n---\n")
+	if assert.Len(t, infos, 2) {
+		_, err = UnMarshalTableInfo(infos[1])
+		assert.NoError(t, err)
+	}
+
+	_, err = ParseSQL(sql, WithCustomTemplate(), WithInfoSeparator("##"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "UserOrder")
+}
+
 func Test_parseOption(t *testing.T) {
 	opts := []Option{
 		WithDBDriver("foo"),
@@ -676,3 +699,108 @@ func Test_customEndOfLetterToLower(t *testing.T) {
 		t.Log(customEndOfLetterToLower(name, inflection.Plural(name)))
 	}
 }
+
+func TestParseSQL_WithoutColumnWhitelist(t *testing.T) {
+	sql := `create table user_order (id bigint unsigned auto_increment, user_id bigint unsigned comment 'user id', primary key (id));`
+
+	codes, err := ParseSQL(sql)
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeModel], "var UserOrderColumnNames = map[string]bool{")
+
+	codes, err = ParseSQL(sql, WithoutColumnWhitelist())
+	assert.NoError(t, err)
+	assert.Contains(t, codes[CodeTypeModel], "type UserOrder struct {")
+	assert.NotContains(t, codes[CodeTypeModel], "ColumnNames")
+}
+
+func TestParseSQL_WithExplicitNullUpdates(t *testing.T) {
+	sql := `create table user_order (
+    id       bigint unsigned auto_increment,
+    order_no varchar(36) null comment 'order no',
+    amount   int         null comment 'amount',
+    status   varchar(20) not null comment 'status',
+    primary key (id)
+);`
+
+	codes, err := ParseSQL(sql, WithNullStyle(NullInPointer), WithExplicitNullUpdates())
+	assert.NoError(t, err)
+	code := codes[CodeTypeDAO]
+	assert.Contains(t, code, "if table.OrderNo != nil {\n\t\tupdate[\"order_no\"] = table.OrderNo")
+	assert.Contains(t, code, "if table.Amount != nil {\n\t\tupdate[\"amount\"] = table.Amount")
+	assert.Contains(t, code, `if table.Status != "" {`)
+
+	codes, err = ParseSQL(sql, WithNullStyle(NullInPointer))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes[CodeTypeDAO], "if table.OrderNo != nil {")
+}
+
+func TestParseSQL_Deterministic(t *testing.T) {
+	sql := `create table user_order (
+    id          bigint unsigned auto_increment,
+    user_id     bigint unsigned not null comment 'user id',
+    order_no    varchar(36)     not null comment 'order no',
+    remark      json            null comment 'remark',
+    amount      decimal(10,2)   not null comment 'amount',
+    status      tinyint         not null comment 'status',
+    created_at  datetime        null,
+    updated_at  datetime        null,
+    primary key (id),
+    unique key uk_order_no (order_no)
+);`
+	fieldTypes := map[string]string{"remark": "string", "amount": "float64", "status": "int32", "user_id": "int64"}
+
+	for _, driver := range []string{DBDriverMysql, DBDriverPostgresql, DBDriverSqlite, DBDriverMongodb} {
+		generate := func() map[string]string {
+			codes, err := ParseSQL(sql, WithJSONTag(1), WithDBDriver(driver), WithFieldTypes(fieldTypes), WithWebProto())
+			assert.NoError(t, err)
+			return codes
+		}
+		expected := generate()
+		for i := 0; i < 10; i++ {
+			codes := generate()
+			assert.Equal(t, len(expected), len(codes), driver)
+			for k, v := range expected {
+				assert.Equal(t, v, codes[k], "driver %s, code type %s", driver, k)
+			}
+		}
+
+		// the fields keep the column order of sql
+		model := expected[CodeTypeModel]
+		last := -1
+		for _, name := range []string{"UserID", "OrderNo", "Remark", "Amount", "Status"} {
+			i := strings.Index(model, "\t"+name+" ")
+			assert.Greater(t, i, last, "driver %s, field %s", driver, name)
+			last = i
+		}
+	}
+}
+
+func TestParseSQL_JSONProtoMessage(t *testing.T) {
+	sql := `create table user (
+    id       bigint unsigned auto_increment,
+    name     varchar(50) not null comment 'name',
+    address  json        not null comment 'address',
+    contact  json        null comment 'contact',
+    primary key (id)
+);`
+	subStructs := "message Address {\n  string city = 1;\n  string street = 2;\n}\n\nmessage ContactInfo {\n  string phone = 1;\n}\n"
+
+	// the message is named by the column name or specified by the field type
+	codes, err := ParseSQL(sql, WithJSONTag(1), WithFieldTypes(map[string]string{
+		ProtoSubStructKey: subStructs,
+		"contact":         "ContactInfo",
+	}))
+	assert.NoError(t, err)
+	protoCode := codes[CodeTypeProto]
+	assert.Contains(t, protoCode, "Address address = ")
+	assert.Contains(t, protoCode, "ContactInfo contact = ")
+	assert.Contains(t, protoCode, "message Address {")
+	assert.Contains(t, protoCode, "message ContactInfo {")
+
+	// without the nested message, the json column is a string
+	codes, err = ParseSQL(sql, WithJSONTag(1))
+	assert.NoError(t, err)
+	protoCode = codes[CodeTypeProto]
+	assert.Contains(t, protoCode, "string address = ")
+	assert.NotContains(t, protoCode, "message Address {")
+}
@@ -92,6 +92,8 @@ func (field *PGField) getMysqlType() string {
 		return "json"
 	case "boolean", "bool":
 		return "bit(1)"
+	case "uuid":
+		return "char(36)"
 	case "bit":
 		return "bit"
 	}
@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlQueries the hand-written queries and arguments of the dao without orm
+type sqlQueries struct {
+	Insert     string // example: INSERT INTO user_order (order_no, amount) VALUES (?, ?)
+	InsertArgs string // example: table.OrderNo, table.Amount
+	Update     string // example: UPDATE user_order SET order_no = ?, amount = ? WHERE id = ?
+	UpdateArgs string // example: table.OrderNo, table.Amount, table.ID
+	Delete     string // example: DELETE FROM user_order WHERE id = ?
+	DeleteArgs string // example: id
+	Get        string // example: SELECT id, order_no, amount FROM user_order WHERE id = ?
+	List       string // example: SELECT id, order_no, amount FROM user_order ORDER BY id DESC LIMIT ? OFFSET ?
+	ScanArgs   string // example: &record.ID, &record.OrderNo, &record.Amount
+
+	IsInsertNow bool // the insert sets created_at or updated_at to the variable now
+	IsUpdateNow bool // the update sets updated_at to the variable now
+
+	AutoIncrementField string // model field of the auto increment primary key written back after insert, example: ID
+	AutoIncrementType  string // go type of the auto increment primary key, example: uint64
+	IsReturning        bool   // the insert returns the auto increment primary key, postgresql has no LastInsertId
+}
+
+// checkORM check the orm is supported and the options depending on gorm are not used with sqlx or stdlib
+func (o options) checkORM() error {
+	switch o.ORM {
+	case "", ORMGorm:
+		return nil
+	case ORMSqlx, ORMStdlib:
+	default:
+		return fmt.Errorf("unsupported orm %q, the orm is %s, %s or %s", o.ORM, ORMGorm, ORMSqlx, ORMStdlib)
+	}
+
+	if o.DBDriver == DBDriverMongodb || o.DBDriver == DBDriverClickHouse {
+		return fmt.Errorf("orm %s does not support db driver %s", o.ORM, o.DBDriver)
+	}
+	gormOptions := []struct {
+		enabled bool
+		name    string
+	}{
+		{o.GormType, "WithGormType"},
+		{o.IsEmbed, "WithEmbed"},
+		{o.IsUUIDHook, "WithUUIDHook"},
+		{o.IsAuditFields, "WithAuditFields"},
+		{o.IsCreateBatch, "WithCreateBatch"},
+		{o.IsRestore, "WithRestore"},
+		{len(o.DistinctColumns) > 0, "WithDistinctColumns"},
+		{o.IsRWSplit, "WithReadWriteSplit"},
+		{o.IsListByTimeRange, "WithListByTimeRange"},
+		{o.IsFieldMask, "WithFieldMask"},
+		{o.IsCountByGroup, "WithCountByGroup"},
+		{o.IsRepository, "WithRepository"},
+		{o.IsDaoMetrics, "WithDaoMetrics"},
+		{o.IsSeed, "WithSeed"},
+		{o.IsForUpdate, "WithForUpdate"},
+		{o.IsUpsert, "WithUpsert"},
+		{len(o.SearchColumns) > 0, "WithSearch"},
+		{o.IsCursorList, "WithCursorList"},
+		{o.IsUpdateByCondition, "WithUpdateByCondition"},
+		{o.IsSortValidation, "WithSortValidation"},
+	}
+	var names []string
+	for _, opt := range gormOptions {
+		if opt.enabled {
+			names = append(names, opt.name)
+		}
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("options %s depend on gorm, they are not supported by orm %s", strings.Join(names, ", "), o.ORM)
+	}
+	return nil
+}
+
+// isSQLORM return true if the models and dao use database/sql or sqlx instead of gorm
+func (o options) isSQLORM() bool {
+	return o.ORM == ORMSqlx || o.ORM == ORMStdlib
+}
+
+// IsSqlx return true if the dao uses sqlx, otherwise database/sql
+func (d extendTmplData) IsSqlx() bool {
+	return d.Opt.ORM == ORMSqlx
+}
+
+// sqlPlaceholder return the placeholder of the i-th argument, i starts from 1, example: ? or $1
+func (d extendTmplData) sqlPlaceholder(i int) string {
+	if d.DBDriver == DBDriverPostgresql {
+		return fmt.Sprintf("$%d", i)
+	}
+	return "?"
+}
+
+// SQLQueries return the hand-written queries of the dao, the auto increment primary key is not inserted.
+// Like gorm, created_at and updated_at are set to the current time, the table with deleted_at is soft deleted
+// and the deleted records are not updated or queried.
+func (d extendTmplData) SQLQueries() sqlQueries {
+	var q sqlQueries
+	var pk tmplField
+	var columns, scanArgs, insertColumns, insertArgs, setColumns, updateArgs []string
+	isSoftDelete := false
+	for _, field := range d.Fields {
+		columns = append(columns, field.ColName)
+		scanArgs = append(scanArgs, "&record."+field.Name)
+		if field.IsPrimaryKey {
+			pk = field
+			if field.isAutoIncrement {
+				q.AutoIncrementField, q.AutoIncrementType = field.Name, field.GoType
+				continue
+			}
+		}
+
+		switch field.ColName {
+		case columnDeletedAt:
+			isSoftDelete = true
+			continue
+		case columnCreatedAt:
+			insertColumns = append(insertColumns, field.ColName)
+			insertArgs = append(insertArgs, "now")
+			q.IsInsertNow = true
+			continue
+		case columnUpdatedAt:
+			insertColumns = append(insertColumns, field.ColName)
+			insertArgs = append(insertArgs, "now")
+			setColumns = append(setColumns, field.ColName)
+			updateArgs = append(updateArgs, "now")
+			q.IsInsertNow, q.IsUpdateNow = true, true
+			continue
+		}
+
+		insertColumns = append(insertColumns, field.ColName)
+		insertArgs = append(insertArgs, "table."+field.Name)
+		if !field.IsPrimaryKey {
+			setColumns = append(setColumns, field.ColName)
+			updateArgs = append(updateArgs, "table."+field.Name)
+		}
+	}
+	if pk.ColName == "" && d.CrudInfo != nil {
+		pk = tmplField{ColName: d.CrudInfo.ColumnName, Name: d.PKFieldName()}
+	}
+
+	placeholders := make([]string, 0, len(insertColumns))
+	for i := range insertColumns {
+		placeholders = append(placeholders, d.sqlPlaceholder(i+1))
+	}
+	q.Insert = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", d.RawTableName,
+		strings.Join(insertColumns, ", "), strings.Join(placeholders, ", "))
+	if q.AutoIncrementField != "" && d.DBDriver == DBDriverPostgresql {
+		q.Insert += " RETURNING " + pk.ColName
+		q.IsReturning = true
+	}
+	q.InsertArgs = strings.Join(insertArgs, ", ")
+
+	for i, column := range setColumns {
+		setColumns[i] = column + " = " + d.sqlPlaceholder(i+1)
+	}
+	notDeleted, whereNotDeleted := "", ""
+	if isSoftDelete {
+		notDeleted = " AND " + columnDeletedAt + " IS NULL"
+		whereNotDeleted = " WHERE " + columnDeletedAt + " IS NULL"
+	}
+
+	q.Update = fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s%s", d.RawTableName,
+		strings.Join(setColumns, ", "), pk.ColName, d.sqlPlaceholder(len(setColumns)+1), notDeleted)
+	q.UpdateArgs = strings.Join(append(updateArgs, "table."+pk.Name), ", ")
+
+	if isSoftDelete {
+		q.Delete = fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s%s", d.RawTableName, columnDeletedAt,
+			d.sqlPlaceholder(1), pk.ColName, d.sqlPlaceholder(2), notDeleted)
+		q.DeleteArgs = "time.Now(), " + d.PKParam()
+	} else {
+		q.Delete = fmt.Sprintf("DELETE FROM %s WHERE %s = %s", d.RawTableName, pk.ColName, d.sqlPlaceholder(1))
+		q.DeleteArgs = d.PKParam()
+	}
+
+	selectColumns := strings.Join(columns, ", ")
+	q.Get = fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s%s", selectColumns, d.RawTableName,
+		pk.ColName, d.sqlPlaceholder(1), notDeleted)
+	q.List = fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s DESC LIMIT %s OFFSET %s", selectColumns, d.RawTableName,
+		whereNotDeleted, pk.ColName, d.sqlPlaceholder(1), d.sqlPlaceholder(2))
+	q.ScanArgs = strings.Join(scanArgs, ", ")
+
+	return q
+}
@@ -65,7 +65,7 @@ import (
 // Create{{.TableName}}Request request params
 type Create{{.TableName}}Request struct {
 {{- range .Fields}}
-	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}" binding:""` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
+	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}" binding:"{{.BindingTag}}"{{.ExampleTag}}` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
 {{- end}}
 }
 `
@@ -75,7 +75,7 @@ type Create{{.TableName}}Request struct {
 // Update{{.TableName}}ByIDRequest request params
 type Update{{.TableName}}ByIDRequest struct {
 {{- range .Fields}}
-	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}" binding:""` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
+	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}" binding:"{{.UpdateBindingTag}}"{{.ExampleTag}}` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
 {{- end}}
 }
 `
@@ -679,7 +679,7 @@ message List{{.TableName}}Reply {
 	protoMessageCreateTmpl    *template.Template
 	protoMessageCreateTmplRaw = `message Create{{.TableName}}Request {
 {{- range $i, $v := .Fields}}
-	{{$v.GoType}} {{$v.JSONName}} = {{$v.AddOne $i}}; {{if $v.Comment}} // {{$v.Comment}}{{end}}
+	{{$v.GoType}} {{$v.JSONName}} = {{$v.AddOneWithCheck $i}}; {{if $v.Comment}} // {{$v.Comment}}{{end}}
 {{- end}}
 }`
 
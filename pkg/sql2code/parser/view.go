@@ -0,0 +1,202 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhufuyi/sqlparser/ast"
+	"github.com/zhufuyi/sqlparser/dependency/model"
+	"github.com/zhufuyi/sqlparser/dependency/mysql"
+	"github.com/zhufuyi/sqlparser/dependency/types"
+)
+
+// viewSource a table in the from clause of the view
+type viewSource struct {
+	name  string // table name or alias in lower case
+	table *ast.CreateTableStmt
+}
+
+// viewToCreateTable convert the view to a table statement, the column definitions are copied from the
+// tables of the from clause, tables is the created tables of the sql, the key is table name in lower case.
+// The primary key of the first table is kept if it is selected, the columns of expressions are converted
+// to bigint for COUNT and varchar for the others.
+func viewToCreateTable(view *ast.CreateViewStmt, tables map[string]*ast.CreateTableStmt) (*ast.CreateTableStmt, error) {
+	viewName := view.ViewName.Name.String()
+	sel, ok := view.Select.(*ast.SelectStmt)
+	if !ok || sel.Fields == nil {
+		return nil, fmt.Errorf("view %s: only simple select statement is supported", viewName)
+	}
+
+	var sources []viewSource
+	if sel.From != nil {
+		var err error
+		sources, err = getViewSources(sel.From.TableRefs, tables, sources)
+		if err != nil {
+			return nil, fmt.Errorf("view %s: %v", viewName, err)
+		}
+	}
+
+	var cols []*ast.ColumnDef
+	var pkName string
+	addColumn := func(src *viewSource, col *ast.ColumnDef, name string) {
+		if src != nil && len(sources) > 0 && src.name == sources[0].name && isPrimaryKeyColumn(src.table, col) && pkName == "" {
+			pkName = name
+		}
+		cols = append(cols, copyColumnDef(col, name))
+	}
+
+	for i, field := range sel.Fields.Fields {
+		if field.WildCard != nil {
+			isMatched := false
+			for j := range sources {
+				src := &sources[j]
+				if field.WildCard.Table.L != "" && field.WildCard.Table.L != src.name {
+					continue
+				}
+				isMatched = true
+				for _, col := range src.table.Cols {
+					addColumn(src, col, col.Name.Name.String())
+				}
+			}
+			if !isMatched {
+				return nil, fmt.Errorf("view %s: the table of %s.* is not found", viewName, field.WildCard.Table.O)
+			}
+			continue
+		}
+
+		name := field.AsName.String()
+		switch expr := field.Expr.(type) {
+		case *ast.ColumnNameExpr:
+			if name == "" {
+				name = expr.Name.Name.String()
+			}
+			src, col := findViewColumn(sources, expr.Name)
+			if col != nil {
+				addColumn(src, col, name)
+				continue
+			}
+		case *ast.AggregateFuncExpr:
+			if name == "" {
+				return nil, fmt.Errorf("view %s: the column %d requires an alias", viewName, i+1)
+			}
+			if strings.EqualFold(expr.F, ast.AggFuncCount) {
+				cols = append(cols, newViewColumnDef(name, mysql.TypeLonglong))
+				continue
+			}
+		default:
+			if name == "" {
+				return nil, fmt.Errorf("view %s: the column %d requires an alias", viewName, i+1)
+			}
+		}
+		cols = append(cols, newViewColumnDef(name, mysql.TypeVarchar))
+	}
+
+	if len(view.Cols) > 0 {
+		if len(view.Cols) != len(cols) {
+			return nil, fmt.Errorf("view %s: the number of view columns does not match the select fields", viewName)
+		}
+		for i, col := range cols {
+			if pkName == col.Name.Name.String() {
+				pkName = view.Cols[i].String()
+			}
+			col.Name = &ast.ColumnName{Name: view.Cols[i]}
+		}
+	}
+
+	ct := &ast.CreateTableStmt{Table: view.ViewName, Cols: cols}
+	for _, col := range cols {
+		if col.Name.Name.String() == pkName {
+			ct.Constraints = append(ct.Constraints, &ast.Constraint{
+				Tp:   ast.ConstraintPrimaryKey,
+				Keys: []*ast.IndexColName{{Column: col.Name}},
+			})
+			break
+		}
+	}
+	return ct, nil
+}
+
+// getViewSources get the tables of the from clause, sub queries are not supported
+func getViewSources(node ast.ResultSetNode, tables map[string]*ast.CreateTableStmt, sources []viewSource) ([]viewSource, error) {
+	var err error
+	switch n := node.(type) {
+	case *ast.Join:
+		if sources, err = getViewSources(n.Left, tables, sources); err != nil {
+			return nil, err
+		}
+		if n.Right != nil {
+			return getViewSources(n.Right, tables, sources)
+		}
+	case *ast.TableSource:
+		tn, ok := n.Source.(*ast.TableName)
+		if !ok {
+			return nil, fmt.Errorf("sub query in from clause is not supported")
+		}
+		ct, ok := tables[tn.Name.L]
+		if !ok {
+			return nil, fmt.Errorf("the table %s is not defined, add the CREATE TABLE statement before the view", tn.Name.O)
+		}
+		name := tn.Name.L
+		if n.AsName.L != "" {
+			name = n.AsName.L
+		}
+		sources = append(sources, viewSource{name: name, table: ct})
+	case nil:
+	default:
+		return nil, fmt.Errorf("unsupported from clause %T", node)
+	}
+	return sources, nil
+}
+
+// findViewColumn find the column definition in the tables of the from clause
+func findViewColumn(sources []viewSource, name *ast.ColumnName) (*viewSource, *ast.ColumnDef) {
+	for i := range sources {
+		src := &sources[i]
+		if name.Table.L != "" && name.Table.L != src.name {
+			continue
+		}
+		for _, col := range src.table.Cols {
+			if col.Name.Name.L == name.Name.L {
+				return src, col
+			}
+		}
+	}
+	return nil, nil
+}
+
+func isPrimaryKeyColumn(ct *ast.CreateTableStmt, col *ast.ColumnDef) bool {
+	for _, o := range col.Options {
+		if o.Tp == ast.ColumnOptionPrimaryKey {
+			return true
+		}
+	}
+	for _, con := range ct.Constraints {
+		if con.Tp == ast.ConstraintPrimaryKey && len(con.Keys) == 1 && con.Keys[0].Column.Name.L == col.Name.Name.L {
+			return true
+		}
+	}
+	return false
+}
+
+// copyColumnDef copy the column definition with a new name, the primary key and auto increment options are removed
+func copyColumnDef(col *ast.ColumnDef, name string) *ast.ColumnDef {
+	options := make([]*ast.ColumnOption, 0, len(col.Options))
+	for _, o := range col.Options {
+		if o.Tp != ast.ColumnOptionPrimaryKey && o.Tp != ast.ColumnOptionAutoIncrement {
+			options = append(options, o)
+		}
+	}
+	return &ast.ColumnDef{
+		Name:    &ast.ColumnName{Name: model.NewCIStr(name)},
+		Tp:      col.Tp,
+		Options: options,
+	}
+}
+
+func newViewColumnDef(name string, tp byte) *ast.ColumnDef {
+	ft := types.NewFieldType(tp)
+	if tp == mysql.TypeVarchar {
+		ft.Flen = 255
+	}
+	return &ast.ColumnDef{Name: &ast.ColumnName{Name: model.NewCIStr(name)}, Tp: ft}
+}
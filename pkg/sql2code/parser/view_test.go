@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQL_CreateView(t *testing.T) {
+	sql := extendTestSQL + `
+CREATE TABLE user (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    name varchar(50) NOT NULL COMMENT 'user name',
+    PRIMARY KEY (id)
+);
+CREATE VIEW user_order_view AS
+SELECT o.id, o.order_no, o.amount, u.name AS user_name, COUNT(*) AS total, CONCAT(o.order_no, '-', u.name) AS title
+FROM user_order o JOIN user u ON o.user_id = u.id;`
+
+	codes, err := ParseSQL(sql, WithJSONTag(0), WithCreateBatch(), WithRepository())
+	assert.NoError(t, err)
+	assert.Equal(t, "UserOrder, User, UserOrderView", codes[TableName])
+
+	model := codes[CodeTypeModel]
+	assert.Contains(t, model, "type UserOrderView struct {")
+	assert.Contains(t, model, `gorm:"column:id;primary_key" json:"id"`)
+	assert.Regexp(t, `OrderNo\s+string\s`, model)
+	assert.Regexp(t, `UserName\s+string\s+.*column:user_name;not null.*// user name`, model)
+	assert.Regexp(t, `Total\s+int64\s`, model)
+	assert.Regexp(t, `Title\s+string\s`, model)
+
+	dao := codes[CodeTypeDAOExtend]
+	assert.Contains(t, dao, "func (d *userOrderViewDao) GetByID(ctx context.Context, id uint64) (*model.UserOrderView, error) {")
+	assert.Contains(t, dao, "func (d *userOrderViewDao) List(ctx context.Context, params *query.Params) ([]*model.UserOrderView, int64, error) {")
+	assert.NotContains(t, dao, "items []*model.UserOrderView, chunkSize int")
+	assert.NotContains(t, dao, "UserOrderViewRepository")
+	assert.Contains(t, dao, "UserOrderRepository")
+	assert.NotContains(t, codes[CodeTypeDAO], "UserOrderView")
+
+	_, err = ParseSQL(`CREATE VIEW v AS SELECT id FROM not_exist;`)
+	assert.Error(t, err)
+}